@@ -0,0 +1,79 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/antonjah/ssm/internal/check"
+	"github.com/antonjah/ssm/internal/probe"
+)
+
+// runCheck implements "ssm check", which diagnoses a host's configuration
+// and connectivity.
+func runCheck(args []string) int {
+	fs := flag.NewFlagSet("check", flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: ssm check [flags] <alias...>\n\nCheck DNS, reachability, identity files, jump hosts and known_hosts.\n\nFlags:\n")
+		fs.PrintDefaults()
+	}
+	timeout := fs.Duration("timeout", probe.DefaultTimeout, "DNS and connect timeout")
+	jsonOutput := fs.Bool("json", false, "print results as JSON")
+	positional := parseInterspersed(fs, args)
+
+	if len(positional) == 0 {
+		fs.Usage()
+		return 2
+	}
+
+	hosts, err := loadHosts()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error reading SSH config: %v\n", err)
+		return 1
+	}
+	selected, err := selectHosts(hosts, hostSelector{hosts: joinList(positional)})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 2
+	}
+
+	var reports []check.Report
+	for _, host := range selected {
+		reports = append(reports, check.Run(context.Background(), host, hosts, check.Options{Timeout: *timeout}))
+	}
+
+	if *jsonOutput {
+		if err := writeJSON(os.Stdout, reports); err != nil {
+			fmt.Fprintf(os.Stderr, "Error writing JSON: %v\n", err)
+			return 1
+		}
+	} else {
+		printCheck(os.Stdout, reports)
+	}
+
+	for _, report := range reports {
+		if !report.OK() {
+			return 1
+		}
+	}
+	return 0
+}
+
+// printCheck prints check reports as tables.
+func printCheck(w io.Writer, reports []check.Report) {
+	for i, report := range reports {
+		if i > 0 {
+			fmt.Fprintln(w)
+		}
+		fmt.Fprintf(w, "%s\n", report.Alias)
+		tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+		for _, result := range report.Results {
+			fmt.Fprintf(tw, "  %s\t%s\t%s\n", strings.ToUpper(string(result.Status)), result.Name, result.Detail)
+		}
+		tw.Flush()
+	}
+}
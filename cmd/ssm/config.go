@@ -0,0 +1,206 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"maps"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/antonjah/ssm/internal/config"
+	"github.com/antonjah/ssm/internal/keys"
+	"github.com/antonjah/ssm/internal/notes"
+	"github.com/antonjah/ssm/internal/record"
+	"github.com/antonjah/ssm/internal/settings"
+	"github.com/antonjah/ssm/internal/theme"
+	"github.com/antonjah/ssm/internal/tmux"
+	"github.com/antonjah/ssm/internal/transport"
+)
+
+// runConfig implements "ssm config", which inspects ssm's own settings.
+func runConfig(args []string) int {
+	if len(args) == 0 {
+		configUsage()
+		return 2
+	}
+	switch args[0] {
+	case "show":
+		return runConfigShow(args[1:])
+	case "path":
+		return runConfigPath(args[1:])
+	case "help", "-h", "--help":
+		configUsage()
+		return 0
+	}
+	fmt.Fprintf(os.Stderr, "Unknown config command %q\n\n", args[0])
+	configUsage()
+	return 2
+}
+
+func configUsage() {
+	fmt.Fprint(os.Stderr, `Usage: ssm config <command> [flags]
+
+Inspect ssm's settings file. Environment variables named
+SSM_<SECTION>_<KEY>, e.g. SSM_RECORD_DIR, override its settings.
+
+Commands:
+  show   Print the effective settings: defaults, the file and the environment merged
+  path   Print the settings file's location
+`)
+}
+
+func runConfigShow(args []string) int {
+	fs := flag.NewFlagSet("config show", flag.ExitOnError)
+	defaults := fs.Bool("defaults", false, "print the defaults, ignoring the file and the environment")
+	fs.Parse(args)
+
+	var cfg settings.Settings
+	var err error
+	source := "the defaults"
+	if *defaults {
+		cfg, err = defaultSettings()
+	} else {
+		var p string
+		cfg, p, err = loadSettings()
+		source = p
+		if _, statErr := os.Stat(p); os.IsNotExist(statErr) {
+			source += " (missing, so the defaults)"
+		}
+		source += " and the environment"
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error reading settings: %v\n", err)
+		return 1
+	}
+
+	fmt.Printf("# Effective settings from %s\n\n", source)
+	if err := cfg.Encode(os.Stdout); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	return 0
+}
+
+func runConfigPath(args []string) int {
+	fs := flag.NewFlagSet("config path", flag.ExitOnError)
+	fs.Parse(args)
+
+	p, err := settings.DefaultPath()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	fmt.Println(p)
+	return 0
+}
+
+// loadSettings reads ssm's settings file and returns them with its path.
+func loadSettings() (settings.Settings, string, error) {
+	p, err := settings.DefaultPath()
+	if err != nil {
+		return settings.Settings{}, "", err
+	}
+	s, err := readSettings(p)
+	return s, p, err
+}
+
+// readSettings reads the settings file at p, which may be missing, over the
+// defaults, and checks the settings that only the packages they configure
+// understand. NO_COLOR (https://no-color.org) selects the monochrome theme
+// whatever else is configured.
+func readSettings(p string) (settings.Settings, error) {
+	defaults, err := defaultSettings()
+	if err != nil {
+		return defaults, err
+	}
+	s, err := settings.Load(p, defaults)
+	if err != nil {
+		return s, err
+	}
+	if os.Getenv("NO_COLOR") != "" {
+		s.Theme.Name = theme.Mono
+	}
+	if err := checkSettings(s); err != nil {
+		return s, fmt.Errorf("%s: %w", p, err)
+	}
+	return s, nil
+}
+
+// defaultSettings returns the settings used when nothing overrides them:
+// the settings package's defaults, completed with those of the packages
+// the other settings configure.
+func defaultSettings() (settings.Settings, error) {
+	s := settings.Default()
+	recordings, err := record.DefaultDir()
+	if err != nil {
+		return s, err
+	}
+	notesDir, err := notes.DefaultDir()
+	if err != nil {
+		return s, err
+	}
+	s.Theme = settings.Theme{Name: theme.Auto, Light: "latte", Dark: theme.Default}
+	s.Keys.Preset = keys.Default
+	s.Tmux = settings.Tmux{
+		WindowPrefix:  tmux.DefaultWindowPrefix,
+		TiledWindow:   tmux.DefaultTiledName,
+		ClusterWindow: tmux.DefaultClusterName,
+		SyncKey:       tmux.DefaultSyncKey,
+	}
+	s.Connect.Transport = transport.Default
+	s.Record.Dir = recordings
+	s.Notes.Dir = notesDir
+	return s, nil
+}
+
+// checkSettings checks the transports, themes and key bindings, which the
+// settings package only holds. Problems are reported in a stable order.
+func checkSettings(s settings.Settings) error {
+	var errs []error
+	fail := func(p string, err error) {
+		errs = append(errs, &settings.Error{Path: p, Message: err.Error()})
+	}
+
+	themes := themeRegistry(s)
+	if !strings.EqualFold(s.Theme.Name, theme.Auto) {
+		if _, err := themes.Lookup(s.Theme.Name); err != nil {
+			fail("theme.name", err)
+		}
+	}
+	if _, err := themes.Lookup(s.Theme.Light); err != nil {
+		fail("theme.light", err)
+	}
+	if _, err := themes.Lookup(s.Theme.Dark); err != nil {
+		fail("theme.dark", err)
+	}
+	for _, name := range slices.Sorted(maps.Keys(s.Themes)) {
+		if _, err := themes.Lookup(name); err != nil {
+			fail("themes."+name, err)
+		}
+	}
+	if _, err := keys.New(s.Keys.Preset, s.Keys.Bindings); err != nil {
+		fail("keys", err)
+	}
+	for _, name := range slices.Sorted(maps.Keys(s.Transports)) {
+		t := transport.Transport{Name: name, Template: s.Transports[name]}
+		if _, err := t.Command(config.Host{Alias: "example"}); err != nil && strings.TrimSpace(t.Template) != "" {
+			fail("transports."+name, err)
+		}
+	}
+	if _, err := transportRegistry(s).Lookup(s.Connect.Transport); err != nil {
+		fail("connect.transport", err)
+	}
+	return errors.Join(errs...)
+}
+
+// themeRegistry returns the built-in and custom themes.
+func themeRegistry(s settings.Settings) theme.Registry {
+	return theme.Registry{Custom: s.Themes}
+}
+
+// transportRegistry returns the transports available to sessions.
+func transportRegistry(s settings.Settings) transport.Registry {
+	return transport.Registry{Custom: s.Transports, Default: s.Connect.Transport}
+}
@@ -0,0 +1,210 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/antonjah/ssm/internal/audit"
+	"github.com/antonjah/ssm/internal/config"
+	"github.com/antonjah/ssm/internal/hooks"
+	"github.com/antonjah/ssm/internal/reconnect"
+	"github.com/antonjah/ssm/internal/record"
+	"github.com/antonjah/ssm/internal/settings"
+	"github.com/antonjah/ssm/internal/tmux"
+)
+
+// runConnect implements "ssm connect", which opens a session to a single
+// host. It isn't listed in the usage: tmux windows run it so that sessions
+// that are audited, recorded or reconnected are managed inside the window.
+func runConnect(args []string) int {
+	fs := flag.NewFlagSet("connect", flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: ssm connect [flags] <alias> [-- command...]\n\nConnect to a host, optionally with the given command instead of its\ntransport.\n\nFlags:\n")
+		fs.PrintDefaults()
+	}
+	via := fs.String("via", "", "connect with this transport")
+	var mode sessionMode
+	fs.BoolVar(&mode.reconnect, "reconnect", false, "reconnect when the connection is lost")
+	fs.BoolVar(&mode.record, "record", false, "record the session to a cast file")
+	fs.StringVar(&mode.recordDir, "record-dir", "", "save recordings in this directory")
+	fs.StringVar(&mode.auditLog, "audit-log", "", "log the session to this audit log")
+	fs.StringVar(&mode.settings, "config", "", "read settings from this file instead of the default one")
+	positional := parseInterspersed(fs, args)
+
+	if len(positional) == 0 {
+		fs.Usage()
+		return 2
+	}
+	if mode.settings == "" {
+		p, err := settings.DefaultPath()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return 1
+		}
+		mode.settings = p
+	}
+	cfg, err := readSettings(mode.settings)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error reading settings: %v\n", err)
+		return 1
+	}
+	if mode.recordDir == "" {
+		mode.recordDir = cfg.Record.Dir
+	}
+
+	session := tmux.Session{Host: cleanAlias(positional[0]), Command: positional[1:]}
+	hosts, err := loadHosts()
+	if len(session.Command) == 0 {
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error reading SSH config: %v\n", err)
+			return 1
+		}
+		if session, err = newSession(transportRegistry(cfg), hosts, session.Host, *via); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return 1
+		}
+	}
+	mode.hooks = cfg.HooksFor(findHost(hosts, session.Host))
+	// A vetoed window would close before its reason could be read
+	mode.hold = tmux.IsTmuxSession()
+
+	if mode.managed() {
+		return runSession(session, mode, hosts)
+	}
+	return execSession(session)
+}
+
+// sessionMode says how ssm runs a session it doesn't hand over with exec.
+type sessionMode struct {
+	reconnect bool
+	record    bool
+	// recordDir is the directory recordings are saved in.
+	recordDir string
+	// auditLog is the audit log the session is logged to, if any.
+	auditLog string
+	// hooks run before and after the session. settings is the file ssm's
+	// settings were read from.
+	hooks    []settings.Hook
+	settings string
+	// hold waits for a key before returning when a hook vetoes the session.
+	hold bool
+}
+
+// managed reports whether ssm has to stay around while the session runs.
+func (m sessionMode) managed() bool {
+	return m.reconnect || m.record || m.auditLog != "" || len(m.hooks) > 0
+}
+
+// execSession replaces ssm with the session's command. It only returns if
+// that fails.
+func execSession(session tmux.Session) int {
+	path, err := exec.LookPath(session.Command[0])
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s command not found: %v\n", session.Command[0], err)
+		return 1
+	}
+	err = syscall.Exec(path, session.Command, os.Environ())
+	fmt.Fprintf(os.Stderr, "Failed to execute %s: %v\n", session.Command[0], err)
+	return 1
+}
+
+// runSession runs the session's command as a child of ssm, recording and
+// auditing each connection and reconnecting when it is lost as mode asks.
+// Its hooks run before the first connection and after the last. It returns
+// the command's exit status.
+func runSession(session tmux.Session, mode sessionMode, hosts []config.Host) int {
+	hookRunner := &hooks.Runner{
+		Hooks:     mode.hooks,
+		Host:      findHost(hosts, session.Host),
+		Transport: filepath.Base(session.Command[0]),
+	}
+	if err := hookRunner.PreConnect(); err != nil {
+		fmt.Fprintf(os.Stderr, "Not connecting to %s: %v\n", session.Host, err)
+		if mode.hold {
+			fmt.Fprint(os.Stderr, "Press enter to close this window")
+			bufio.NewReader(os.Stdin).ReadString('\n')
+		}
+		return 1
+	}
+	started := time.Now()
+
+	runner := reconnect.RunAttached
+	if mode.record {
+		runner = func(command []string) (int, error) {
+			recorder := &record.Recorder{
+				Path:    record.Path(mode.recordDir, session.Host, time.Now()),
+				Title:   strings.Join(command, " "),
+				Command: command,
+			}
+			code, err := recorder.Run()
+			if err == nil {
+				fmt.Fprintf(os.Stderr, "Session recorded to %s\r\n", recorder.Path)
+			}
+			return code, err
+		}
+	}
+	if mode.auditLog != "" {
+		logger := &audit.Logger{Path: mode.auditLog, Hosts: hosts}
+		run := runner
+		runner = func(command []string) (int, error) {
+			entry := audit.Entry{Action: audit.Connect, Host: session.Host, Transport: filepath.Base(command[0])}
+			start := time.Now()
+			code, err := run(command)
+			entry.Finish(start, code, err)
+			logAudit(logger, entry)
+			return code, err
+		}
+	}
+
+	var code int
+	var err error
+	if mode.reconnect {
+		supervisor := &reconnect.Supervisor{Host: session.Host, Command: session.Command, Runner: runner}
+		code, err = supervisor.Run()
+	} else {
+		code, err = runner(session.Command)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to execute %s: %v\n", session.Command[0], err)
+	}
+	if err := hookRunner.PostConnect(code, time.Since(started)); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+	}
+	return code
+}
+
+// managedSession returns a session that runs the session's command through
+// "ssm connect", so a tmux window is managed as mode asks.
+func managedSession(session tmux.Session, mode sessionMode) tmux.Session {
+	self, err := os.Executable()
+	if err != nil {
+		self = os.Args[0]
+	}
+	command := []string{self, "connect"}
+	if mode.reconnect {
+		command = append(command, "--reconnect")
+	}
+	if mode.record {
+		command = append(command, "--record")
+	}
+	// tmux windows don't inherit ssm's environment, so settings that may
+	// come from it are passed on explicitly
+	if mode.record && mode.recordDir != "" {
+		command = append(command, "--record-dir", mode.recordDir)
+	}
+	if mode.auditLog != "" {
+		command = append(command, "--audit-log", mode.auditLog)
+	}
+	if mode.settings != "" {
+		command = append(command, "--config", mode.settings)
+	}
+	command = append(append(command, session.Host, "--"), session.Command...)
+	return tmux.Session{Host: session.Host, Name: session.Name, Command: command, Style: session.Style}
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/antonjah/ssm/internal/clipboard"
+	"github.com/antonjah/ssm/internal/config"
+)
+
+// runCopy implements "ssm copy", which copies a host's ssh command,
+// destination, HostName or config block to the clipboard.
+func runCopy(args []string) int {
+	fs := flag.NewFlagSet("copy", flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: ssm copy [flags] <alias>\n\nCopy a host's ssh command, user@hostname, HostName or config block to the\nclipboard. The text is sent to the terminal with OSC 52, which works over SSH\nand inside tmux, and to pbcopy, wl-copy, xclip, xsel or clip.exe if one is\ninstalled.\n\nFlags:\n")
+		fs.PrintDefaults()
+	}
+	format := fs.String("format", config.FormatCommand, "what to copy: "+strings.Join(config.Formats, ", "))
+	printOnly := fs.Bool("print", false, "print the text instead of copying it")
+	positional := parseInterspersed(fs, args)
+
+	if len(positional) != 1 {
+		fs.Usage()
+		return 2
+	}
+
+	hosts, err := loadHosts()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error reading SSH config: %v\n", err)
+		return 1
+	}
+	selected, err := selectHosts(hosts, hostSelector{hosts: positional[0]})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 2
+	}
+	if len(selected) != 1 {
+		fmt.Fprintln(os.Stderr, "Error: copy takes a single host")
+		return 2
+	}
+	text, err := selected[0].Format(*format)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 2
+	}
+
+	if *printOnly {
+		fmt.Println(strings.TrimRight(text, "\n"))
+		return 0
+	}
+	methods, err := clipboard.Copy(text)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error copying to the clipboard: %v\n", err)
+		return 1
+	}
+	fmt.Fprintf(os.Stderr, "Copied %s's %s (%s)\n", selected[0].Alias, *format, strings.Join(methods, ", "))
+	return 0
+}
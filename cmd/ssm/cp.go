@@ -0,0 +1,100 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"os/exec"
+	"time"
+
+	"github.com/antonjah/ssm/internal/audit"
+	"github.com/antonjah/ssm/internal/transfer"
+)
+
+// runCp implements "ssm cp", which copies files to or from a host by alias.
+func runCp(args []string) int {
+	fs := flag.NewFlagSet("cp", flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: ssm cp [flags] <source> <destination>\n\nCopy files to or from a host. One side is alias:path, the other a local\npath, for example 'ssm cp ./file prod-db:/tmp/'. Directories are copied\nrecursively.\n\nFlags:\n")
+		fs.PrintDefaults()
+	}
+	toolName := fs.String("tool", string(transfer.SCP), "copy with scp, rsync or sftp")
+	positional := parseInterspersed(fs, args)
+
+	if len(positional) != 2 {
+		fs.Usage()
+		return 2
+	}
+	tool, err := transfer.ParseTool(*toolName)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 2
+	}
+
+	hosts, err := loadHosts()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error reading SSH config: %v\n", err)
+		return 1
+	}
+	cfg, _, err := loadSettings()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error reading settings: %v\n", err)
+		return 1
+	}
+	aliases := make(map[string]bool, len(hosts))
+	for _, host := range hosts {
+		aliases[host.Alias] = true
+	}
+	isAlias := func(name string) bool { return aliases[name] }
+
+	src, err := transfer.ParseEndpoint(positional[0], isAlias)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 2
+	}
+	dst, err := transfer.ParseEndpoint(positional[1], isAlias)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 2
+	}
+	t, err := transfer.New(tool, src, dst)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 2
+	}
+
+	// On the command line the tool prints its own progress to the terminal
+	// and may prompt for a password
+	t.Prompt = true
+	cmd := t.Command(context.Background(), "")
+	if cmd.Stdin == nil {
+		cmd.Stdin = os.Stdin
+	}
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	entry := audit.CopyEntry(t)
+	start := time.Now()
+	err = cmd.Run()
+
+	var exitErr *exec.ExitError
+	switch {
+	case err == nil:
+		entry.Finish(start, 0, nil)
+	case errors.As(err, &exitErr):
+		entry.Finish(start, exitErr.ExitCode(), nil)
+	default:
+		entry.Finish(start, 1, err)
+	}
+	logAudit(newAuditLogger(cfg, hosts), entry)
+
+	if err != nil {
+		if exitErr != nil {
+			return exitErr.ExitCode()
+		}
+		fmt.Fprintf(os.Stderr, "Error running %s: %v\n", tool, err)
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,153 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/antonjah/ssm/internal/audit"
+	"github.com/antonjah/ssm/internal/remote"
+)
+
+// runExec implements "ssm exec", which runs a command on many hosts at once.
+func runExec(args []string) int {
+	fs := flag.NewFlagSet("exec", flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: ssm exec [flags] -- <command>\n\nRun a command on several hosts in parallel.\n\nFlags:\n")
+		fs.PrintDefaults()
+	}
+
+	var selector hostSelector
+	fs.StringVar(&selector.hosts, "host", "", "comma-separated host aliases")
+	fs.StringVar(&selector.tags, "tag", "", "comma-separated tags; hosts with any of them are selected")
+	fs.StringVar(&selector.filter, "filter", "", "host query, e.g. 'user:deploy -tag:legacy'")
+	workers := fs.Int("j", remote.DefaultWorkers, "number of hosts to run at once")
+	timeout := fs.Duration("timeout", remote.DefaultTimeout, "per-host timeout")
+	group := fs.Bool("group", false, "print each host's output as a block when it finishes")
+	jsonOutput := fs.Bool("json", false, "print results as JSON")
+	fs.Parse(args)
+
+	command := fs.Args()
+	if len(command) == 0 {
+		fs.Usage()
+		return 2
+	}
+	if selector.empty() {
+		fmt.Fprintln(os.Stderr, "Error: select hosts with --host, --tag or --filter")
+		return 2
+	}
+
+	hosts, err := loadHosts()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error reading SSH config: %v\n", err)
+		return 1
+	}
+	cfg, _, err := loadSettings()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error reading settings: %v\n", err)
+		return 1
+	}
+	selected, err := selectHosts(hosts, selector)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 2
+	}
+	if len(selected) == 0 {
+		fmt.Fprintln(os.Stderr, "No hosts matched")
+		return 1
+	}
+
+	targets := aliases(selected)
+	width := 0
+	for _, alias := range targets {
+		width = max(width, len(alias))
+	}
+
+	opts := remote.Options{Workers: *workers, Timeout: *timeout}
+	switch {
+	case *jsonOutput:
+		// Results only
+	case *group:
+		opts.OnResult = func(result remote.Result) {
+			printGroup(os.Stdout, result)
+		}
+	default:
+		opts.OnLine = func(host string, stream remote.Stream, line string) {
+			out := os.Stdout
+			if stream == remote.Stderr {
+				out = os.Stderr
+			}
+			fmt.Fprintf(out, "%-*s | %s\n", width, host, line)
+		}
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	results := remote.Run(ctx, targets, command, opts)
+	logger := newAuditLogger(cfg, hosts)
+	for _, result := range results {
+		logAudit(logger, audit.ExecEntry(strings.Join(command, " "), result))
+	}
+
+	if *jsonOutput {
+		if err := writeJSON(os.Stdout, results); err != nil {
+			fmt.Fprintf(os.Stderr, "Error writing JSON: %v\n", err)
+			return 1
+		}
+	} else {
+		fmt.Println()
+		printSummary(os.Stdout, results)
+	}
+
+	for _, result := range results {
+		if !result.OK() {
+			return 1
+		}
+	}
+	return 0
+}
+
+// printGroup prints a finished host's output as a single block.
+func printGroup(w io.Writer, result remote.Result) {
+	fmt.Fprintf(w, "==> %s (%s) <==\n", result.Host, resultStatus(result))
+	io.WriteString(w, result.Stdout)
+	io.WriteString(w, result.Stderr)
+	if !strings.HasSuffix(result.Stdout+result.Stderr, "\n") && result.Stdout+result.Stderr != "" {
+		fmt.Fprintln(w)
+	}
+}
+
+// printSummary prints a table of exit codes and durations.
+func printSummary(w io.Writer, results []remote.Result) {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "HOST\tEXIT\tDURATION\tSTATUS")
+	failed := 0
+	for _, result := range results {
+		if !result.OK() {
+			failed++
+		}
+		fmt.Fprintf(tw, "%s\t%d\t%s\t%s\n", result.Host, result.ExitCode, result.Duration.Round(time.Millisecond), resultStatus(result))
+	}
+	tw.Flush()
+	fmt.Fprintf(w, "\n%d ok, %d failed\n", len(results)-failed, failed)
+}
+
+// resultStatus returns a short description of a result.
+func resultStatus(result remote.Result) string {
+	switch {
+	case result.OK():
+		return "ok"
+	case result.Err != nil:
+		return result.Err.Error()
+	case result.ExitCode == 255:
+		return "ssh error"
+	}
+	return "failed"
+}
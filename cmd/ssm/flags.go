@@ -0,0 +1,25 @@
+package main
+
+import "flag"
+
+// parseInterspersed parses flags that may appear before or after positional
+// arguments, so "ssm ping web --json" works as well as "ssm ping --json web".
+// Arguments after "--" are always positional.
+func parseInterspersed(fs *flag.FlagSet, args []string) []string {
+	var positional []string
+	for len(args) > 0 {
+		fs.Parse(args)
+		rest := fs.Args()
+		// The flag package stops at the first positional argument or
+		// consumes a "--" terminator
+		if consumed := len(args) - len(rest); consumed > 0 && args[consumed-1] == "--" {
+			return append(positional, rest...)
+		}
+		if len(rest) == 0 {
+			break
+		}
+		positional = append(positional, rest[0])
+		args = rest[1:]
+	}
+	return positional
+}
@@ -0,0 +1,200 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"text/tabwriter"
+	"time"
+
+	"github.com/antonjah/ssm/internal/config"
+	"github.com/antonjah/ssm/internal/forward"
+)
+
+// runFwd implements "ssm fwd", which manages background port forwards.
+func runFwd(args []string) int {
+	if len(args) == 0 {
+		fwdUsage()
+		return 2
+	}
+	switch args[0] {
+	case "ls", "list":
+		return runFwdList(args[1:])
+	case "start":
+		return runFwdStart(args[1:])
+	case "stop":
+		return runFwdStop(args[1:])
+	case "help", "-h", "--help":
+		fwdUsage()
+		return 0
+	}
+	fmt.Fprintf(os.Stderr, "Unknown fwd command %q\n\n", args[0])
+	fwdUsage()
+	return 2
+}
+
+func fwdUsage() {
+	fmt.Fprint(os.Stderr, `Usage: ssm fwd <command> [flags]
+
+Manage port forwards running as background ssh processes.
+
+Commands:
+  ls                         List running forwards
+  start <alias> [-L|-R|-D]   Start forwards, or the host's configured ones
+  stop <id...>               Stop forwards by ID
+`)
+}
+
+func runFwdList(args []string) int {
+	fs := flag.NewFlagSet("fwd ls", flag.ExitOnError)
+	jsonOutput := fs.Bool("json", false, "print forwards as JSON")
+	fs.Parse(args)
+
+	manager, err := forward.NewManager("")
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	forwards, err := manager.List()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error listing forwards: %v\n", err)
+		return 1
+	}
+
+	if *jsonOutput {
+		if err := writeJSON(os.Stdout, forwards); err != nil {
+			fmt.Fprintf(os.Stderr, "Error writing JSON: %v\n", err)
+			return 1
+		}
+		return 0
+	}
+	if len(forwards) == 0 {
+		fmt.Println("No forwards running")
+		return 0
+	}
+	printForwards(os.Stdout, forwards)
+	return 0
+}
+
+// printForwards prints running forwards as a table.
+func printForwards(w io.Writer, forwards []forward.Forward) {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "ID\tHOST\tFORWARD\tPID\tUPTIME")
+	for _, fwd := range forwards {
+		uptime := time.Since(fwd.Started).Round(time.Second)
+		fmt.Fprintf(tw, "%d\t%s\t%s\t%d\t%s\n", fwd.ID, fwd.Host, fwd.Spec, fwd.PID, uptime)
+	}
+	tw.Flush()
+}
+
+func runFwdStart(args []string) int {
+	fs := flag.NewFlagSet("fwd start", flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: ssm fwd start <alias> [-L spec] [-R spec] [-D spec]\n\nStart port forwards through a host. Without forwards, the host's\nLocalForward, RemoteForward and DynamicForward entries are started.\n\nFlags:\n")
+		fs.PrintDefaults()
+	}
+	var specs []config.Forward
+	for _, typ := range []config.ForwardType{config.LocalForward, config.RemoteForward, config.DynamicForward} {
+		fs.Func(string(typ), "forward in ssh's -"+string(typ)+" syntax (repeatable)", func(value string) error {
+			spec, err := config.ParseForward(typ, value)
+			if err == nil {
+				specs = append(specs, spec)
+			}
+			return err
+		})
+	}
+	positional := parseInterspersed(fs, args)
+	if len(positional) != 1 {
+		fs.Usage()
+		return 2
+	}
+
+	hosts, err := loadHosts()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error reading SSH config: %v\n", err)
+		return 1
+	}
+	selected, err := selectHosts(hosts, hostSelector{hosts: positional[0]})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 2
+	}
+	host := selected[0]
+	if len(specs) == 0 {
+		specs = host.Forwards
+	}
+	if len(specs) == 0 {
+		fmt.Fprintf(os.Stderr, "Error: %s has no configured forwards; pass -L, -R or -D\n", host.Alias)
+		return 2
+	}
+
+	manager, err := forward.NewManager("")
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	status := 0
+	for _, spec := range specs {
+		fwd, err := manager.Start(host.Alias, spec)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error starting %s: %v\n", spec, err)
+			status = 1
+			continue
+		}
+		fmt.Printf("Started #%d %s through %s (pid %d)\n", fwd.ID, spec, host.Alias, fwd.PID)
+	}
+	return status
+}
+
+func runFwdStop(args []string) int {
+	fs := flag.NewFlagSet("fwd stop", flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: ssm fwd stop [flags] <id...>\n\nStop port forwards by the ID shown by 'ssm fwd ls'.\n\nFlags:\n")
+		fs.PrintDefaults()
+	}
+	all := fs.Bool("all", false, "stop every forward")
+	positional := parseInterspersed(fs, args)
+	if len(positional) == 0 && !*all {
+		fs.Usage()
+		return 2
+	}
+
+	manager, err := forward.NewManager("")
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+
+	var ids []int
+	if *all {
+		forwards, err := manager.List()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error listing forwards: %v\n", err)
+			return 1
+		}
+		for _, fwd := range forwards {
+			ids = append(ids, fwd.ID)
+		}
+	}
+	for _, arg := range positional {
+		id, err := strconv.Atoi(arg)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: invalid forward ID %q\n", arg)
+			return 2
+		}
+		ids = append(ids, id)
+	}
+
+	status := 0
+	for _, id := range ids {
+		if err := manager.Stop(id); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			status = 1
+			continue
+		}
+		fmt.Printf("Stopped #%d\n", id)
+	}
+	return status
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/antonjah/ssm/internal/config"
+)
+
+// runGraph implements "ssm graph", which prints the jump host topology.
+func runGraph(args []string) int {
+	fs := flag.NewFlagSet("graph", flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: ssm graph [flags]\n\nPrint how hosts are reached through jump hosts. Loops and jump hosts\nthat aren't configured aliases are reported on stderr.\n\nFlags:\n")
+		fs.PrintDefaults()
+	}
+	format := fs.String("format", "dot", "output format: dot or mermaid")
+	fs.Parse(args)
+
+	hosts, err := loadHosts()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error reading SSH config: %v\n", err)
+		return 1
+	}
+	graph := config.NewGraph(hosts)
+
+	switch *format {
+	case "dot":
+		err = graph.WriteDOT(os.Stdout)
+	case "mermaid":
+		err = graph.WriteMermaid(os.Stdout)
+	default:
+		fmt.Fprintf(os.Stderr, "Error: unknown format %q\n", *format)
+		return 2
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error writing graph: %v\n", err)
+		return 1
+	}
+
+	for _, problem := range graph.Problems() {
+		fmt.Fprintf(os.Stderr, "warning: %s: %s\n", problem.Alias, problem.Message)
+	}
+	return 0
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/antonjah/ssm/internal/config"
+	"github.com/antonjah/ssm/internal/search"
+)
+
+// hostSelector holds the host selection flags shared by subcommands.
+type hostSelector struct {
+	hosts  string
+	tags   string
+	filter string
+}
+
+// empty reports whether no selection flag was given.
+func (s hostSelector) empty() bool {
+	return s.hosts == "" && s.tags == "" && s.filter == ""
+}
+
+// selectHosts returns the configured hosts matching the selector, in config
+// order. Hosts named with --host must exist; --tag matches any of the given
+// tags and --filter uses the same query language as the menu. Given together,
+// the flags narrow each other down.
+func selectHosts(all []config.Host, selector hostSelector) ([]config.Host, error) {
+	byAlias := make(map[string]config.Host, len(all))
+	for _, host := range all {
+		byAlias[host.Alias] = host
+	}
+
+	wanted := make(map[string]bool)
+	for _, alias := range splitList(selector.hosts) {
+		if _, ok := byAlias[alias]; !ok {
+			return nil, fmt.Errorf("unknown host %q", alias)
+		}
+		wanted[alias] = true
+	}
+
+	tags := splitList(selector.tags)
+	query := search.Parse(selector.filter)
+
+	var selected []config.Host
+	for _, host := range all {
+		if selector.hosts != "" && !wanted[host.Alias] {
+			continue
+		}
+		if len(tags) > 0 && !hasAnyTag(host, tags) {
+			continue
+		}
+		if _, ok := query.Match(host); !ok {
+			continue
+		}
+		selected = append(selected, host)
+	}
+	return selected, nil
+}
+
+// hasAnyTag reports whether the host has at least one of the tags.
+func hasAnyTag(host config.Host, tags []string) bool {
+	for _, tag := range tags {
+		for _, hostTag := range host.Tags {
+			if strings.EqualFold(tag, hostTag) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// splitList splits a comma-separated flag value.
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// joinList joins positional arguments into a comma-separated flag value.
+func joinList(values []string) string {
+	return strings.Join(values, ",")
+}
+
+// aliases returns the aliases of the hosts.
+func aliases(hosts []config.Host) []string {
+	result := make([]string, len(hosts))
+	for i, host := range hosts {
+		result[i] = cleanAlias(host.Alias)
+	}
+	return result
+}
@@ -0,0 +1,124 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/antonjah/ssm/internal/audit"
+	"github.com/antonjah/ssm/internal/config"
+	"github.com/antonjah/ssm/internal/settings"
+)
+
+// runLog implements "ssm log", which queries the audit log.
+func runLog(args []string) int {
+	fs := flag.NewFlagSet("log", flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: ssm log [flags]\n\nShow the audit log of sessions, remote commands, file copies and config\nedits, oldest first.\n\nFlags:\n")
+		fs.PrintDefaults()
+	}
+	var filter audit.Filter
+	fs.StringVar(&filter.Host, "host", "", "only entries for this alias; wildcards such as 'web*' are allowed")
+	action := fs.String("action", "", "only this action: connect, exec, copy or edit")
+	since := fs.String("since", "", "only entries from this time on, e.g. 24h, 7d or 2026-03-14")
+	until := fs.String("until", "", "only entries before this time")
+	fs.BoolVar(&filter.Failed, "failed", false, "only failed actions")
+	jsonOutput := fs.Bool("json", false, "print entries as JSON")
+	fs.Parse(args)
+
+	if fs.NArg() > 0 {
+		fs.Usage()
+		return 2
+	}
+	filter.Action = audit.Action(*action)
+	now := time.Now()
+	var err error
+	if *since != "" {
+		if filter.Since, err = audit.ParseTime(*since, now); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: --since: %v\n", err)
+			return 2
+		}
+	}
+	if *until != "" {
+		if filter.Until, err = audit.ParseTime(*until, now); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: --until: %v\n", err)
+			return 2
+		}
+	}
+
+	cfg, _, err := loadSettings()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error reading settings: %v\n", err)
+		return 1
+	}
+	path := auditPath(cfg)
+	if path == "" {
+		fmt.Fprintf(os.Stderr, "Auditing is off; set log under [audit] to %q or a path to turn it on\n", settings.AuditOn)
+		return 1
+	}
+	entries, err := audit.Read(path, filter)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error reading audit log: %v\n", err)
+		return 1
+	}
+
+	if *jsonOutput {
+		if entries == nil {
+			entries = []audit.Entry{}
+		}
+		if err := writeJSON(os.Stdout, entries); err != nil {
+			fmt.Fprintf(os.Stderr, "Error writing JSON: %v\n", err)
+			return 1
+		}
+		return 0
+	}
+	if len(entries) == 0 {
+		fmt.Println("No entries")
+		return 0
+	}
+	printAudit(os.Stdout, entries)
+	return 0
+}
+
+// printAudit prints audit entries as a table.
+func printAudit(w io.Writer, entries []audit.Entry) {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "TIME\tACTION\tUSER\tHOST\tTARGET\tVIA\tEXIT\tDURATION\tCOMMAND")
+	for _, entry := range entries {
+		target := entry.HostName
+		if target != "" {
+			target = fmt.Sprintf("%s@%s:%s", entry.RemoteUser, entry.HostName, entry.Port)
+		}
+		command := entry.Command
+		if entry.Error != "" {
+			command = fmt.Sprintf("%s (%s)", command, entry.Error)
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\t%d\t%s\t%s\n",
+			entry.Time.Local().Format("2006-01-02 15:04:05"), entry.Action, entry.User, entry.Host,
+			target, entry.Transport, entry.ExitCode, entry.Duration().Round(time.Millisecond), command)
+	}
+	tw.Flush()
+}
+
+// logAudit appends entry to the audit log, warning if that fails.
+func logAudit(logger *audit.Logger, entry audit.Entry) {
+	if err := logger.Log(entry); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+	}
+}
+
+// newAuditLogger returns the audit logger the settings ask for, or nil if
+// auditing is off.
+func newAuditLogger(cfg settings.Settings, hosts []config.Host) *audit.Logger {
+	return audit.New(auditPath(cfg), hosts)
+}
+
+// auditPath returns the audit log's path, or "" if auditing is off or is
+// on without a known default location.
+func auditPath(cfg settings.Settings) string {
+	defaultPath, _ := audit.DefaultPath()
+	return cfg.Audit.Path(defaultPath)
+}
@@ -2,60 +2,316 @@
 package main
 
 import (
+	"flag"
 	"fmt"
 	"os"
 	"os/exec"
 	"strings"
-	"syscall"
 
 	"github.com/antonjah/ssm/internal/config"
+	"github.com/antonjah/ssm/internal/keys"
 	"github.com/antonjah/ssm/internal/menu"
+	"github.com/antonjah/ssm/internal/record"
+	"github.com/antonjah/ssm/internal/settings"
 	"github.com/antonjah/ssm/internal/tmux"
+	"github.com/antonjah/ssm/internal/transport"
+
+	"github.com/charmbracelet/lipgloss"
 )
 
 func main() {
-	hosts, err := config.GetSSHHosts()
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "exec":
+			os.Exit(runExec(os.Args[2:]))
+		case "ping":
+			os.Exit(runPing(os.Args[2:]))
+		case "check":
+			os.Exit(runCheck(os.Args[2:]))
+		case "graph":
+			os.Exit(runGraph(os.Args[2:]))
+		case "fwd":
+			os.Exit(runFwd(os.Args[2:]))
+		case "cp":
+			os.Exit(runCp(os.Args[2:]))
+		case "copy":
+			os.Exit(runCopy(os.Args[2:]))
+		case "note":
+			os.Exit(runNote(os.Args[2:]))
+		case "log":
+			os.Exit(runLog(os.Args[2:]))
+		case "replay":
+			os.Exit(runReplay(os.Args[2:]))
+		case "connect":
+			os.Exit(runConnect(os.Args[2:]))
+		case "config":
+			os.Exit(runConfig(os.Args[2:]))
+		case "help", "-h", "--help":
+			usage()
+			return
+		}
+	}
+
+	noProbe := flag.Bool("no-probe", false, "don't check host reachability in the background")
+	banner := flag.Bool("banner", false, "read each host's SSH banner while checking reachability")
+	via := flag.String("via", "", "connect with this transport (ssh, mosh, et, autossh or a custom one)")
+	supervise := flag.Bool("reconnect", false, "reconnect with backoff when the connection is lost")
+	recordAll := flag.Bool("record", false, "record sessions to asciinema cast files")
+	flag.Usage = usage
+	flag.Parse()
+
+	cfg, cfgPath, err := loadSettings()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error reading settings: %v\n", err)
+		os.Exit(1)
+	}
+	transports := transportRegistry(cfg)
+	if *via != "" {
+		if _, err := transports.Lookup(*via); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(2)
+		}
+	}
+
+	hosts, err := loadHosts()
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Error reading SSH config: %v\n", err)
 		os.Exit(1)
 	}
 
-	if len(hosts) == 0 {
-		fmt.Fprintf(os.Stderr, "No SSH hosts found in ~/.ssh/config\n")
+	// The terminal's background is only queried for the automatic theme,
+	// and before the menu takes over the terminal
+	colours, err := themeRegistry(cfg).Resolve(cfg.Theme.Name, cfg.Theme.Light, cfg.Theme.Dark, lipgloss.HasDarkBackground)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	km, err := keys.New(cfg.Keys.Preset, cfg.Keys.Bindings)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 		os.Exit(1)
 	}
 
-	host, err := menu.RenderMenu(hosts)
+	// Notes are extra, so the menu opens without them if they can't be read
+	notesStore, err := openNotes(cfg, hosts)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: notes unavailable: %v\n", err)
+		notesStore = nil
+	}
+
+	logger := newAuditLogger(cfg, hosts)
+	choice, err := menu.RenderMenu(hosts, menu.Options{
+		Probe:            cfg.Menu.Probe && !*noProbe,
+		ProbeBanner:      cfg.Menu.Banner || *banner,
+		MaxHeight:        cfg.Menu.Height,
+		HideDescriptions: !cfg.Menu.Descriptions,
+		Preview:          cfg.Menu.Preview,
+		Table:            cfg.Menu.View == settings.ViewTable,
+		Columns:          cfg.Menu.Columns,
+		Mouse:            cfg.Menu.Mouse,
+		Audit:            logger,
+		Notes:            notesStore,
+		Protect:          cfg.Protect,
+		Theme:            colours,
+		Keys:             km,
+	})
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Error rendering menu: %v\n", err)
 		os.Exit(1)
 	}
 
-	if host == "exit" {
+	if len(choice.Hosts) == 0 {
 		os.Exit(0)
 	}
 
 	// Clean up host selection (remove any trailing spaces or extra parts)
-	if strings.Contains(host, " ") {
-		host = strings.Split(host, " ")[0]
+	sessions := make([]tmux.Session, len(choice.Hosts))
+	modes := make([]sessionMode, len(choice.Hosts))
+	for i, alias := range choice.Hosts {
+		alias = cleanAlias(alias)
+		session, err := newSession(transports, hosts, alias, *via)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		session.Name = cfg.Tmux.WindowPrefix + alias
+		host := findHost(hosts, alias)
+		if cfg.Protect.Matches(host) {
+			session.Style = cfg.Protect.Style()
+		}
+		sessions[i] = session
+		modes[i] = sessionMode{
+			reconnect: cfg.Connect.Reconnect || *supervise,
+			record:    cfg.Record.All || *recordAll || record.Required(host, cfg.Record.Tags),
+			recordDir: cfg.Record.Dir,
+			auditLog:  auditPath(cfg),
+			hooks:     cfg.HooksFor(host),
+			settings:  cfgPath,
+		}
+	}
+
+	if choice.Layout != menu.LayoutWindows || len(sessions) > 1 {
+		if err := openMany(sessions, modes, hosts, cfg.Tmux, choice.Layout, commonTag(hosts, choice.Hosts)); err != nil {
+			fmt.Fprintf(os.Stderr, "Error opening hosts: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	session, mode := sessions[0], modes[0]
+	fmt.Printf("Connecting to %s ...\n", session.Host)
+
+	// Handle tmux window management
+	if mode.managed() {
+		tmux.SSHWindow(managedSession(session, mode))
+		os.Exit(runSession(session, mode, hosts))
 	}
+	tmux.SSHWindow(session)
 
-	fmt.Printf("Connecting to %s ...\n", host)
+	// Execute the transport's command
+	os.Exit(execSession(session))
+}
 
-	// Verify ssh command is available
-	sshPath, err := exec.LookPath("ssh")
+// newSession returns the session that connects to alias with the transport
+// chosen by via, the host's "# ssm:via" metadata or the default transport.
+// The transport's program must be installed.
+func newSession(transports transport.Registry, hosts []config.Host, alias, via string) (tmux.Session, error) {
+	host := findHost(hosts, alias)
+	t, err := transports.ForHost(host, via)
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "ssh command not found: %v\n", err)
-		os.Exit(1)
+		return tmux.Session{}, fmt.Errorf("%s: %w", alias, err)
+	}
+	_, command, err := t.Resolve(host)
+	if err != nil {
+		return tmux.Session{}, fmt.Errorf("%s: %w", alias, err)
 	}
+	return tmux.Session{Host: alias, Command: command}, nil
+}
 
-	// Handle tmux window management
-	tmux.SSHWindow(host)
+// usage prints the list of subcommands.
+func usage() {
+	fmt.Fprint(os.Stderr, `Usage: ssm [flags] [command]
+
+Without a command, ssm shows the interactive host menu.
+
+Commands:
+  exec    Run a command on several hosts in parallel
+  ping    Check that hosts accept connections and speak SSH
+  check   Diagnose a host's configuration and connectivity
+  graph   Print the jump host topology as Graphviz DOT or Mermaid
+  fwd     Start, list and stop background port forwards
+  cp      Copy files to or from a host with scp, rsync or sftp
+  copy    Copy a host's ssh command or config block to the clipboard
+  note    Print or edit a host's notes
+  replay  Play back a recorded session
+  log     Show the audit log
+  config  Show ssm's effective settings
 
-	// Execute ssh command
-	err = syscall.Exec(sshPath, []string{"ssh", host}, os.Environ())
+Run 'ssm <command> -h' for details on a command.
+
+Flags:
+`)
+	flag.PrintDefaults()
+}
+
+// loadHosts reads the SSH config and fails if it has no hosts.
+func loadHosts() ([]config.Host, error) {
+	hosts, err := config.GetSSHHosts()
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "Failed to execute ssh: %v\n", err)
-		os.Exit(1)
+		return nil, err
+	}
+	if len(hosts) == 0 {
+		return nil, fmt.Errorf("no SSH hosts found in ~/.ssh/config")
+	}
+	return hosts, nil
+}
+
+// cleanAlias returns the first pattern of a multi-pattern Host line.
+func cleanAlias(host string) string {
+	if strings.Contains(host, " ") {
+		return strings.Split(host, " ")[0]
+	}
+	return host
+}
+
+// commonTag returns the first tag shared by all chosen hosts, or "".
+func commonTag(hosts []config.Host, chosen []string) string {
+	counts := make(map[string]int)
+	var order []string
+	for _, host := range hosts {
+		for _, alias := range chosen {
+			if host.Alias != alias {
+				continue
+			}
+			for _, tag := range host.Tags {
+				if counts[tag] == 0 {
+					order = append(order, tag)
+				}
+				counts[tag]++
+			}
+		}
+	}
+	for _, tag := range order {
+		if counts[tag] == len(chosen) {
+			return tag
+		}
+	}
+	return ""
+}
+
+// findHost returns the host called alias, or a host with just the alias if
+// there is none.
+func findHost(hosts []config.Host, alias string) config.Host {
+	for _, host := range hosts {
+		if host.Alias == alias {
+			return host
+		}
+	}
+	return config.Host{Alias: alias}
+}
+
+// openMany opens several hosts at once. Inside tmux each host gets a window,
+// or a pane of a single tiled or cluster window. Outside tmux the sessions run
+// one after another, since there is nowhere to put them side by side. Each
+// session is reconnected or recorded as its mode asks.
+func openMany(sessions []tmux.Session, modes []sessionMode, hosts []config.Host, names settings.Tmux, layout menu.Layout, tag string) error {
+	if tmux.IsTmuxSession() {
+		for i, session := range sessions {
+			if modes[i].managed() {
+				sessions[i] = managedSession(session, modes[i])
+			}
+		}
+		switch layout {
+		case menu.LayoutTiled:
+			_, err := tmux.SSHTiled(names.TiledWindow, sessions)
+			return err
+		case menu.LayoutCluster:
+			_, err := tmux.SSHCluster(tmux.ClusterName(names.ClusterWindow, tag), sessions, names.SyncKey)
+			return err
+		}
+		return tmux.SSHWindows(sessions)
+	}
+
+	switch layout {
+	case menu.LayoutTiled:
+		return fmt.Errorf("tiled layout requires tmux: %w", tmux.ErrNotInTmux)
+	case menu.LayoutCluster:
+		return fmt.Errorf("cluster mode requires tmux: %w", tmux.ErrNotInTmux)
+	}
+
+	for i, session := range sessions {
+		fmt.Printf("Connecting to %s (%d/%d) ...\n", session.Host, i+1, len(sessions))
+		if modes[i].managed() {
+			runSession(session, modes[i], hosts)
+			continue
+		}
+		cmd := exec.Command(session.Command[0], session.Command[1:]...)
+		cmd.Stdin = os.Stdin
+		cmd.Stdout = os.Stdout
+		cmd.Stderr = os.Stderr
+		if err := cmd.Run(); err != nil {
+			fmt.Fprintf(os.Stderr, "Session to %s ended: %v\n", session.Host, err)
+		}
 	}
+	return nil
 }
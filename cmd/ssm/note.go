@@ -0,0 +1,139 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"time"
+
+	"github.com/antonjah/ssm/internal/audit"
+	"github.com/antonjah/ssm/internal/config"
+	"github.com/antonjah/ssm/internal/notes"
+	"github.com/antonjah/ssm/internal/settings"
+)
+
+// openNotes opens the notes the settings point at and moves the notes of
+// renamed hosts to their new aliases.
+func openNotes(cfg settings.Settings, hosts []config.Host) (*notes.Store, error) {
+	store, err := notes.Open(cfg.Notes.Dir)
+	if err != nil {
+		return nil, err
+	}
+	renames, err := store.Sync(hosts)
+	for _, rename := range renames {
+		fmt.Fprintf(os.Stderr, "Moved the note for %s to %s\n", rename.From, rename.To)
+	}
+	return store, err
+}
+
+// runNote implements "ssm note", which prints or edits a host's notes.
+func runNote(args []string) int {
+	fs := flag.NewFlagSet("note", flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: ssm note [flags] [alias]\n\nPrint a host's Markdown notes, or edit them in $EDITOR with -e. Without an\nalias, list the hosts that have notes.\n\nFlags:\n")
+		fs.PrintDefaults()
+	}
+	edit := fs.Bool("e", false, "edit the notes in $EDITOR")
+	set := fs.Bool("set", false, "replace the notes with standard input")
+	positional := parseInterspersed(fs, args)
+
+	if len(positional) > 1 || (len(positional) == 0 && (*edit || *set)) || (*edit && *set) {
+		fs.Usage()
+		return 2
+	}
+
+	cfg, _, err := loadSettings()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error reading settings: %v\n", err)
+		return 1
+	}
+	hosts, err := loadHosts()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error reading SSH config: %v\n", err)
+		return 1
+	}
+	store, err := openNotes(cfg, hosts)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error reading notes: %v\n", err)
+		return 1
+	}
+
+	if len(positional) == 0 {
+		for _, alias := range store.Aliases() {
+			fmt.Println(alias)
+		}
+		return 0
+	}
+	selected, err := selectHosts(hosts, hostSelector{hosts: positional[0]})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 2
+	}
+	if len(selected) != 1 {
+		fmt.Fprintln(os.Stderr, "Error: note takes a single host")
+		return 2
+	}
+	host := selected[0]
+
+	switch {
+	case *set:
+		text, err := io.ReadAll(os.Stdin)
+		if err == nil {
+			err = store.Write(host, string(text))
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return 1
+		}
+		return 0
+	case *edit:
+		return editNote(store, host, newAuditLogger(cfg, hosts))
+	}
+
+	note, err := store.Read(host.Alias)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	if note == "" {
+		fmt.Fprintf(os.Stderr, "%s has no notes; add some with 'ssm note -e %s'\n", host.Alias, host.Alias)
+		return 1
+	}
+	fmt.Println(note)
+	return 0
+}
+
+// editNote opens host's note in $EDITOR and logs the edit.
+func editNote(store *notes.Store, host config.Host, logger *audit.Logger) int {
+	editor := os.Getenv("EDITOR")
+	if editor == "" {
+		fmt.Fprintln(os.Stderr, "Error: set $EDITOR to edit notes")
+		return 1
+	}
+	path, err := store.Path(host)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+
+	cmd := exec.Command(editor, path)
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = os.Stdin, os.Stdout, os.Stderr
+	entry := audit.Entry{Action: audit.Edit, Host: host.Alias, Transport: editor, Command: path}
+	start := time.Now()
+	err = cmd.Run()
+	code := 0
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		code, err = exitErr.ExitCode(), nil
+	}
+	entry.Finish(start, code, err)
+	logger.Log(entry)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error running %s: %v\n", editor, err)
+		return 1
+	}
+	return code
+}
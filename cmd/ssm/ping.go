@@ -0,0 +1,123 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/antonjah/ssm/internal/config"
+	"github.com/antonjah/ssm/internal/probe"
+)
+
+// pingResult is a probe result for a host that may have been skipped.
+type pingResult struct {
+	probe.Result
+	Skipped string `json:"skipped,omitempty"`
+}
+
+// runPing implements "ssm ping", which reports TCP reachability, the SSH
+// banner and connect latency for each host.
+func runPing(args []string) int {
+	fs := flag.NewFlagSet("ping", flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: ssm ping [flags] [alias...]\n\nCheck that hosts accept TCP connections and speak SSH.\n\nFlags:\n")
+		fs.PrintDefaults()
+	}
+
+	var selector hostSelector
+	fs.StringVar(&selector.tags, "tag", "", "comma-separated tags; hosts with any of them are selected")
+	fs.StringVar(&selector.filter, "filter", "", "host query, e.g. 'user:deploy -tag:legacy'")
+	timeout := fs.Duration("timeout", probe.DefaultTimeout, "connect and banner timeout")
+	jsonOutput := fs.Bool("json", false, "print results as JSON")
+	positional := parseInterspersed(fs, args)
+	selector.hosts = joinList(positional)
+
+	if selector.empty() {
+		fs.Usage()
+		return 2
+	}
+
+	hosts, err := loadHosts()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error reading SSH config: %v\n", err)
+		return 1
+	}
+	selected, err := selectHosts(hosts, selector)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 2
+	}
+	if len(selected) == 0 {
+		fmt.Fprintln(os.Stderr, "No hosts matched")
+		return 1
+	}
+
+	results := pingHosts(context.Background(), selected, probe.Options{Timeout: *timeout, Banner: true})
+
+	if *jsonOutput {
+		if err := writeJSON(os.Stdout, results); err != nil {
+			fmt.Fprintf(os.Stderr, "Error writing JSON: %v\n", err)
+			return 1
+		}
+	} else {
+		printPing(os.Stdout, results)
+	}
+
+	for _, result := range results {
+		if result.Skipped == "" && !result.Reachable {
+			return 1
+		}
+	}
+	return 0
+}
+
+// pingHosts probes every host that can be reached directly.
+func pingHosts(ctx context.Context, hosts []config.Host, opts probe.Options) []pingResult {
+	results := make([]pingResult, len(hosts))
+	var targets []probe.Target
+	var indexes []int
+	for i, host := range hosts {
+		results[i].Alias = host.Alias
+		results[i].Address = host.Address()
+		if host.Proxied() {
+			results[i].Skipped = "reached through a jump host"
+			continue
+		}
+		targets = append(targets, probe.Target{Alias: host.Alias, Address: host.Address()})
+		indexes = append(indexes, i)
+	}
+
+	for i, result := range probe.ProbeAll(ctx, targets, opts, nil) {
+		results[indexes[i]].Result = result
+	}
+	return results
+}
+
+// printPing prints ping results as a table.
+func printPing(w io.Writer, results []pingResult) {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "HOST\tADDRESS\tSTATUS\tLATENCY\tBANNER")
+	for _, result := range results {
+		status, latency, detail := "up", result.Latency.Round(100*time.Microsecond).String(), result.Banner
+		switch {
+		case result.Skipped != "":
+			status, latency, detail = "skipped", "-", result.Skipped
+		case !result.Reachable:
+			status, latency, detail = "down", "-", result.Error
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\n", result.Alias, result.Address, status, latency, detail)
+	}
+	tw.Flush()
+}
+
+// writeJSON writes v as indented JSON.
+func writeJSON(w io.Writer, v any) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(v)
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/antonjah/ssm/internal/record"
+	"github.com/charmbracelet/x/term"
+)
+
+// runReplay implements "ssm replay", which plays a recorded session back in
+// the terminal.
+func runReplay(args []string) int {
+	fs := flag.NewFlagSet("replay", flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: ssm replay [flags] <file.cast>\n\nPlay a recorded session back. While it plays, space pauses, + and -\nchange the speed, . skips a pause and q quits.\n\nFlags:\n")
+		fs.PrintDefaults()
+	}
+	speed := fs.Float64("speed", 1, "playback speed multiplier")
+	idle := fs.Duration("idle", 0, "cap pauses between output at this duration, e.g. 2s")
+	positional := parseInterspersed(fs, args)
+
+	if len(positional) != 1 {
+		fs.Usage()
+		return 2
+	}
+	if *speed < record.MinSpeed || *speed > record.MaxSpeed {
+		fmt.Fprintf(os.Stderr, "Error: speed must be between %g and %g\n", record.MinSpeed, record.MaxSpeed)
+		return 2
+	}
+
+	file, err := os.Open(positional[0])
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	header, events, err := record.Read(file)
+	file.Close()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error reading %s: %v\n", positional[0], err)
+		return 1
+	}
+
+	player := &record.Player{Speed: *speed, MaxIdle: *idle, Out: os.Stdout}
+	if term.IsTerminal(os.Stdin.Fd()) {
+		player.In = os.Stdin
+	}
+	if width, height, err := term.GetSize(os.Stdout.Fd()); err == nil && (width < header.Width || height < header.Height) {
+		fmt.Fprintf(os.Stderr, "Warning: recorded at %dx%d, terminal is %dx%d\n", header.Width, header.Height, width, height)
+	}
+	if header.Timestamp != 0 {
+		fmt.Fprintf(os.Stderr, "Replaying %s recorded %s\n", header.Title, time.Unix(header.Timestamp, 0).Format("2006-01-02 15:04"))
+	}
+
+	if err := player.Play(events); err != nil {
+		fmt.Fprintf(os.Stderr, "\nError: %v\n", err)
+		return 1
+	}
+	// Leave the terminal in a sane state if the recording ended mid-way
+	fmt.Print("\x1b[0m\n")
+	return 0
+}
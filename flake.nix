@@ -12,7 +12,9 @@
         pname = "ssm";
         version = "1.0.3";
         src = ./.;
-        vendorHash = "sha256-WukLNCsgqAck5LMmY//kFfYst2U1JmKt73BT4H4QcVQ=";
+        # Recompute whenever go.mod or go.sum change, or the build fails
+        # with a hash mismatch.
+        vendorHash = "sha256-sG5Y6NLET/0fEOe/1M84BxTTFP1sQG4CI0qJOUYecno=";
 
         meta = with pkgs.lib; {
           description = "ssm - a TUI for managing ssh connections";
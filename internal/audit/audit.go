@@ -0,0 +1,288 @@
+// Package audit keeps a local JSON-lines log of what ssm did to which host:
+// sessions, remote commands, file copies and config edits.
+package audit
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/user"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/antonjah/ssm/internal/config"
+	"github.com/antonjah/ssm/internal/remote"
+	"github.com/antonjah/ssm/internal/transfer"
+	"github.com/antonjah/ssm/internal/xdg"
+)
+
+// Action is the kind of thing ssm did.
+type Action string
+
+// Actions recorded in the audit log.
+const (
+	Connect Action = "connect"
+	Exec    Action = "exec"
+	Copy    Action = "copy"
+	Edit    Action = "edit"
+)
+
+// Entry is a single line of the audit log.
+type Entry struct {
+	Time   time.Time `json:"time"`
+	Action Action    `json:"action"`
+	// User is the local user who ran ssm.
+	User string `json:"user"`
+	// Host is the alias acted on, if any.
+	Host string `json:"host,omitempty"`
+	// HostName, RemoteUser and Port are the effective connection settings.
+	HostName   string `json:"hostname,omitempty"`
+	RemoteUser string `json:"remote_user,omitempty"`
+	Port       string `json:"port,omitempty"`
+	// Transport is the program used, e.g. "ssh", "mosh" or "rsync".
+	Transport string `json:"transport,omitempty"`
+	// Command is the remote command, the copy's source and destination or
+	// the edited file.
+	Command    string `json:"command,omitempty"`
+	ExitCode   int    `json:"exit_code"`
+	DurationMS int64  `json:"duration_ms"`
+	Error      string `json:"error,omitempty"`
+}
+
+// Failed reports whether the action failed.
+func (e Entry) Failed() bool {
+	return e.ExitCode != 0 || e.Error != ""
+}
+
+// Duration returns how long the action took.
+func (e Entry) Duration() time.Duration {
+	return time.Duration(e.DurationMS) * time.Millisecond
+}
+
+// Finish records the outcome of an action that started at start.
+func (e *Entry) Finish(start time.Time, code int, err error) {
+	e.Time = start
+	e.DurationMS = time.Since(start).Milliseconds()
+	e.ExitCode = code
+	if err != nil {
+		e.Error = err.Error()
+		if code == 0 {
+			e.ExitCode = 1
+		}
+	}
+}
+
+// ExecEntry returns the entry for a command run on a host.
+func ExecEntry(command string, result remote.Result) Entry {
+	entry := Entry{
+		Time:       time.Now().Add(-result.Duration),
+		Action:     Exec,
+		Host:       result.Host,
+		Transport:  "ssh",
+		Command:    command,
+		ExitCode:   result.ExitCode,
+		DurationMS: result.Duration.Milliseconds(),
+	}
+	switch {
+	case result.TimedOut:
+		entry.Error = "timed out"
+	case result.Err != nil:
+		entry.Error = result.Err.Error()
+	}
+	return entry
+}
+
+// CopyEntry returns the entry for a file copy, with the host's user
+// overriding the configured one.
+func CopyEntry(t transfer.Transfer) Entry {
+	return Entry{
+		Action:     Copy,
+		Host:       t.Host().Host,
+		RemoteUser: t.Host().User,
+		Transport:  string(t.Tool),
+		Command:    t.Source.String() + " → " + t.Dest.String(),
+	}
+}
+
+// Logger appends entries to an audit log. A nil Logger discards them, so
+// callers don't need to check whether auditing is on.
+type Logger struct {
+	// Path is the log file.
+	Path string
+	// Hosts fill in the effective settings of entries that only have an alias.
+	Hosts []config.Host
+}
+
+// DefaultPath returns where the audit log is kept when ssm's settings turn
+// it on without picking a path: audit.log in ssm's state directory.
+func DefaultPath() (string, error) {
+	dir, err := xdg.StateDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "audit.log"), nil
+}
+
+// New returns a Logger writing to p, or nil if p is empty because auditing
+// is off.
+func New(p string, hosts []config.Host) *Logger {
+	if p == "" {
+		return nil
+	}
+	return &Logger{Path: p, Hosts: hosts}
+}
+
+// Log appends entry to the log, filling in the time, local user and the
+// host's effective settings where they are missing.
+func (l *Logger) Log(entry Entry) error {
+	if l == nil {
+		return nil
+	}
+	if entry.Time.IsZero() {
+		entry.Time = time.Now()
+	}
+	if entry.User == "" {
+		entry.User = localUser()
+	}
+	if entry.Host != "" {
+		l.resolve(&entry)
+	}
+
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(l.Path), 0o700); err != nil {
+		return fmt.Errorf("failed to create audit log directory: %w", err)
+	}
+	file, err := os.OpenFile(l.Path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o600)
+	if err != nil {
+		return fmt.Errorf("failed to open audit log: %w", err)
+	}
+	defer file.Close()
+
+	// Several ssm processes may log at once, e.g. one per tmux window
+	if err := syscall.Flock(int(file.Fd()), syscall.LOCK_EX); err != nil {
+		return fmt.Errorf("failed to lock audit log: %w", err)
+	}
+	defer syscall.Flock(int(file.Fd()), syscall.LOCK_UN)
+	_, err = file.Write(append(line, '\n'))
+	return err
+}
+
+// resolve fills in the effective HostName, User and Port of entry's host
+// the way ssh defaults them.
+func (l *Logger) resolve(entry *Entry) {
+	host := config.Host{Alias: entry.Host}
+	for _, h := range l.Hosts {
+		if h.Alias == entry.Host {
+			host = h
+			break
+		}
+	}
+	if entry.HostName == "" {
+		entry.HostName = host.EffectiveHostName()
+	}
+	// ssh logs in as the local user the entry was recorded for when the host
+	// has no User
+	if entry.RemoteUser == "" {
+		entry.RemoteUser = host.User
+		if entry.RemoteUser == "" {
+			entry.RemoteUser = entry.User
+		}
+	}
+	if entry.Port == "" {
+		entry.Port = host.EffectivePort()
+	}
+}
+
+// localUser returns the name of the user running ssm.
+func localUser() string {
+	if u, err := user.Current(); err == nil {
+		return u.Username
+	}
+	return os.Getenv("USER")
+}
+
+// Filter selects entries from the log. Zero fields match everything.
+type Filter struct {
+	// Host matches aliases, with shell-style wildcards.
+	Host   string
+	Action Action
+	Since  time.Time
+	Until  time.Time
+	// Failed selects only failed actions.
+	Failed bool
+}
+
+// Match reports whether entry passes the filter.
+func (f Filter) Match(entry Entry) bool {
+	if f.Host != "" {
+		if ok, _ := path.Match(f.Host, entry.Host); !ok {
+			return false
+		}
+	}
+	switch {
+	case f.Action != "" && entry.Action != f.Action:
+		return false
+	case !f.Since.IsZero() && entry.Time.Before(f.Since):
+		return false
+	case !f.Until.IsZero() && !entry.Time.Before(f.Until):
+		return false
+	case f.Failed && !entry.Failed():
+		return false
+	}
+	return true
+}
+
+// Read returns the entries in the log at p that match filter, oldest first.
+// Lines that aren't valid entries are skipped. A missing log has no entries.
+func Read(p string, filter Filter) ([]Entry, error) {
+	file, err := os.Open(p)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var entries []Entry
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		var entry Entry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			continue
+		}
+		if filter.Match(entry) {
+			entries = append(entries, entry)
+		}
+	}
+	return entries, scanner.Err()
+}
+
+// ParseTime parses a time for a filter: a duration before now such as "24h"
+// or "7d", a date such as "2026-03-14", or an RFC 3339 timestamp.
+func ParseTime(s string, now time.Time) (time.Time, error) {
+	if days, ok := strings.CutSuffix(s, "d"); ok {
+		if n, err := strconv.Atoi(days); err == nil {
+			return now.AddDate(0, 0, -n), nil
+		}
+	}
+	if d, err := time.ParseDuration(s); err == nil {
+		return now.Add(-d), nil
+	}
+	if t, err := time.ParseInLocation("2006-01-02", s, now.Location()); err == nil {
+		return t, nil
+	}
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t, nil
+	}
+	return time.Time{}, fmt.Errorf("invalid time %q: use a duration like 24h or 7d, a date or an RFC 3339 timestamp", s)
+}
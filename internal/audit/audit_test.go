@@ -0,0 +1,146 @@
+package audit
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/antonjah/ssm/internal/config"
+	"github.com/antonjah/ssm/internal/remote"
+	"github.com/antonjah/ssm/internal/transfer"
+)
+
+func TestDefaultPath(t *testing.T) {
+	t.Setenv("XDG_STATE_HOME", "/tmp/state")
+	if p, err := DefaultPath(); err != nil || p != "/tmp/state/ssm/audit.log" {
+		t.Errorf("Expected /tmp/state/ssm/audit.log, got %s (%v)", p, err)
+	}
+	if logger := New("", nil); logger != nil {
+		t.Errorf("Expected auditing to be off, got %+v", logger)
+	}
+}
+
+func TestLogger_Log(t *testing.T) {
+	logger := &Logger{
+		Path:  filepath.Join(t.TempDir(), "ssm", "audit.log"),
+		Hosts: []config.Host{{Alias: "db", HostName: "10.0.0.5", User: "deploy", Port: "2222"}},
+	}
+	start := time.Date(2026, 3, 14, 9, 0, 0, 0, time.UTC)
+
+	entries := []Entry{
+		{Time: start, Action: Connect, User: "alice", Host: "db", Transport: "ssh", DurationMS: 61000},
+		{Time: start.Add(time.Hour), Action: Exec, User: "alice", Host: "web", Command: "uptime", ExitCode: 255},
+		{Time: start.Add(2 * time.Hour), Action: Edit, User: "alice", Command: "~/.ssh/config"},
+	}
+	for _, entry := range entries {
+		if err := logger.Log(entry); err != nil {
+			t.Fatalf("Log failed: %v", err)
+		}
+	}
+	if info, err := os.Stat(logger.Path); err != nil || info.Mode().Perm() != 0o600 {
+		t.Errorf("Expected a private log file, got %v (%v)", info, err)
+	}
+
+	all, err := Read(logger.Path, Filter{})
+	if err != nil || len(all) != 3 {
+		t.Fatalf("Expected 3 entries, got %d (%v)", len(all), err)
+	}
+	if db := all[0]; db.HostName != "10.0.0.5" || db.RemoteUser != "deploy" || db.Port != "2222" {
+		t.Errorf("Expected db's effective settings, got %+v", db)
+	}
+	if web := all[1]; web.HostName != "web" || web.RemoteUser != "alice" || web.Port != "22" {
+		t.Errorf("Expected ssh's defaults for web, got %+v", web)
+	}
+	if edit := all[2]; edit.HostName != "" || edit.Port != "" {
+		t.Errorf("Expected no host settings for an edit, got %+v", edit)
+	}
+}
+
+func TestFilter_Match(t *testing.T) {
+	start := time.Date(2026, 3, 14, 9, 0, 0, 0, time.UTC)
+	entry := Entry{Time: start, Action: Exec, Host: "web1", ExitCode: 1}
+
+	tests := []struct {
+		filter   Filter
+		expected bool
+	}{
+		{Filter{}, true},
+		{Filter{Host: "web*"}, true},
+		{Filter{Host: "db"}, false},
+		{Filter{Action: Connect}, false},
+		{Filter{Since: start}, true},
+		{Filter{Since: start.Add(time.Second)}, false},
+		{Filter{Until: start}, false},
+		{Filter{Until: start.Add(time.Second)}, true},
+		{Filter{Failed: true}, true},
+	}
+	for _, test := range tests {
+		if got := test.filter.Match(entry); got != test.expected {
+			t.Errorf("Filter %+v: expected %v, got %v", test.filter, test.expected, got)
+		}
+	}
+	if (Filter{Failed: true}).Match(Entry{Action: Connect}) {
+		t.Error("Expected a successful entry not to match --failed")
+	}
+}
+
+func TestRead(t *testing.T) {
+	if entries, err := Read(filepath.Join(t.TempDir(), "missing.log"), Filter{}); err != nil || entries != nil {
+		t.Errorf("Expected no entries for a missing log, got %v (%v)", entries, err)
+	}
+
+	path := filepath.Join(t.TempDir(), "audit.log")
+	content := `{"time":"2026-03-14T09:00:00Z","action":"connect","user":"alice","host":"db","exit_code":0,"duration_ms":5}
+not json
+{"time":"2026-03-14T10:00:00Z","action":"copy","user":"alice","host":"web","exit_code":1,"duration_ms":7}
+`
+	os.WriteFile(path, []byte(content), 0o600)
+	entries, err := Read(path, Filter{Failed: true})
+	if err != nil || len(entries) != 1 || entries[0].Host != "web" {
+		t.Errorf("Expected the failed copy, got %+v (%v)", entries, err)
+	}
+}
+
+func TestEntries(t *testing.T) {
+	result := remote.Result{Host: "web", ExitCode: 0, Duration: 2 * time.Second, TimedOut: true}
+	exec := ExecEntry("uptime", result)
+	if exec.Action != Exec || exec.Host != "web" || exec.DurationMS != 2000 || exec.Error != "timed out" || !exec.Failed() {
+		t.Errorf("Unexpected exec entry %+v", exec)
+	}
+
+	cp := CopyEntry(transfer.Transfer{
+		Tool:   transfer.Rsync,
+		Source: transfer.Endpoint{Path: "./build"},
+		Dest:   transfer.Endpoint{User: "root", Host: "web", Path: "/srv"},
+	})
+	if cp.Host != "web" || cp.RemoteUser != "root" || cp.Transport != "rsync" || cp.Command != "./build → root@web:/srv" {
+		t.Errorf("Unexpected copy entry %+v", cp)
+	}
+
+	cp.Finish(time.Now(), 0, errors.New("connection refused"))
+	if cp.ExitCode != 1 || !strings.Contains(cp.Error, "refused") {
+		t.Errorf("Expected a failed copy, got %+v", cp)
+	}
+}
+
+func TestParseTime(t *testing.T) {
+	now := time.Date(2026, 3, 14, 12, 0, 0, 0, time.UTC)
+	tests := map[string]time.Time{
+		"24h":                  now.Add(-24 * time.Hour),
+		"7d":                   now.AddDate(0, 0, -7),
+		"2026-03-01":           time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC),
+		"2026-03-01T08:30:00Z": time.Date(2026, 3, 1, 8, 30, 0, 0, time.UTC),
+	}
+	for input, expected := range tests {
+		got, err := ParseTime(input, now)
+		if err != nil || !got.Equal(expected) {
+			t.Errorf("ParseTime(%q): expected %s, got %s (%v)", input, expected, got, err)
+		}
+	}
+	if _, err := ParseTime("yesterday", now); err == nil {
+		t.Error("Expected an error for an invalid time")
+	}
+}
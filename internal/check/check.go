@@ -0,0 +1,259 @@
+// Package check diagnoses common problems with an SSH host's configuration
+// and connectivity.
+package check
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/antonjah/ssm/internal/config"
+	"github.com/antonjah/ssm/internal/probe"
+)
+
+// Status is the outcome of a single check.
+type Status string
+
+const (
+	// StatusOK means the check passed.
+	StatusOK Status = "ok"
+	// StatusWarn means something may be wrong but won't stop a connection.
+	StatusWarn Status = "warn"
+	// StatusFail means the connection is likely to fail.
+	StatusFail Status = "fail"
+	// StatusSkip means the check doesn't apply to this host.
+	StatusSkip Status = "skip"
+)
+
+// Result is the outcome of a single named check.
+type Result struct {
+	Name   string `json:"name"`
+	Status Status `json:"status"`
+	Detail string `json:"detail"`
+}
+
+// Report holds every check run for a host.
+type Report struct {
+	Alias   string   `json:"alias"`
+	Results []Result `json:"checks"`
+}
+
+// OK reports whether no check failed.
+func (r Report) OK() bool {
+	for _, result := range r.Results {
+		if result.Status == StatusFail {
+			return false
+		}
+	}
+	return true
+}
+
+// Options configures the checks.
+type Options struct {
+	// Timeout bounds DNS lookups and the TCP connection.
+	Timeout time.Duration
+	// HomeDir is used to expand "~" in paths. Defaults to the user's home.
+	HomeDir string
+	// KnownHostsFiles are searched for the host key. Defaults to the files
+	// ssh reads.
+	KnownHostsFiles []string
+}
+
+// defaults fills in unset options.
+func (o Options) defaults() Options {
+	if o.Timeout <= 0 {
+		o.Timeout = probe.DefaultTimeout
+	}
+	if o.HomeDir == "" {
+		o.HomeDir, _ = os.UserHomeDir()
+	}
+	if o.KnownHostsFiles == nil {
+		o.KnownHostsFiles = []string{
+			filepath.Join(o.HomeDir, ".ssh", "known_hosts"),
+			filepath.Join(o.HomeDir, ".ssh", "known_hosts2"),
+			"/etc/ssh/ssh_known_hosts",
+			"/etc/ssh/ssh_known_hosts2",
+		}
+	}
+	return o
+}
+
+// Run runs every check for host. hosts is the full configuration, used to
+// resolve jump hosts by alias.
+func Run(ctx context.Context, host config.Host, hosts []config.Host, opts Options) Report {
+	opts = opts.defaults()
+	return Report{
+		Alias: host.Alias,
+		Results: []Result{
+			checkDNS(ctx, host, opts),
+			checkReachability(ctx, host, opts),
+			checkIdentityFiles(host, opts),
+			checkProxyJump(ctx, host, hosts, opts),
+			checkKnownHosts(host, opts),
+		},
+	}
+}
+
+// resolve looks up name, returning its addresses. IP literals resolve to
+// themselves.
+func resolve(ctx context.Context, name string, timeout time.Duration) ([]string, error) {
+	if net.ParseIP(name) != nil {
+		return []string{name}, nil
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	return net.DefaultResolver.LookupHost(ctx, name)
+}
+
+func checkDNS(ctx context.Context, host config.Host, opts Options) Result {
+	result := Result{Name: "DNS"}
+	name := host.EffectiveHostName()
+	if net.ParseIP(name) != nil {
+		result.Status = StatusOK
+		result.Detail = name + " is an IP address"
+		return result
+	}
+
+	addrs, err := resolve(ctx, name, opts.Timeout)
+	switch {
+	case err == nil:
+		result.Status = StatusOK
+		result.Detail = fmt.Sprintf("%s resolves to %s", name, strings.Join(addrs, ", "))
+	case host.Proxied():
+		// The name may only resolve from the jump host
+		result.Status = StatusWarn
+		result.Detail = fmt.Sprintf("%s does not resolve locally; it may resolve from the jump host", name)
+	default:
+		result.Status = StatusFail
+		result.Detail = fmt.Sprintf("cannot resolve %s: %v", name, err)
+	}
+	return result
+}
+
+func checkReachability(ctx context.Context, host config.Host, opts Options) Result {
+	result := Result{Name: "Reachability"}
+	if host.Proxied() {
+		result.Status = StatusSkip
+		result.Detail = "reached through a jump host"
+		return result
+	}
+
+	target := probe.Target{Alias: host.Alias, Address: host.Address()}
+	probed := probe.Probe(ctx, target, probe.Options{Timeout: opts.Timeout, Banner: true})
+	if !probed.Reachable {
+		result.Status = StatusFail
+		result.Detail = probed.Error
+		return result
+	}
+
+	result.Status = StatusOK
+	result.Detail = fmt.Sprintf("%s connected in %s", target.Address, probed.Latency.Round(100*time.Microsecond))
+	switch {
+	case strings.HasPrefix(probed.Banner, "SSH-"):
+		result.Detail += ", " + probed.Banner
+	case probed.Banner != "":
+		result.Status = StatusWarn
+		result.Detail += fmt.Sprintf(", unexpected banner %q", probed.Banner)
+	default:
+		result.Status = StatusWarn
+		result.Detail += ", no SSH banner received"
+	}
+	return result
+}
+
+func checkIdentityFiles(host config.Host, opts Options) Result {
+	result := Result{Name: "IdentityFile"}
+	if len(host.IdentityFiles) == 0 {
+		result.Status = StatusSkip
+		result.Detail = "none configured"
+		return result
+	}
+
+	var details []string
+	result.Status = StatusOK
+	for _, identity := range host.IdentityFiles {
+		path := expandPath(identity, opts.HomeDir)
+		info, err := os.Stat(path)
+		switch {
+		case err != nil:
+			result.Status = StatusFail
+			details = append(details, fmt.Sprintf("%s: %v", path, err))
+		case info.Mode().Perm()&0o077 != 0:
+			// ssh refuses private keys that other users can read
+			result.Status = StatusFail
+			details = append(details, fmt.Sprintf("%s: permissions %04o are too open", path, info.Mode().Perm()))
+		default:
+			details = append(details, fmt.Sprintf("%s: ok", path))
+		}
+	}
+	result.Detail = strings.Join(details, "; ")
+	return result
+}
+
+func checkProxyJump(ctx context.Context, host config.Host, hosts []config.Host, opts Options) Result {
+	result := Result{Name: "ProxyJump"}
+	if len(host.Jumps()) == 0 {
+		result.Status = StatusSkip
+		result.Detail = "no jump host"
+		return result
+	}
+
+	graph := config.NewGraph(append(append([]config.Host(nil), hosts...), host))
+	chain, err := graph.Chain(host.Alias)
+	if err != nil {
+		result.Status = StatusFail
+		result.Detail = err.Error()
+		return result
+	}
+
+	// Jump hosts that aren't aliases must at least resolve
+	for _, hop := range chain[:len(chain)-1] {
+		if hop.Alias != "" {
+			continue
+		}
+		if _, err := resolve(ctx, hop.Host, opts.Timeout); err != nil {
+			result.Status = StatusFail
+			result.Detail = fmt.Sprintf("jump host %s is not an alias and does not resolve", hop.Host)
+			return result
+		}
+	}
+
+	result.Status = StatusOK
+	result.Detail = config.Route(chain)
+	return result
+}
+
+func checkKnownHosts(host config.Host, opts Options) Result {
+	result := Result{Name: "KnownHosts"}
+	name := knownHostName(host.EffectiveHostName(), host.EffectivePort())
+
+	found, file, err := inKnownHosts(opts.KnownHostsFiles, name)
+	switch {
+	case err != nil:
+		result.Status = StatusWarn
+		result.Detail = err.Error()
+	case found:
+		result.Status = StatusOK
+		result.Detail = fmt.Sprintf("%s found in %s", name, file)
+	default:
+		result.Status = StatusWarn
+		result.Detail = fmt.Sprintf("%s not in known_hosts; ssh will ask to verify the key", name)
+	}
+	return result
+}
+
+// expandPath expands a leading "~" and the "%d" token to the home directory.
+func expandPath(path, home string) string {
+	path = strings.ReplaceAll(path, "%d", home)
+	if path == "~" {
+		return home
+	}
+	if strings.HasPrefix(path, "~/") {
+		return filepath.Join(home, path[2:])
+	}
+	return path
+}
@@ -0,0 +1,154 @@
+package check
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha1"
+	"encoding/base64"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/antonjah/ssm/internal/config"
+)
+
+func hashHost(salt []byte, name string) string {
+	mac := hmac.New(sha1.New, salt)
+	mac.Write([]byte(name))
+	return "|1|" + base64.StdEncoding.EncodeToString(salt) + "|" + base64.StdEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func TestMatchHostPatterns(t *testing.T) {
+	tests := []struct {
+		patterns string
+		name     string
+		expected bool
+	}{
+		{"example.com,10.0.0.1", "10.0.0.1", true},
+		{"*.example.com", "web.example.com", true},
+		{"*.example.com,!db.example.com", "db.example.com", false},
+		{"web?.example.com", "web1.example.com", true},
+		{"[example.com]:2222", "[example.com]:2222", true},
+		{"example.com", "[example.com]:2222", false},
+		{hashHost([]byte("0123456789abcdef0123"), "secret.example.com"), "secret.example.com", true},
+		{hashHost([]byte("0123456789abcdef0123"), "secret.example.com"), "other.example.com", false},
+	}
+
+	for _, test := range tests {
+		if got := matchHostPatterns(test.patterns, test.name); got != test.expected {
+			t.Errorf("matchHostPatterns(%q, %q): expected %v, got %v", test.patterns, test.name, test.expected, got)
+		}
+	}
+}
+
+func TestCheckKnownHosts(t *testing.T) {
+	knownHosts := filepath.Join(t.TempDir(), "known_hosts")
+	content := "@revoked revoked.example.com ssh-ed25519 AAAA\n" +
+		"web.example.com ssh-ed25519 AAAA\n" +
+		"[db.example.com]:2222 ssh-ed25519 AAAA\n"
+	if err := os.WriteFile(knownHosts, []byte(content), 0600); err != nil {
+		t.Fatalf("Failed to write known_hosts: %v", err)
+	}
+	opts := Options{KnownHostsFiles: []string{knownHosts, "/non/existent"}}
+
+	tests := []struct {
+		host     config.Host
+		expected Status
+	}{
+		{config.Host{Alias: "web", HostName: "web.example.com"}, StatusOK},
+		{config.Host{Alias: "db", HostName: "db.example.com", Port: "2222"}, StatusOK},
+		{config.Host{Alias: "db", HostName: "db.example.com"}, StatusWarn},
+		{config.Host{Alias: "revoked.example.com"}, StatusWarn},
+	}
+	for _, test := range tests {
+		if result := checkKnownHosts(test.host, opts); result.Status != test.expected {
+			t.Errorf("Host %+v: expected %s, got %s (%s)", test.host, test.expected, result.Status, result.Detail)
+		}
+	}
+}
+
+func TestCheckIdentityFiles(t *testing.T) {
+	home := t.TempDir()
+	os.WriteFile(filepath.Join(home, "good"), []byte("key"), 0600)
+	os.WriteFile(filepath.Join(home, "open"), []byte("key"), 0644)
+	opts := Options{HomeDir: home}
+
+	tests := []struct {
+		files    []string
+		expected Status
+	}{
+		{nil, StatusSkip},
+		{[]string{"~/good"}, StatusOK},
+		{[]string{"%d/good", "~/open"}, StatusFail},
+		{[]string{"~/missing"}, StatusFail},
+	}
+	for _, test := range tests {
+		result := checkIdentityFiles(config.Host{Alias: "web", IdentityFiles: test.files}, opts)
+		if result.Status != test.expected {
+			t.Errorf("Files %v: expected %s, got %s (%s)", test.files, test.expected, result.Status, result.Detail)
+		}
+	}
+}
+
+func TestCheckProxyJump(t *testing.T) {
+	hosts := []config.Host{
+		{Alias: "bastion", HostName: "203.0.113.1"},
+		{Alias: "jump", HostName: "10.0.0.1", ProxyJump: "admin@bastion:2222"},
+		{Alias: "target", HostName: "10.0.1.1", ProxyJump: "jump"},
+		{Alias: "loop-a", ProxyJump: "loop-b"},
+		{Alias: "loop-b", ProxyJump: "loop-a"},
+		{Alias: "direct", ProxyJump: "198.51.100.7"},
+	}
+
+	result := checkProxyJump(context.Background(), hosts[2], hosts, Options{})
+	if result.Status != StatusOK || result.Detail != "local → bastion → jump → target" {
+		t.Errorf("Expected chain 'local → bastion → jump → target', got %s (%s)", result.Status, result.Detail)
+	}
+
+	result = checkProxyJump(context.Background(), hosts[3], hosts, Options{})
+	if result.Status != StatusFail || !strings.Contains(result.Detail, "loop") {
+		t.Errorf("Expected loop failure, got %s (%s)", result.Status, result.Detail)
+	}
+
+	result = checkProxyJump(context.Background(), hosts[5], hosts, Options{})
+	if result.Status != StatusOK {
+		t.Errorf("Expected IP jump host to resolve, got %s (%s)", result.Status, result.Detail)
+	}
+
+	viaCommand := config.Host{Alias: "legacy", HostName: "10.0.2.1", ProxyCommand: "ssh -q -W %h:%p bastion"}
+	result = checkProxyJump(context.Background(), viaCommand, hosts, Options{})
+	if result.Status != StatusOK || result.Detail != "local → bastion → legacy" {
+		t.Errorf("Expected chain through ProxyCommand, got %s (%s)", result.Status, result.Detail)
+	}
+}
+
+func TestRun(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	defer listener.Close()
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			conn.Write([]byte("SSH-2.0-Test\r\n"))
+			conn.Close()
+		}
+	}()
+
+	address := listener.Addr().(*net.TCPAddr)
+	host := config.Host{Alias: "local", HostName: "127.0.0.1", Port: strings.TrimPrefix(address.String(), "127.0.0.1:")}
+	report := Run(context.Background(), host, []config.Host{host}, Options{HomeDir: t.TempDir(), KnownHostsFiles: []string{}})
+
+	if !report.OK() {
+		t.Errorf("Expected report to pass, got %+v", report.Results)
+	}
+	if report.Results[1].Status != StatusOK || !strings.Contains(report.Results[1].Detail, "SSH-2.0-Test") {
+		t.Errorf("Expected reachability with banner, got %+v", report.Results[1])
+	}
+}
@@ -0,0 +1,139 @@
+package check
+
+import (
+	"bufio"
+	"crypto/hmac"
+	"crypto/sha1"
+	"encoding/base64"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// hashedPrefix marks a hashed host entry in a known_hosts file.
+const hashedPrefix = "|1|"
+
+// knownHostName returns the name ssh looks up in known_hosts for a host and
+// port, e.g. "example.com" or "[example.com]:2222".
+func knownHostName(host, port string) string {
+	if port == "22" {
+		return host
+	}
+	return "[" + host + "]:" + port
+}
+
+// inKnownHosts reports whether any of the known_hosts files has a key for
+// name. Missing files are skipped.
+func inKnownHosts(files []string, name string) (bool, string, error) {
+	for _, path := range files {
+		found, err := fileHasHost(path, name)
+		if err != nil {
+			if os.IsNotExist(err) {
+				continue
+			}
+			return false, "", err
+		}
+		if found {
+			return true, path, nil
+		}
+	}
+	return false, "", nil
+}
+
+// fileHasHost scans a single known_hosts file for name.
+func fileHasHost(path, name string) (bool, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return false, err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 3 || strings.HasPrefix(fields[0], "#") {
+			continue
+		}
+		if strings.HasPrefix(fields[0], "@") {
+			// @cert-authority and @revoked lines don't vouch for a host key
+			continue
+		}
+		if matchHostPatterns(fields[0], name) {
+			return true, nil
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return false, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	return false, nil
+}
+
+// matchHostPatterns matches name against a comma-separated list of
+// known_hosts patterns. A matching negated pattern overrides any match.
+func matchHostPatterns(patterns, name string) bool {
+	matched := false
+	for _, pattern := range strings.Split(patterns, ",") {
+		negate := strings.HasPrefix(pattern, "!")
+		pattern = strings.TrimPrefix(pattern, "!")
+
+		var ok bool
+		if strings.HasPrefix(pattern, hashedPrefix) {
+			ok = matchHashed(pattern, name)
+		} else {
+			ok = matchGlob(strings.ToLower(pattern), strings.ToLower(name))
+		}
+
+		if ok && negate {
+			return false
+		}
+		matched = matched || ok
+	}
+	return matched
+}
+
+// matchHashed matches name against a "|1|salt|hash" entry, where hash is
+// HMAC-SHA1 of the name keyed with salt.
+func matchHashed(pattern, name string) bool {
+	parts := strings.Split(strings.TrimPrefix(pattern, hashedPrefix), "|")
+	if len(parts) != 2 {
+		return false
+	}
+	salt, err := base64.StdEncoding.DecodeString(parts[0])
+	if err != nil {
+		return false
+	}
+	want, err := base64.StdEncoding.DecodeString(parts[1])
+	if err != nil {
+		return false
+	}
+	mac := hmac.New(sha1.New, salt)
+	mac.Write([]byte(name))
+	return hmac.Equal(mac.Sum(nil), want)
+}
+
+// matchGlob matches s against a pattern where "*" matches any run of
+// characters and "?" matches exactly one.
+func matchGlob(pattern, s string) bool {
+	for len(pattern) > 0 {
+		switch pattern[0] {
+		case '*':
+			for i := len(s); i >= 0; i-- {
+				if matchGlob(pattern[1:], s[i:]) {
+					return true
+				}
+			}
+			return false
+		case '?':
+			if len(s) == 0 {
+				return false
+			}
+		default:
+			if len(s) == 0 || pattern[0] != s[0] {
+				return false
+			}
+		}
+		pattern = pattern[1:]
+		s = s[1:]
+	}
+	return len(s) == 0
+}
@@ -0,0 +1,146 @@
+// Package clipboard copies text to the clipboard. It writes an OSC 52 escape
+// sequence to the terminal, which works over SSH and inside tmux, and also
+// hands the text to a native clipboard tool where one is available, for
+// terminals that don't support OSC 52.
+package clipboard
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/aymanbagabas/go-osc52/v2"
+)
+
+// ErrUnavailable is returned when there is neither a terminal nor a
+// clipboard tool to copy with.
+var ErrUnavailable = errors.New("no terminal or clipboard tool to copy with")
+
+// Method names reported by Copy.
+const (
+	MethodOSC52 = "OSC 52"
+	MethodTmux  = "tmux"
+)
+
+// tool is a native clipboard command that reads the text on stdin.
+type tool struct {
+	// env is an environment variable that must be set for the tool to
+	// work, e.g. DISPLAY for X11 tools.
+	env  string
+	name string
+	args []string
+}
+
+// tools are tried in order and the first one installed is used.
+var tools = []tool{
+	{name: "pbcopy"},
+	{env: "WAYLAND_DISPLAY", name: "wl-copy"},
+	{env: "DISPLAY", name: "xclip", args: []string{"-selection", "clipboard"}},
+	{env: "DISPLAY", name: "xsel", args: []string{"--clipboard", "--input"}},
+	{name: "clip.exe"},
+}
+
+// Clipboard copies text with the terminal and the tools it finds.
+type Clipboard struct {
+	// Terminal receives the OSC 52 sequence. Nil skips it.
+	Terminal io.Writer
+	// Getenv looks up environment variables.
+	Getenv func(string) string
+	// LookPath finds clipboard tools.
+	LookPath func(string) (string, error)
+	// Run runs a command with input on its stdin.
+	Run func(input string, name string, args ...string) error
+}
+
+// New returns a Clipboard that writes to terminal and runs the tools
+// installed on this system.
+func New(terminal io.Writer) Clipboard {
+	return Clipboard{Terminal: terminal, Getenv: os.Getenv, LookPath: exec.LookPath, Run: run}
+}
+
+// Copy copies text to the clipboard through the controlling terminal and the
+// native tools, returning the methods used.
+func Copy(text string) ([]string, error) {
+	var terminal io.Writer
+	if tty, err := os.OpenFile("/dev/tty", os.O_WRONLY, 0); err == nil {
+		defer tty.Close()
+		terminal = tty
+	}
+	return New(terminal).Copy(text)
+}
+
+// Copy copies text every way it can and returns the methods that worked. It
+// fails only if none did.
+func (c Clipboard) Copy(text string) ([]string, error) {
+	var methods []string
+	var errs []error
+
+	inTmux := c.Getenv("TMUX") != ""
+	if c.Terminal != nil {
+		seq := osc52.New(text)
+		if inTmux {
+			seq = seq.Tmux()
+		} else if c.Getenv("STY") != "" {
+			seq = seq.Screen()
+		}
+		if _, err := seq.WriteTo(c.Terminal); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", MethodOSC52, err))
+		} else {
+			methods = append(methods, MethodOSC52)
+		}
+	}
+
+	// tmux keeps its own buffer, and with -w sets the outer terminal's
+	// clipboard even when passthrough is off
+	if inTmux {
+		if err := c.Run(text, "tmux", "load-buffer", "-w", "-"); err != nil {
+			errs = append(errs, err)
+		} else {
+			methods = append(methods, MethodTmux)
+		}
+	}
+
+	if t, ok := c.tool(); ok {
+		if err := c.Run(text, t.name, t.args...); err != nil {
+			errs = append(errs, err)
+		} else {
+			methods = append(methods, t.name)
+		}
+	}
+
+	if len(methods) > 0 {
+		return methods, nil
+	}
+	if len(errs) == 0 {
+		return nil, ErrUnavailable
+	}
+	return nil, errors.Join(errs...)
+}
+
+// tool returns the first clipboard tool that is installed and usable.
+func (c Clipboard) tool() (tool, bool) {
+	for _, t := range tools {
+		if t.env != "" && c.Getenv(t.env) == "" {
+			continue
+		}
+		if _, err := c.LookPath(t.name); err == nil {
+			return t, true
+		}
+	}
+	return tool{}, false
+}
+
+// run runs a command with input on its stdin. Its output is discarded, as
+// tools like xclip fork a child that holds on to the clipboard, and waiting
+// for that child's pipes to close would block until something else is copied.
+func run(input string, name string, args ...string) error {
+	cmd := exec.Command(name, args...)
+	cmd.Stdin = strings.NewReader(input)
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("%s: %w", name, err)
+	}
+	return nil
+}
@@ -0,0 +1,84 @@
+package clipboard
+
+import (
+	"bytes"
+	"errors"
+	"os/exec"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// fake returns a Clipboard with the given environment and installed tools
+// that records the commands it runs.
+func fake(terminal *bytes.Buffer, env map[string]string, installed ...string) (Clipboard, *[]string) {
+	var ran []string
+	c := Clipboard{
+		Getenv: func(key string) string { return env[key] },
+		LookPath: func(name string) (string, error) {
+			for _, tool := range installed {
+				if tool == name {
+					return "/usr/bin/" + name, nil
+				}
+			}
+			return "", exec.ErrNotFound
+		},
+		Run: func(input string, name string, args ...string) error {
+			ran = append(ran, strings.Join(append([]string{name}, args...), " ")+" <"+input)
+			return nil
+		},
+	}
+	if terminal != nil {
+		c.Terminal = terminal
+	}
+	return c, &ran
+}
+
+func TestClipboard_Copy(t *testing.T) {
+	var terminal bytes.Buffer
+	c, ran := fake(&terminal, map[string]string{"DISPLAY": ":0"}, "xsel", "wl-copy")
+	methods, err := c.Copy("ssh web")
+	if err != nil {
+		t.Fatalf("Copy failed: %v", err)
+	}
+	if expected := []string{MethodOSC52, "xsel"}; !reflect.DeepEqual(methods, expected) {
+		t.Errorf("Expected methods %v, got %v", expected, methods)
+	}
+	if got := terminal.String(); got != "\x1b]52;c;c3NoIHdlYg==\x07" {
+		t.Errorf("Expected an OSC 52 sequence, got %q", got)
+	}
+	if expected := []string{"xsel --clipboard --input <ssh web"}; !reflect.DeepEqual(*ran, expected) {
+		t.Errorf("Expected %v to run, got %v", expected, *ran)
+	}
+}
+
+func TestClipboard_CopyTmux(t *testing.T) {
+	var terminal bytes.Buffer
+	c, ran := fake(&terminal, map[string]string{"TMUX": "/tmp/tmux-1000/default,1,0"})
+	methods, err := c.Copy("web")
+	if err != nil {
+		t.Fatalf("Copy failed: %v", err)
+	}
+	if expected := []string{MethodOSC52, MethodTmux}; !reflect.DeepEqual(methods, expected) {
+		t.Errorf("Expected methods %v, got %v", expected, methods)
+	}
+	if got := terminal.String(); !strings.HasPrefix(got, "\x1bPtmux;") {
+		t.Errorf("Expected the sequence wrapped for tmux, got %q", got)
+	}
+	if expected := []string{"tmux load-buffer -w - <web"}; !reflect.DeepEqual(*ran, expected) {
+		t.Errorf("Expected %v to run, got %v", expected, *ran)
+	}
+}
+
+func TestClipboard_CopyFails(t *testing.T) {
+	c, _ := fake(nil, nil, "xclip")
+	if _, err := c.Copy("web"); !errors.Is(err, ErrUnavailable) {
+		t.Errorf("Expected ErrUnavailable without a terminal or usable tool, got %v", err)
+	}
+
+	c, _ = fake(nil, nil, "pbcopy")
+	c.Run = func(string, string, ...string) error { return errors.New("pbcopy: exit status 1") }
+	if _, err := c.Copy("web"); err == nil || !strings.Contains(err.Error(), "pbcopy") {
+		t.Errorf("Expected the tool's error, got %v", err)
+	}
+}
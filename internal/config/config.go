@@ -4,7 +4,9 @@ package config
 import (
 	"bufio"
 	"fmt"
+	"net"
 	"os"
+	"os/user"
 	"path/filepath"
 	"sort"
 	"strings"
@@ -16,8 +18,84 @@ type Host struct {
 	Alias string
 	// HostName is the actual hostname or IP address to connect to.
 	HostName string
+	// User is the login user configured for the host, if any.
+	User string
+	// Port is the port configured for the host, if any.
+	Port string
+	// ProxyJump is the configured jump host chain, if any.
+	ProxyJump string
+	// ProxyCommand is the configured proxy command, if any.
+	ProxyCommand string
+	// IdentityFiles are the configured identity files in the order given.
+	IdentityFiles []string
+	// Forwards are the configured LocalForward, RemoteForward and
+	// DynamicForward entries.
+	Forwards []Forward
+	// Tags are free-form labels from an "# ssm:tags" comment (e.g., "prod, db").
+	Tags []string
+	// Description is a short note from an "# ssm:description" comment.
+	Description string
+	// Via is the transport from an "# ssm:via" comment (e.g., "mosh").
+	Via string
+	// Lines are the lines of the host's block as written in the SSH config,
+	// after its Host line, including options and comments ssm doesn't use.
+	Lines []string
 }
 
+// defaultPort is the port ssh uses when none is configured.
+const defaultPort = "22"
+
+// Address returns the effective host:port ssh connects to.
+func (h Host) Address() string {
+	return net.JoinHostPort(h.EffectiveHostName(), h.EffectivePort())
+}
+
+// EffectiveHostName returns the HostName ssh connects to, which is the alias
+// when no HostName is set.
+func (h Host) EffectiveHostName() string {
+	if h.HostName == "" {
+		return h.Alias
+	}
+	return h.HostName
+}
+
+// EffectivePort returns the port ssh connects to, which is 22 when no Port
+// is set.
+func (h Host) EffectivePort() string {
+	if h.Port == "" {
+		return defaultPort
+	}
+	return h.Port
+}
+
+// EffectiveUser returns the user ssh logs in as, which is the local user
+// when no User is set, or "" if the local user can't be looked up.
+func (h Host) EffectiveUser() string {
+	if h.User != "" {
+		return h.User
+	}
+	if u, err := user.Current(); err == nil {
+		return u.Username
+	}
+	return ""
+}
+
+// Proxied reports whether the host is reached through a ProxyJump or
+// ProxyCommand rather than directly.
+func (h Host) Proxied() bool {
+	return (h.ProxyJump != "" && !strings.EqualFold(h.ProxyJump, "none")) ||
+		(h.ProxyCommand != "" && !strings.EqualFold(h.ProxyCommand, "none"))
+}
+
+// IsPattern reports whether the alias is a wildcard pattern rather than a
+// concrete host, e.g. "*.example.com".
+func (h Host) IsPattern() bool {
+	return h.HostName == "" && strings.ContainsAny(h.Alias, "*?!")
+}
+
+// metaPrefix marks comment lines inside a Host block that carry ssm metadata.
+const metaPrefix = "ssm:"
+
 // GetSSHHosts reads SSH hosts from the default configuration file (~/.ssh/config).
 func GetSSHHosts() ([]Host, error) {
 	home, err := os.UserHomeDir()
@@ -34,25 +112,88 @@ func parseSSHConfig(scanner *bufio.Scanner) (map[string]Host, error) {
 	hosts := make(map[string]Host)
 	var currentHost string
 
+	// endBlock drops the blank lines and comments at the end of the current
+	// block, as they usually belong to the block after it
+	endBlock := func() {
+		if currentHost == "" {
+			return
+		}
+		host := hosts[currentHost]
+		for len(host.Lines) > 0 {
+			last := strings.TrimSpace(host.Lines[len(host.Lines)-1])
+			if last != "" && (!strings.HasPrefix(last, "#") || isMetaComment(last)) {
+				break
+			}
+			host.Lines = host.Lines[:len(host.Lines)-1]
+		}
+		hosts[currentHost] = host
+	}
+
 	for scanner.Scan() {
-		line := strings.TrimSpace(scanner.Text())
+		raw := strings.TrimRight(scanner.Text(), " \t\r")
+		line := strings.TrimSpace(raw)
+		parts := strings.Fields(line)
+		key := ""
+		if len(parts) > 0 {
+			key = strings.ToLower(parts[0])
+		}
+		if currentHost != "" && key != "host" && key != "match" {
+			host := hosts[currentHost]
+			host.Lines = append(host.Lines, raw)
+			hosts[currentHost] = host
+		}
+
 		if strings.HasPrefix(line, "#") {
+			if currentHost != "" {
+				host := hosts[currentHost]
+				parseMetaComment(&host, line)
+				hosts[currentHost] = host
+			}
 			continue
 		}
-		parts := strings.Fields(line)
 		if len(parts) >= 2 {
-			key := strings.ToLower(parts[0])
 			value := strings.Join(parts[1:], " ")
 
-			if key == "host" && value != "*" {
+			switch {
+			case key == "host" && value == "*", key == "match":
+				// Options for every host or for matched hosts don't belong
+				// to the block above
+				endBlock()
+				currentHost = ""
+			case key == "host":
+				endBlock()
 				currentHost = value
-				hosts[currentHost] = Host{Alias: currentHost}
-			} else if currentHost != "" {
-				if key == "hostname" {
-					host := hosts[currentHost]
-					host.HostName = value
-					hosts[currentHost] = host
+				if _, ok := hosts[currentHost]; !ok {
+					hosts[currentHost] = Host{Alias: currentHost}
+				}
+			case currentHost != "":
+				host := hosts[currentHost]
+				// As in ssh, the first value given for an option wins
+				first := func(field *string) {
+					if *field == "" {
+						*field = value
+					}
 				}
+				switch key {
+				case "hostname":
+					first(&host.HostName)
+				case "user":
+					first(&host.User)
+				case "port":
+					first(&host.Port)
+				case "proxyjump":
+					first(&host.ProxyJump)
+				case "proxycommand":
+					first(&host.ProxyCommand)
+				case "identityfile":
+					host.IdentityFiles = append(host.IdentityFiles, value)
+				case "localforward", "remoteforward", "dynamicforward":
+					typ := ForwardType(strings.ToUpper(key[:1]))
+					if forward, err := parseConfigForward(typ, value); err == nil {
+						host.Forwards = append(host.Forwards, forward)
+					}
+				}
+				hosts[currentHost] = host
 			}
 		}
 	}
@@ -60,10 +201,41 @@ func parseSSHConfig(scanner *bufio.Scanner) (map[string]Host, error) {
 	if err := scanner.Err(); err != nil {
 		return nil, fmt.Errorf("failed to read SSH config: %w", err)
 	}
+	endBlock()
 
 	return hosts, nil
 }
 
+// parseMetaComment applies an "# ssm:<key> <value>" comment to the host.
+// Comments without the ssm prefix or with unknown keys are ignored.
+func parseMetaComment(host *Host, line string) {
+	if !isMetaComment(line) {
+		return
+	}
+	comment := strings.TrimSpace(strings.TrimPrefix(line, "#"))
+	key, value, _ := strings.Cut(strings.TrimPrefix(comment, metaPrefix), " ")
+	value = strings.TrimSpace(value)
+
+	switch strings.ToLower(key) {
+	case "tags":
+		for _, tag := range strings.Split(value, ",") {
+			if tag = strings.TrimSpace(tag); tag != "" {
+				host.Tags = append(host.Tags, tag)
+			}
+		}
+	case "description":
+		host.Description = value
+	case "via":
+		host.Via = value
+	}
+}
+
+// isMetaComment reports whether line is an "# ssm:<key> <value>" comment.
+func isMetaComment(line string) bool {
+	comment := strings.TrimSpace(strings.TrimPrefix(line, "#"))
+	return strings.HasPrefix(comment, metaPrefix)
+}
+
 // GetSSHHostsFromPath reads SSH hosts from the specified configuration file path.
 func GetSSHHostsFromPath(configPath string) ([]Host, error) {
 	file, err := os.Open(configPath)
@@ -2,7 +2,10 @@ package config
 
 import (
 	"os"
+	"os/user"
 	"path/filepath"
+	"reflect"
+	"strings"
 	"testing"
 )
 
@@ -37,9 +40,9 @@ Host server3
 	}
 
 	expected := []Host{
-		{Alias: "server1", HostName: "192.168.1.100"},
-		{Alias: "server2", HostName: "example.com"},
-		{Alias: "server3", HostName: "test.com"},
+		{Alias: "server1", HostName: "192.168.1.100", Lines: []string{"    HostName 192.168.1.100"}},
+		{Alias: "server2", HostName: "example.com", Lines: []string{"    HostName example.com"}},
+		{Alias: "server3", HostName: "test.com", Lines: []string{"    HostName test.com"}},
 	}
 
 	if len(hosts) != len(expected) {
@@ -47,7 +50,7 @@ Host server3
 	}
 
 	for i, host := range hosts {
-		if host != expected[i] {
+		if !reflect.DeepEqual(host, expected[i]) {
 			t.Errorf("Expected host %+v, got %+v", expected[i], host)
 		}
 	}
@@ -59,3 +62,171 @@ func TestGetSSHHosts_NoConfig(t *testing.T) {
 		t.Error("Expected error when config file doesn't exist")
 	}
 }
+
+func TestGetSSHHosts_Fields(t *testing.T) {
+	tempDir := t.TempDir()
+	configPath := filepath.Join(tempDir, "config")
+
+	configContent := `Host db
+    # ssm:tags prod, db
+    # ssm:description Primary database
+    # ssm:via mosh
+    # An ordinary comment
+    HostName 10.0.0.5
+    User deploy
+    Port 2222
+    IdentityFile ~/.ssh/id_prod
+    IdentityFile ~/.ssh/id_backup
+    LocalForward 5432 localhost:5432
+    DynamicForward 1080
+`
+
+	err := os.WriteFile(configPath, []byte(configContent), 0644)
+	if err != nil {
+		t.Fatalf("Failed to write test config: %v", err)
+	}
+
+	hosts, err := GetSSHHostsFromPath(configPath)
+	if err != nil {
+		t.Fatalf("GetSSHHostsFromPath failed: %v", err)
+	}
+
+	expected := Host{
+		Alias:         "db",
+		HostName:      "10.0.0.5",
+		User:          "deploy",
+		Port:          "2222",
+		IdentityFiles: []string{"~/.ssh/id_prod", "~/.ssh/id_backup"},
+		Forwards: []Forward{
+			{Type: LocalForward, Listen: "5432", Target: "localhost:5432"},
+			{Type: DynamicForward, Listen: "1080"},
+		},
+		Tags:        []string{"prod", "db"},
+		Description: "Primary database",
+		Via:         "mosh",
+		Lines:       strings.Split(strings.TrimSuffix(strings.TrimPrefix(configContent, "Host db\n"), "\n"), "\n"),
+	}
+
+	if len(hosts) != 1 {
+		t.Fatalf("Expected 1 host, got %d", len(hosts))
+	}
+	if !reflect.DeepEqual(hosts[0], expected) {
+		t.Errorf("Expected host %+v, got %+v", expected, hosts[0])
+	}
+}
+
+func TestGetSSHHosts_WildcardAndMatch(t *testing.T) {
+	configPath := filepath.Join(t.TempDir(), "config")
+	configContent := `Host web
+    User deploy
+
+Host *
+    User root
+    Port 2200
+    ProxyJump bastion
+
+Host db
+    HostName 10.0.0.5
+
+Match host db exec "true"
+    User postgres
+    IdentityFile ~/.ssh/id_match
+`
+	if err := os.WriteFile(configPath, []byte(configContent), 0644); err != nil {
+		t.Fatalf("Failed to write test config: %v", err)
+	}
+
+	hosts, err := GetSSHHostsFromPath(configPath)
+	if err != nil {
+		t.Fatalf("GetSSHHostsFromPath failed: %v", err)
+	}
+	expected := []Host{
+		{Alias: "db", HostName: "10.0.0.5", Lines: []string{"    HostName 10.0.0.5"}},
+		{Alias: "web", User: "deploy", Lines: []string{"    User deploy"}},
+	}
+	if !reflect.DeepEqual(hosts, expected) {
+		t.Errorf("Expected Host * and Match options to be left out, got %+v", hosts)
+	}
+}
+
+func TestGetSSHHosts_FirstValueWins(t *testing.T) {
+	configPath := filepath.Join(t.TempDir(), "config")
+	configContent := `Host web
+    HostName 10.0.0.1
+    User deploy
+    HostName 10.0.0.2
+    Port 2222
+    IdentityFile ~/.ssh/id_web
+
+Host web
+    User root
+    Port 22
+    IdentityFile ~/.ssh/id_other
+`
+	if err := os.WriteFile(configPath, []byte(configContent), 0644); err != nil {
+		t.Fatalf("Failed to write test config: %v", err)
+	}
+
+	hosts, err := GetSSHHostsFromPath(configPath)
+	if err != nil {
+		t.Fatalf("GetSSHHostsFromPath failed: %v", err)
+	}
+	expected := []Host{{
+		Alias:         "web",
+		HostName:      "10.0.0.1",
+		User:          "deploy",
+		Port:          "2222",
+		IdentityFiles: []string{"~/.ssh/id_web", "~/.ssh/id_other"},
+		Lines: []string{
+			"    HostName 10.0.0.1", "    User deploy", "    HostName 10.0.0.2", "    Port 2222", "    IdentityFile ~/.ssh/id_web",
+			"    User root", "    Port 22", "    IdentityFile ~/.ssh/id_other",
+		},
+	}}
+	if !reflect.DeepEqual(hosts, expected) {
+		t.Errorf("Expected the first value of each option to win, got %+v", hosts)
+	}
+}
+
+func TestHost_Address(t *testing.T) {
+	tests := []struct {
+		host     Host
+		expected string
+	}{
+		{Host{Alias: "web"}, "web:22"},
+		{Host{Alias: "web", HostName: "10.0.0.1", Port: "2222"}, "10.0.0.1:2222"},
+		{Host{Alias: "v6", HostName: "fe80::1"}, "[fe80::1]:22"},
+	}
+	for _, test := range tests {
+		if address := test.host.Address(); address != test.expected {
+			t.Errorf("Expected '%s', got '%s'", test.expected, address)
+		}
+	}
+}
+
+func TestHost_Effective(t *testing.T) {
+	host := Host{Alias: "web", HostName: "10.0.0.1", User: "deploy", Port: "2222"}
+	if host.EffectiveHostName() != "10.0.0.1" || host.EffectiveUser() != "deploy" || host.EffectivePort() != "2222" {
+		t.Errorf("Expected the configured values, got %s, %s and %s", host.EffectiveHostName(), host.EffectiveUser(), host.EffectivePort())
+	}
+
+	bare := Host{Alias: "web"}
+	local, err := user.Current()
+	if err != nil {
+		t.Skipf("Can't look up the local user: %v", err)
+	}
+	if bare.EffectiveHostName() != "web" || bare.EffectiveUser() != local.Username || bare.EffectivePort() != "22" {
+		t.Errorf("Expected ssh's defaults, got %s, %s and %s", bare.EffectiveHostName(), bare.EffectiveUser(), bare.EffectivePort())
+	}
+}
+
+func TestHost_Proxied(t *testing.T) {
+	if (Host{Alias: "web"}).Proxied() {
+		t.Error("Expected host without proxy not to be proxied")
+	}
+	if !(Host{Alias: "web", ProxyJump: "bastion"}).Proxied() {
+		t.Error("Expected host with ProxyJump to be proxied")
+	}
+	if (Host{Alias: "web", ProxyJump: "none"}).Proxied() {
+		t.Error("Expected ProxyJump none not to be proxied")
+	}
+}
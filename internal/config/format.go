@@ -0,0 +1,134 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Ways of describing a host for sharing, e.g. on the clipboard.
+const (
+	// FormatCommand is an ssh command that connects without the SSH config,
+	// e.g. "ssh -p 2222 deploy@10.0.0.1".
+	FormatCommand = "command"
+	// FormatDestination is ssh's destination argument, e.g.
+	// "deploy@10.0.0.1".
+	FormatDestination = "destination"
+	// FormatHostName is the HostName, e.g. "10.0.0.1".
+	FormatHostName = "hostname"
+	// FormatBlock is the host's Host block for an SSH config file.
+	FormatBlock = "config"
+)
+
+// Formats lists the formats in the order they are offered.
+var Formats = []string{FormatCommand, FormatDestination, FormatHostName, FormatBlock}
+
+// Format describes the host in one of the Formats.
+func (h Host) Format(format string) (string, error) {
+	switch format {
+	case FormatCommand:
+		return h.SSHCommand(), nil
+	case FormatDestination:
+		return h.Destination(), nil
+	case FormatHostName:
+		if h.HostName == "" {
+			return h.Alias, nil
+		}
+		return h.HostName, nil
+	case FormatBlock:
+		return h.Block(), nil
+	default:
+		return "", fmt.Errorf("unknown format %q (expected %s)", format, strings.Join(Formats, ", "))
+	}
+}
+
+// Destination returns "user@hostname", or only the hostname if no user is
+// configured. The alias stands in for a missing HostName.
+func (h Host) Destination() string {
+	hostName := h.HostName
+	if hostName == "" {
+		hostName = h.Alias
+	}
+	if h.User == "" {
+		return hostName
+	}
+	return h.User + "@" + hostName
+}
+
+// SSHCommand returns a shell command that connects to the host with the
+// port, jump hosts and proxy command from its config, so that it works
+// without the config. Identity files are left out as they are local paths.
+func (h Host) SSHCommand() string {
+	args := []string{"ssh"}
+	if h.Port != "" && h.Port != defaultPort {
+		args = append(args, "-p", h.Port)
+	}
+	if h.ProxyJump != "" && !strings.EqualFold(h.ProxyJump, "none") {
+		args = append(args, "-J", h.ProxyJump)
+	}
+	if h.ProxyCommand != "" && !strings.EqualFold(h.ProxyCommand, "none") {
+		args = append(args, "-o", "ProxyCommand="+h.ProxyCommand)
+	}
+	args = append(args, h.Destination())
+	for i, arg := range args {
+		args[i] = shellQuote(arg)
+	}
+	return strings.Join(args, " ")
+}
+
+// Block returns the host as a Host block in SSH config syntax. A host read
+// from the SSH config gets its block as written there; otherwise the block
+// is built from the host's fields, including its ssm metadata comments.
+func (h Host) Block() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Host %s\n", h.Alias)
+	if len(h.Lines) > 0 {
+		for _, line := range h.Lines {
+			b.WriteString(line + "\n")
+		}
+		return b.String()
+	}
+	if h.Description != "" {
+		fmt.Fprintf(&b, "    # %sdescription %s\n", metaPrefix, h.Description)
+	}
+	if len(h.Tags) > 0 {
+		fmt.Fprintf(&b, "    # %stags %s\n", metaPrefix, strings.Join(h.Tags, ", "))
+	}
+	if h.Via != "" {
+		fmt.Fprintf(&b, "    # %svia %s\n", metaPrefix, h.Via)
+	}
+	option := func(name, value string) {
+		if value != "" {
+			fmt.Fprintf(&b, "    %s %s\n", name, value)
+		}
+	}
+	option("HostName", h.HostName)
+	option("User", h.User)
+	option("Port", h.Port)
+	option("ProxyJump", h.ProxyJump)
+	option("ProxyCommand", h.ProxyCommand)
+	for _, file := range h.IdentityFiles {
+		option("IdentityFile", file)
+	}
+	for _, f := range h.Forwards {
+		option(forwardOptions[f.Type], strings.TrimSpace(f.Listen+" "+f.Target))
+	}
+	return b.String()
+}
+
+// forwardOptions maps forward types to their SSH config options.
+var forwardOptions = map[ForwardType]string{
+	LocalForward:   "LocalForward",
+	RemoteForward:  "RemoteForward",
+	DynamicForward: "DynamicForward",
+}
+
+// shellQuote quotes s for a POSIX shell if it contains anything but plain
+// characters.
+func shellQuote(s string) string {
+	if s != "" && strings.IndexFunc(s, func(r rune) bool {
+		return !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || strings.ContainsRune("@%+=:,./_-", r))
+	}) < 0 {
+		return s
+	}
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
@@ -0,0 +1,130 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestHost_Format(t *testing.T) {
+	host := Host{Alias: "web", HostName: "10.0.0.1", User: "deploy", Port: "2222", ProxyJump: "bastion"}
+	tests := []struct {
+		host     Host
+		format   string
+		expected string
+	}{
+		{host, FormatCommand, "ssh -p 2222 -J bastion deploy@10.0.0.1"},
+		{host, FormatDestination, "deploy@10.0.0.1"},
+		{host, FormatHostName, "10.0.0.1"},
+		{Host{Alias: "web", Port: "22"}, FormatCommand, "ssh web"},
+		{Host{Alias: "web"}, FormatHostName, "web"},
+		{Host{Alias: "web", ProxyJump: "none"}, FormatCommand, "ssh web"},
+		{Host{Alias: "web", ProxyCommand: "nc -X 5 -x proxy:1080 %h %p"}, FormatCommand, "ssh -o 'ProxyCommand=nc -X 5 -x proxy:1080 %h %p' web"},
+		{Host{Alias: "web", User: "o'brien"}, FormatDestination, "o'brien@web"},
+		{Host{Alias: "web", User: "o'brien"}, FormatCommand, `ssh 'o'\''brien@web'`},
+	}
+	for _, test := range tests {
+		got, err := test.host.Format(test.format)
+		if err != nil {
+			t.Fatalf("Format(%q) failed: %v", test.format, err)
+		}
+		if got != test.expected {
+			t.Errorf("Expected '%s', got '%s'", test.expected, got)
+		}
+	}
+
+	if _, err := host.Format("yaml"); err == nil {
+		t.Error("Expected an error for an unknown format")
+	}
+}
+
+func TestHost_Block(t *testing.T) {
+	host := Host{
+		Alias:         "web",
+		HostName:      "10.0.0.1",
+		User:          "deploy",
+		Port:          "2222",
+		ProxyJump:     "bastion",
+		IdentityFiles: []string{"~/.ssh/web", "~/.ssh/backup"},
+		Forwards: []Forward{
+			{Type: LocalForward, Listen: "8080", Target: "localhost:80"},
+			{Type: DynamicForward, Listen: "1080"},
+		},
+		Tags:        []string{"prod", "web"},
+		Description: "Frontend",
+		Via:         "mosh",
+	}
+	expected := `Host web
+    # ssm:description Frontend
+    # ssm:tags prod, web
+    # ssm:via mosh
+    HostName 10.0.0.1
+    User deploy
+    Port 2222
+    ProxyJump bastion
+    IdentityFile ~/.ssh/web
+    IdentityFile ~/.ssh/backup
+    LocalForward 8080 localhost:80
+    DynamicForward 1080
+`
+	block := host.Block()
+	if block != expected {
+		t.Errorf("Expected block:\n%s\ngot:\n%s", expected, block)
+	}
+
+	// The block reads back as the same host
+	path := filepath.Join(t.TempDir(), "config")
+	if err := os.WriteFile(path, []byte(block), 0644); err != nil {
+		t.Fatalf("Failed to write test config: %v", err)
+	}
+	hosts, err := GetSSHHostsFromPath(path)
+	if err != nil {
+		t.Fatalf("GetSSHHostsFromPath failed: %v", err)
+	}
+	if len(hosts) != 1 {
+		t.Fatalf("Expected 1 host, got %+v", hosts)
+	}
+	read := hosts[0]
+	read.Lines = nil
+	if !reflect.DeepEqual(read, host) {
+		t.Errorf("Expected host %+v, got %+v", host, read)
+	}
+}
+
+func TestHost_BlockAsWritten(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config")
+	content := `Host web
+  # Frontend, see the runbook
+  HostName 10.0.0.1
+  ForwardAgent yes
+  ServerAliveInterval 30
+  SetEnv TERM=xterm-256color
+
+# The database
+Host db
+  HostName 10.0.0.5
+`
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write test config: %v", err)
+	}
+	hosts, err := GetSSHHostsFromPath(path)
+	if err != nil {
+		t.Fatalf("GetSSHHostsFromPath failed: %v", err)
+	}
+
+	// Options ssm doesn't know are kept, and the comment above the next
+	// block is left to it
+	expected := `Host web
+  # Frontend, see the runbook
+  HostName 10.0.0.1
+  ForwardAgent yes
+  ServerAliveInterval 30
+  SetEnv TERM=xterm-256color
+`
+	for _, host := range hosts {
+		if host.Alias == "web" && host.Block() != expected {
+			t.Errorf("Expected block:\n%s\ngot:\n%s", expected, host.Block())
+		}
+	}
+}
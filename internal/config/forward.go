@@ -0,0 +1,172 @@
+package config
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+)
+
+// ForwardType is the kind of port forward, matching ssh's -L, -R and -D.
+type ForwardType string
+
+const (
+	// LocalForward listens locally and forwards to a target via the host.
+	LocalForward ForwardType = "L"
+	// RemoteForward listens on the host and forwards to a local target.
+	RemoteForward ForwardType = "R"
+	// DynamicForward runs a local SOCKS proxy through the host.
+	DynamicForward ForwardType = "D"
+)
+
+// Forward is a port forward in ssh's command-line syntax.
+type Forward struct {
+	Type ForwardType `json:"type"`
+	// Listen is "[bind_address:]port" on the listening side.
+	Listen string `json:"listen"`
+	// Target is "host:hostport" or a socket path. Dynamic forwards, and
+	// remote forwards acting as a SOCKS proxy, have none.
+	Target string `json:"target,omitempty"`
+}
+
+// ParseForward parses a forward in ssh's -L, -R or -D argument syntax, for
+// example "8080:localhost:80", "127.0.0.1:5432:db:5432" or "1080".
+func ParseForward(typ ForwardType, spec string) (Forward, error) {
+	fields := splitForward(strings.TrimSpace(spec))
+	forward := Forward{Type: typ}
+
+	n := len(fields)
+	switch typ {
+	case DynamicForward:
+		if n > 2 {
+			return Forward{}, fmt.Errorf("invalid dynamic forward %q: expected [bind_address:]port", spec)
+		}
+		forward.Listen = joinForward(fields)
+	case LocalForward, RemoteForward:
+		switch {
+		case (n == 2 || n == 3) && strings.HasPrefix(fields[n-1], "/"):
+			forward.Listen, forward.Target = joinForward(fields[:n-1]), fields[n-1]
+		case n == 3 || n == 4:
+			if !isPort(fields[n-1]) {
+				return Forward{}, fmt.Errorf("invalid forward %q: bad target port", spec)
+			}
+			forward.Listen, forward.Target = joinForward(fields[:n-2]), joinForward(fields[n-2:])
+		case typ == RemoteForward && n <= 2:
+			forward.Listen = joinForward(fields)
+		default:
+			return Forward{}, fmt.Errorf("invalid forward %q: expected [bind_address:]port:host:hostport", spec)
+		}
+	default:
+		return Forward{}, fmt.Errorf("unknown forward type %q", typ)
+	}
+
+	if _, port := forward.BindAddress(); !isPort(port) {
+		return Forward{}, fmt.Errorf("invalid forward %q: bad listen port", spec)
+	}
+	return forward, nil
+}
+
+// ParseForwardFlag parses a forward written as a flag, such as
+// "-L 8080:localhost:80", "L 8080:localhost:80" or "-D1080".
+func ParseForwardFlag(s string) (Forward, error) {
+	s = strings.TrimPrefix(strings.TrimSpace(s), "-")
+	if s == "" {
+		return Forward{}, fmt.Errorf("empty forward")
+	}
+	typ := ForwardType(strings.ToUpper(s[:1]))
+	return ParseForward(typ, s[1:])
+}
+
+// parseConfigForward parses a LocalForward, RemoteForward or DynamicForward
+// value from ssh_config, where the listen and target parts are separated by
+// whitespace rather than a colon.
+func parseConfigForward(typ ForwardType, value string) (Forward, error) {
+	return ParseForward(typ, strings.Join(strings.Fields(value), ":"))
+}
+
+// splitForward splits a forward spec on colons, keeping bracketed IPv6
+// addresses together and dropping their brackets.
+func splitForward(spec string) []string {
+	var fields []string
+	for spec != "" {
+		if strings.HasPrefix(spec, "[") {
+			if end := strings.Index(spec, "]"); end > 0 {
+				fields = append(fields, spec[1:end])
+				spec = strings.TrimPrefix(spec[end+1:], ":")
+				continue
+			}
+		}
+		field, rest, found := strings.Cut(spec, ":")
+		fields = append(fields, field)
+		if !found {
+			break
+		}
+		spec = rest
+	}
+	return fields
+}
+
+// joinForward joins one or two fields of a split spec back into "port" or
+// "host:port" form, bracketing IPv6 addresses.
+func joinForward(fields []string) string {
+	if len(fields) == 2 {
+		return net.JoinHostPort(fields[0], fields[1])
+	}
+	return strings.Join(fields, ":")
+}
+
+// isPort reports whether s is a valid TCP port number.
+func isPort(s string) bool {
+	port, err := strconv.Atoi(s)
+	return err == nil && port > 0 && port <= 65535
+}
+
+// BindAddress returns the listen address and port. The address is empty when
+// none was given.
+func (f Forward) BindAddress() (address, port string) {
+	fields := splitForward(f.Listen)
+	if len(fields) == 0 {
+		return "", ""
+	}
+	port = fields[len(fields)-1]
+	return strings.Join(fields[:len(fields)-1], ":"), port
+}
+
+// LocalPort returns the local port the forward listens on, or an empty
+// string for remote forwards, which listen on the host.
+func (f Forward) LocalPort() string {
+	if f.Type == RemoteForward {
+		return ""
+	}
+	_, port := f.BindAddress()
+	return port
+}
+
+// LocalAddress returns the local address the forward will bind, for checking
+// whether the port is free. Remote forwards return an empty string.
+func (f Forward) LocalAddress() string {
+	if f.Type == RemoteForward {
+		return ""
+	}
+	address, port := f.BindAddress()
+	switch address {
+	case "", "localhost":
+		address = "127.0.0.1"
+	case "*":
+		address = ""
+	}
+	return net.JoinHostPort(address, port)
+}
+
+// Arg returns the forward's argument to ssh's -L, -R or -D flag.
+func (f Forward) Arg() string {
+	if f.Target == "" {
+		return f.Listen
+	}
+	return f.Listen + ":" + f.Target
+}
+
+// String returns the forward as a flag, for example "-L 8080:localhost:80".
+func (f Forward) String() string {
+	return "-" + string(f.Type) + " " + f.Arg()
+}
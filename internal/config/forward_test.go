@@ -0,0 +1,46 @@
+package config
+
+import "testing"
+
+func TestParseForwardFlag(t *testing.T) {
+	tests := []struct {
+		flag     string
+		expected Forward
+		local    string
+	}{
+		{"-L 8080:localhost:80", Forward{Type: LocalForward, Listen: "8080", Target: "localhost:80"}, "127.0.0.1:8080"},
+		{"L *:5432:db:5432", Forward{Type: LocalForward, Listen: "*:5432", Target: "db:5432"}, ":5432"},
+		{"-L[::1]:8080:[2001:db8::1]:80", Forward{Type: LocalForward, Listen: "[::1]:8080", Target: "[2001:db8::1]:80"}, "[::1]:8080"},
+		{"-L 2375:/var/run/docker.sock", Forward{Type: LocalForward, Listen: "2375", Target: "/var/run/docker.sock"}, "127.0.0.1:2375"},
+		{"-R 9000:localhost:3000", Forward{Type: RemoteForward, Listen: "9000", Target: "localhost:3000"}, ""},
+		{"-R 1080", Forward{Type: RemoteForward, Listen: "1080"}, ""},
+		{"-D1080", Forward{Type: DynamicForward, Listen: "1080"}, "127.0.0.1:1080"},
+	}
+
+	for _, test := range tests {
+		forward, err := ParseForwardFlag(test.flag)
+		if err != nil {
+			t.Errorf("ParseForwardFlag(%q): unexpected error %v", test.flag, err)
+			continue
+		}
+		if forward != test.expected {
+			t.Errorf("ParseForwardFlag(%q): expected %+v, got %+v", test.flag, test.expected, forward)
+		}
+		if got := forward.LocalAddress(); got != test.local {
+			t.Errorf("ParseForwardFlag(%q): expected local address %q, got %q", test.flag, test.local, got)
+		}
+	}
+
+	for _, bad := range []string{"", "-L 8080", "-L 8080:host", "-L 8080:host:http", "-L 99999:host:80", "-D a:b:c", "-X 8080"} {
+		if forward, err := ParseForwardFlag(bad); err == nil {
+			t.Errorf("ParseForwardFlag(%q): expected error, got %+v", bad, forward)
+		}
+	}
+}
+
+func TestForward_String(t *testing.T) {
+	forward := Forward{Type: LocalForward, Listen: "[::1]:8080", Target: "db:5432"}
+	if got := forward.String(); got != "-L [::1]:8080:db:5432" {
+		t.Errorf("Expected '-L [::1]:8080:db:5432', got '%s'", got)
+	}
+}
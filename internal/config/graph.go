@@ -0,0 +1,73 @@
+package config
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// WriteDOT writes the jump topology as a Graphviz DOT digraph. Jump hosts
+// that are not configured aliases are drawn dashed.
+func (g *Graph) WriteDOT(w io.Writer) error {
+	var b strings.Builder
+	b.WriteString("digraph ssh {\n")
+	b.WriteString("  rankdir=LR;\n")
+	b.WriteString("  node [shape=box];\n")
+	fmt.Fprintf(&b, "  %q [shape=ellipse];\n", LocalNode)
+
+	for _, node := range g.nodes() {
+		if node != LocalNode && !g.IsAlias(node) {
+			fmt.Fprintf(&b, "  %q [style=dashed];\n", node)
+		}
+	}
+	for _, edge := range g.Edges() {
+		fmt.Fprintf(&b, "  %q -> %q;\n", edge.From, edge.To)
+	}
+	b.WriteString("}\n")
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// WriteMermaid writes the jump topology as a Mermaid flowchart. Jump hosts
+// that are not configured aliases are drawn with rounded corners.
+func (g *Graph) WriteMermaid(w io.Writer) error {
+	ids := make(map[string]string)
+	var b strings.Builder
+	b.WriteString("flowchart LR\n")
+
+	for i, node := range g.nodes() {
+		id := fmt.Sprintf("n%d", i)
+		ids[node] = id
+		label := strings.ReplaceAll(node, `"`, "#quot;")
+		switch {
+		case node == LocalNode:
+			fmt.Fprintf(&b, "  %s([\"%s\"])\n", id, label)
+		case g.IsAlias(node):
+			fmt.Fprintf(&b, "  %s[\"%s\"]\n", id, label)
+		default:
+			fmt.Fprintf(&b, "  %s(\"%s\")\n", id, label)
+		}
+	}
+	for _, edge := range g.Edges() {
+		fmt.Fprintf(&b, "  %s --> %s\n", ids[edge.From], ids[edge.To])
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// nodes returns every node that appears in an edge, starting with LocalNode.
+func (g *Graph) nodes() []string {
+	seen := map[string]bool{LocalNode: true}
+	nodes := []string{LocalNode}
+	for _, edge := range g.Edges() {
+		for _, node := range []string{edge.From, edge.To} {
+			if !seen[node] {
+				seen[node] = true
+				nodes = append(nodes, node)
+			}
+		}
+	}
+	return nodes
+}
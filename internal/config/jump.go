@@ -0,0 +1,293 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// LocalNode is the name used for the machine ssm runs on in jump chains.
+const LocalNode = "local"
+
+// Hop is a single jump host from a ProxyJump or ProxyCommand.
+type Hop struct {
+	User string
+	Host string
+	Port string
+}
+
+// String returns the hop in "[user@]host[:port]" form.
+func (h Hop) String() string {
+	s := h.Host
+	if h.User != "" {
+		s = h.User + "@" + s
+	}
+	if h.Port != "" {
+		s = net.JoinHostPort(s, h.Port)
+	}
+	return s
+}
+
+// ParseProxyJump parses a ProxyJump value: a comma-separated list of
+// "[user@]host[:port]" or "ssh://[user@]host[:port]" hops. "none" yields no hops.
+func ParseProxyJump(value string) []Hop {
+	value = strings.TrimSpace(value)
+	if value == "" || strings.EqualFold(value, "none") {
+		return nil
+	}
+
+	var hops []Hop
+	for _, part := range strings.Split(value, ",") {
+		part = strings.TrimPrefix(strings.TrimSpace(part), "ssh://")
+		if part != "" {
+			hops = append(hops, parseHop(part))
+		}
+	}
+	return hops
+}
+
+// parseHop parses "[user@]host[:port]", including bracketed IPv6 addresses.
+func parseHop(s string) Hop {
+	var hop Hop
+	if i := strings.LastIndex(s, "@"); i >= 0 {
+		hop.User = s[:i]
+		s = s[i+1:]
+	}
+	if host, port, err := net.SplitHostPort(s); err == nil {
+		hop.Host, hop.Port = host, port
+	} else {
+		hop.Host = strings.Trim(s, "[]")
+	}
+	return hop
+}
+
+// sshArgOptions are the ssh flags that take an argument.
+const sshArgOptions = "BbcDEeFIiJLlmOoPpQRSWw"
+
+// ParseProxyCommand extracts the jump host from a ProxyCommand of the form
+// "ssh [options] [user@]host -W %h:%p". It reports false for any other
+// command, such as nc or a cloud provider's tunnel.
+func ParseProxyCommand(value string) (Hop, bool) {
+	fields := strings.Fields(value)
+	if len(fields) == 0 || filepath.Base(fields[0]) != "ssh" {
+		return Hop{}, false
+	}
+
+	var hop Hop
+	var forwarding bool
+	for i := 1; i < len(fields); i++ {
+		field := fields[i]
+		if !strings.HasPrefix(field, "-") || len(field) < 2 {
+			if hop.Host == "" {
+				parsed := parseHop(field)
+				hop.Host = parsed.Host
+				if parsed.User != "" {
+					hop.User = parsed.User
+				}
+				if parsed.Port != "" && hop.Port == "" {
+					hop.Port = parsed.Port
+				}
+			}
+			continue
+		}
+
+		// Walk combined flags such as "-qW" until one takes an argument
+		for j := 1; j < len(field); j++ {
+			flag := field[j]
+			if !strings.ContainsRune(sshArgOptions, rune(flag)) {
+				continue
+			}
+			arg := field[j+1:]
+			if arg == "" && i+1 < len(fields) {
+				i++
+				arg = fields[i]
+			}
+			switch flag {
+			case 'W':
+				forwarding = true
+			case 'l':
+				hop.User = arg
+			case 'p':
+				hop.Port = arg
+			}
+			break
+		}
+	}
+
+	if !forwarding || hop.Host == "" {
+		return Hop{}, false
+	}
+	return hop, true
+}
+
+// Jumps returns the hops configured for the host through ProxyJump, or
+// through a ProxyCommand that runs "ssh -W".
+func (h Host) Jumps() []Hop {
+	if hops := ParseProxyJump(h.ProxyJump); len(hops) > 0 {
+		return hops
+	}
+	if hop, ok := ParseProxyCommand(h.ProxyCommand); ok {
+		return []Hop{hop}
+	}
+	return nil
+}
+
+// ErrJumpLoop is returned when following jump hosts leads back to a host
+// already in the chain.
+var ErrJumpLoop = errors.New("jump loop")
+
+// ChainHop is one step of a resolved jump chain.
+type ChainHop struct {
+	Hop
+	// Alias is set when the hop refers to a configured host.
+	Alias string
+}
+
+// Name returns the alias of the hop, or its hostname if it has none.
+func (c ChainHop) Name() string {
+	if c.Alias != "" {
+		return c.Alias
+	}
+	return c.Host
+}
+
+// Graph resolves jump chains between configured hosts.
+type Graph struct {
+	hosts map[string]Host
+	order []string
+}
+
+// NewGraph builds a jump graph from the configured hosts.
+func NewGraph(hosts []Host) *Graph {
+	g := &Graph{hosts: make(map[string]Host, len(hosts))}
+	for _, host := range hosts {
+		if _, ok := g.hosts[host.Alias]; !ok {
+			g.order = append(g.order, host.Alias)
+		}
+		g.hosts[host.Alias] = host
+	}
+	sort.Strings(g.order)
+	return g
+}
+
+// Chain returns the hops needed to reach alias, in connection order, ending
+// with the host itself. Only the first hop's own jump hosts are followed,
+// since ssh connects to later hops through the earlier ones.
+func (g *Graph) Chain(alias string) ([]ChainHop, error) {
+	host, ok := g.hosts[alias]
+	if !ok {
+		return nil, fmt.Errorf("unknown host %q", alias)
+	}
+	chain, err := g.chain(host, map[string]bool{alias: true})
+	if err != nil {
+		return nil, err
+	}
+	return append(chain, ChainHop{Hop: Hop{Host: host.HostName}, Alias: alias}), nil
+}
+
+func (g *Graph) chain(host Host, visiting map[string]bool) ([]ChainHop, error) {
+	var chain []ChainHop
+	for i, hop := range host.Jumps() {
+		step := ChainHop{Hop: hop}
+		jump, known := g.hosts[hop.Host]
+		if known {
+			step.Alias = hop.Host
+		}
+		if visiting[hop.Host] {
+			return nil, fmt.Errorf("%w through %s", ErrJumpLoop, hop.Host)
+		}
+
+		if known && i == 0 {
+			visiting[hop.Host] = true
+			inner, err := g.chain(jump, visiting)
+			delete(visiting, hop.Host)
+			if err != nil {
+				return nil, err
+			}
+			chain = append(chain, inner...)
+		}
+		chain = append(chain, step)
+	}
+	return chain, nil
+}
+
+// Route formats a chain as "local → bastion → target".
+func Route(chain []ChainHop) string {
+	names := []string{LocalNode}
+	for _, hop := range chain {
+		names = append(names, hop.Name())
+	}
+	return strings.Join(names, " → ")
+}
+
+// Problem describes an issue found in the jump graph.
+type Problem struct {
+	Alias   string
+	Message string
+}
+
+// Problems reports jump loops and jump hosts that are not configured aliases.
+// The latter are fine if the name resolves, but are often a typo.
+func (g *Graph) Problems() []Problem {
+	var problems []Problem
+	for _, alias := range g.order {
+		host := g.hosts[alias]
+		if _, err := g.Chain(alias); err != nil {
+			problems = append(problems, Problem{Alias: alias, Message: err.Error()})
+		}
+		for _, hop := range host.Jumps() {
+			if _, ok := g.hosts[hop.Host]; !ok {
+				problems = append(problems, Problem{Alias: alias, Message: fmt.Sprintf("jump host %s is not a configured alias", hop.Host)})
+			}
+		}
+	}
+	return problems
+}
+
+// Edge is a connection from one node of the graph to another.
+type Edge struct {
+	From string
+	To   string
+}
+
+// Edges returns every direct connection in the topology: from LocalNode to
+// hosts reached directly and from each jump host to the next step.
+func (g *Graph) Edges() []Edge {
+	seen := make(map[Edge]bool)
+	var edges []Edge
+	add := func(edge Edge) {
+		if !seen[edge] {
+			seen[edge] = true
+			edges = append(edges, edge)
+		}
+	}
+
+	for _, alias := range g.order {
+		host := g.hosts[alias]
+		if host.IsPattern() {
+			continue
+		}
+		from := LocalNode
+		for i, hop := range host.Jumps() {
+			if _, known := g.hosts[hop.Host]; !known && i == 0 {
+				// External first hops are reached directly
+				add(Edge{From: LocalNode, To: hop.Host})
+			}
+			if i > 0 {
+				add(Edge{From: from, To: hop.Host})
+			}
+			from = hop.Host
+		}
+		add(Edge{From: from, To: alias})
+	}
+	return edges
+}
+
+// IsAlias reports whether name is a configured host.
+func (g *Graph) IsAlias(name string) bool {
+	_, ok := g.hosts[name]
+	return ok
+}
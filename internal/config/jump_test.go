@@ -0,0 +1,153 @@
+package config
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseProxyJump(t *testing.T) {
+	tests := []struct {
+		value    string
+		expected []Hop
+	}{
+		{"", nil},
+		{"none", nil},
+		{"bastion", []Hop{{Host: "bastion"}}},
+		{"admin@bastion:2222, jump", []Hop{{User: "admin", Host: "bastion", Port: "2222"}, {Host: "jump"}}},
+		{"ssh://root@[2001:db8::1]:22", []Hop{{User: "root", Host: "2001:db8::1", Port: "22"}}},
+	}
+
+	for _, test := range tests {
+		if got := ParseProxyJump(test.value); !reflect.DeepEqual(got, test.expected) {
+			t.Errorf("ParseProxyJump(%q): expected %+v, got %+v", test.value, test.expected, got)
+		}
+	}
+}
+
+func TestParseProxyCommand(t *testing.T) {
+	tests := []struct {
+		value    string
+		expected Hop
+		ok       bool
+	}{
+		{"ssh -W %h:%p bastion", Hop{Host: "bastion"}, true},
+		{"/usr/bin/ssh -q -l admin -p 2222 bastion -W %h:%p", Hop{User: "admin", Host: "bastion", Port: "2222"}, true},
+		{"ssh -qW %h:%p ops@bastion", Hop{User: "ops", Host: "bastion"}, true},
+		{"ssh bastion nc %h %p", Hop{}, false},
+		{"nc -X 5 -x proxy:1080 %h %p", Hop{}, false},
+	}
+
+	for _, test := range tests {
+		got, ok := ParseProxyCommand(test.value)
+		if ok != test.ok || got != test.expected {
+			t.Errorf("ParseProxyCommand(%q): expected %+v %v, got %+v %v", test.value, test.expected, test.ok, got, ok)
+		}
+	}
+}
+
+func testGraph() *Graph {
+	return NewGraph([]Host{
+		{Alias: "bastion", HostName: "203.0.113.1"},
+		{Alias: "jump", HostName: "10.0.0.1", ProxyJump: "bastion"},
+		{Alias: "db", HostName: "10.0.1.1", ProxyJump: "jump"},
+		{Alias: "legacy", HostName: "10.0.2.1", ProxyCommand: "ssh -W %h:%p bastion"},
+		{Alias: "edge", HostName: "10.0.3.1", ProxyJump: "gw.example.com"},
+		{Alias: "loop-a", ProxyJump: "loop-b"},
+		{Alias: "loop-b", ProxyJump: "loop-a"},
+		{Alias: "*.internal", ProxyJump: "bastion"},
+	})
+}
+
+func TestGraph_Chain(t *testing.T) {
+	graph := testGraph()
+
+	tests := map[string]string{
+		"bastion": "local → bastion",
+		"db":      "local → bastion → jump → db",
+		"legacy":  "local → bastion → legacy",
+		"edge":    "local → gw.example.com → edge",
+	}
+	for alias, expected := range tests {
+		chain, err := graph.Chain(alias)
+		if err != nil {
+			t.Errorf("Chain(%q): unexpected error %v", alias, err)
+			continue
+		}
+		if got := Route(chain); got != expected {
+			t.Errorf("Chain(%q): expected %q, got %q", alias, expected, got)
+		}
+	}
+
+	if _, err := graph.Chain("loop-a"); !errors.Is(err, ErrJumpLoop) {
+		t.Errorf("Expected ErrJumpLoop, got %v", err)
+	}
+	if _, err := graph.Chain("missing"); err == nil {
+		t.Errorf("Expected error for unknown host")
+	}
+}
+
+func TestGraph_Problems(t *testing.T) {
+	var messages []string
+	for _, problem := range testGraph().Problems() {
+		messages = append(messages, problem.Alias+": "+problem.Message)
+	}
+
+	expected := []string{
+		"edge: jump host gw.example.com is not a configured alias",
+		"loop-a: jump loop through loop-a",
+		"loop-b: jump loop through loop-b",
+	}
+	if !reflect.DeepEqual(messages, expected) {
+		t.Errorf("Expected problems %q, got %q", expected, messages)
+	}
+}
+
+func TestGraph_Edges(t *testing.T) {
+	graph := NewGraph([]Host{
+		{Alias: "bastion", HostName: "203.0.113.1"},
+		{Alias: "db", ProxyJump: "bastion,inner"},
+		{Alias: "web", ProxyJump: "bastion"},
+	})
+
+	expected := []Edge{
+		{From: LocalNode, To: "bastion"},
+		{From: "bastion", To: "inner"},
+		{From: "inner", To: "db"},
+		{From: "bastion", To: "web"},
+	}
+	if got := graph.Edges(); !reflect.DeepEqual(got, expected) {
+		t.Errorf("Expected edges %+v, got %+v", expected, got)
+	}
+}
+
+func TestGraph_Write(t *testing.T) {
+	graph := NewGraph([]Host{
+		{Alias: "db", ProxyJump: "gw.example.com"},
+	})
+
+	var dot strings.Builder
+	if err := graph.WriteDOT(&dot); err != nil {
+		t.Fatalf("WriteDOT: %v", err)
+	}
+	for _, line := range []string{`"gw.example.com" [style=dashed];`, `"local" -> "gw.example.com";`, `"gw.example.com" -> "db";`} {
+		if !strings.Contains(dot.String(), line) {
+			t.Errorf("Expected DOT output to contain %q, got:\n%s", line, dot.String())
+		}
+	}
+
+	var mermaid strings.Builder
+	if err := graph.WriteMermaid(&mermaid); err != nil {
+		t.Fatalf("WriteMermaid: %v", err)
+	}
+	expected := "flowchart LR\n" +
+		"  n0([\"local\"])\n" +
+		"  n1(\"gw.example.com\")\n" +
+		"  n2[\"db\"]\n" +
+		"  n0 --> n1\n" +
+		"  n1 --> n2\n"
+	if mermaid.String() != expected {
+		t.Errorf("Expected Mermaid output:\n%s\ngot:\n%s", expected, mermaid.String())
+	}
+}
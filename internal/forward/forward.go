@@ -0,0 +1,293 @@
+// Package forward runs SSH port forwards as background processes and tracks
+// them across ssm invocations.
+package forward
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/antonjah/ssm/internal/config"
+	"github.com/antonjah/ssm/internal/xdg"
+)
+
+// DefaultGrace is how long a new forward must stay up before it is
+// considered started. ssh exits within it if the forward can't be set up.
+const DefaultGrace = time.Second
+
+// ErrPortInUse is returned when a forward's local port is already taken.
+var ErrPortInUse = errors.New("local port in use")
+
+// Forward is a running port forward.
+type Forward struct {
+	ID      int            `json:"id"`
+	Host    string         `json:"host"`
+	Spec    config.Forward `json:"forward"`
+	PID     int            `json:"pid"`
+	Started time.Time      `json:"started"`
+}
+
+// Manager starts, lists and stops forwards recorded in a state directory.
+type Manager struct {
+	// Dir holds the state file and each forward's ssh log.
+	Dir string
+	// SSHPath is the ssh binary to run. Defaults to "ssh".
+	SSHPath string
+	// Grace overrides DefaultGrace.
+	Grace time.Duration
+}
+
+// DefaultDir returns the directory forwards are tracked in.
+func DefaultDir() (string, error) {
+	dir, err := xdg.StateDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "forwards"), nil
+}
+
+// NewManager returns a manager using the default state directory.
+func NewManager(sshPath string) (*Manager, error) {
+	dir, err := DefaultDir()
+	if err != nil {
+		return nil, err
+	}
+	return &Manager{Dir: dir, SSHPath: sshPath}, nil
+}
+
+// Args returns the ssh arguments that run spec through host without a
+// remote command. BatchMode stops ssh from prompting, since it has no
+// terminal to prompt on.
+func Args(host string, spec config.Forward) []string {
+	return []string{
+		"-N",
+		"-o", "ExitOnForwardFailure=yes",
+		"-o", "BatchMode=yes",
+		"-" + string(spec.Type), spec.Arg(),
+		host,
+	}
+}
+
+// List returns the running forwards ordered by ID. Forwards whose process
+// has exited are dropped from the state.
+func (m *Manager) List() ([]Forward, error) {
+	var forwards []Forward
+	err := m.update(func(state []Forward) ([]Forward, error) {
+		forwards = state
+		return state, nil
+	})
+	return forwards, err
+}
+
+// Start checks that spec's local port is free and launches ssh in the
+// background. It returns an error with ssh's output if ssh exits within the
+// grace period.
+func (m *Manager) Start(host string, spec config.Forward) (Forward, error) {
+	var forward Forward
+	err := m.update(func(state []Forward) ([]Forward, error) {
+		if err := checkPort(state, spec); err != nil {
+			return nil, err
+		}
+
+		forward = Forward{ID: nextID(state), Host: host, Spec: spec}
+		if err := m.launch(&forward); err != nil {
+			return nil, err
+		}
+		return append(state, forward), nil
+	})
+	return forward, err
+}
+
+// Stop terminates the forward with the given ID. Forwards whose PID was
+// reused by another process are dropped before this, so only ssh is killed.
+func (m *Manager) Stop(id int) error {
+	return m.update(func(state []Forward) ([]Forward, error) {
+		for i, forward := range state {
+			if forward.ID != id {
+				continue
+			}
+			if err := syscall.Kill(forward.PID, syscall.SIGTERM); err != nil && !errors.Is(err, syscall.ESRCH) {
+				return nil, fmt.Errorf("failed to stop forward %d: %w", id, err)
+			}
+			os.Remove(m.logPath(id))
+			return append(state[:i], state[i+1:]...), nil
+		}
+		return nil, fmt.Errorf("no forward with ID %d", id)
+	})
+}
+
+// checkPort reports ErrPortInUse if another tracked forward or any other
+// process already listens on spec's local address.
+func checkPort(state []Forward, spec config.Forward) error {
+	address := spec.LocalAddress()
+	if address == "" {
+		return nil
+	}
+	for _, forward := range state {
+		if forward.Spec.Type != config.RemoteForward && forward.Spec.LocalPort() == spec.LocalPort() {
+			return fmt.Errorf("%w: %s is forwarded to %s by #%d", ErrPortInUse, spec.LocalPort(), forward.Host, forward.ID)
+		}
+	}
+	listener, err := net.Listen("tcp", address)
+	if err != nil {
+		return fmt.Errorf("%w: %s", ErrPortInUse, address)
+	}
+	return listener.Close()
+}
+
+// nextID returns one more than the highest ID in state.
+func nextID(state []Forward) int {
+	id := 1
+	for _, forward := range state {
+		if forward.ID >= id {
+			id = forward.ID + 1
+		}
+	}
+	return id
+}
+
+// launch starts ssh for forward in its own session, so it outlives ssm, and
+// waits out the grace period.
+func (m *Manager) launch(forward *Forward) error {
+	sshPath := m.SSHPath
+	if sshPath == "" {
+		sshPath = "ssh"
+	}
+	grace := m.Grace
+	if grace <= 0 {
+		grace = DefaultGrace
+	}
+
+	logPath := m.logPath(forward.ID)
+	logFile, err := os.Create(logPath)
+	if err != nil {
+		return fmt.Errorf("failed to create forward log: %w", err)
+	}
+	defer logFile.Close()
+
+	cmd := exec.Command(sshPath, Args(forward.Host, forward.Spec)...)
+	cmd.Stdout = logFile
+	cmd.Stderr = logFile
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setsid: true}
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("failed to start ssh: %w", err)
+	}
+
+	// Keep reaping in the background so a forward that exits later while
+	// ssm is still running doesn't linger as a zombie
+	exited := make(chan error, 1)
+	go func() { exited <- cmd.Wait() }()
+
+	select {
+	case err := <-exited:
+		output, _ := os.ReadFile(logPath)
+		os.Remove(logPath)
+		if message := strings.TrimSpace(string(output)); message != "" {
+			return fmt.Errorf("ssh exited: %s", message)
+		}
+		return fmt.Errorf("ssh exited: %v", err)
+	case <-time.After(grace):
+	}
+
+	forward.PID = cmd.Process.Pid
+	forward.Started = time.Now()
+	return nil
+}
+
+func (m *Manager) statePath() string {
+	return filepath.Join(m.Dir, "forwards.json")
+}
+
+func (m *Manager) logPath(id int) string {
+	return filepath.Join(m.Dir, fmt.Sprintf("%d.log", id))
+}
+
+// update loads the state under an exclusive lock, drops forwards that have
+// exited, applies fn and saves the result. The state is left unchanged if
+// fn fails.
+func (m *Manager) update(fn func([]Forward) ([]Forward, error)) error {
+	if err := os.MkdirAll(m.Dir, 0o700); err != nil {
+		return fmt.Errorf("failed to create state directory: %w", err)
+	}
+	lock, err := os.OpenFile(filepath.Join(m.Dir, "forwards.lock"), os.O_CREATE|os.O_RDWR, 0o600)
+	if err != nil {
+		return fmt.Errorf("failed to lock forward state: %w", err)
+	}
+	defer lock.Close()
+	if err := syscall.Flock(int(lock.Fd()), syscall.LOCK_EX); err != nil {
+		return fmt.Errorf("failed to lock forward state: %w", err)
+	}
+
+	var state []Forward
+	if data, err := os.ReadFile(m.statePath()); err == nil {
+		if err := json.Unmarshal(data, &state); err != nil {
+			return fmt.Errorf("failed to read forward state: %w", err)
+		}
+	} else if !os.IsNotExist(err) {
+		return fmt.Errorf("failed to read forward state: %w", err)
+	}
+
+	var live []Forward
+	for _, forward := range state {
+		if running(forward) {
+			live = append(live, forward)
+		} else {
+			os.Remove(m.logPath(forward.ID))
+		}
+	}
+
+	updated, err := fn(live)
+	if err != nil {
+		return err
+	}
+	sort.Slice(updated, func(i, j int) bool { return updated[i].ID < updated[j].ID })
+	if updated == nil {
+		updated = []Forward{}
+	}
+
+	data, err := json.MarshalIndent(updated, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := m.statePath() + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		return fmt.Errorf("failed to write forward state: %w", err)
+	}
+	return os.Rename(tmp, m.statePath())
+}
+
+// running reports whether forward's ssh is still running. A process with
+// its PID isn't enough, since the PID may have been reused after ssh exited,
+// so the process must also still have the forward's command line.
+func running(forward Forward) bool {
+	if forward.PID <= 0 {
+		return false
+	}
+	args, err := commandLine(forward.PID)
+	if err != nil {
+		return false
+	}
+	return strings.Contains(args, strings.Join(Args(forward.Host, forward.Spec), " "))
+}
+
+// commandLine returns the arguments of the process with the given PID,
+// joined by spaces. /proc is used where there is one and ps elsewhere.
+func commandLine(pid int) (string, error) {
+	if data, err := os.ReadFile(fmt.Sprintf("/proc/%d/cmdline", pid)); err == nil {
+		return strings.ReplaceAll(strings.TrimRight(string(data), "\x00"), "\x00", " "), nil
+	}
+	output, err := exec.Command("ps", "-o", "args=", "-p", strconv.Itoa(pid)).Output()
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(output)), nil
+}
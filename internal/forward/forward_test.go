@@ -0,0 +1,146 @@
+package forward
+
+import (
+	"encoding/json"
+	"errors"
+	"net"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/antonjah/ssm/internal/config"
+)
+
+// fakeSSH writes an ssh stand-in running script and returns its path.
+func fakeSSH(t *testing.T, script string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "ssh")
+	if err := os.WriteFile(path, []byte("#!/bin/sh\n"+script+"\n"), 0o755); err != nil {
+		t.Fatalf("Failed to write fake ssh: %v", err)
+	}
+	return path
+}
+
+// freePort returns a local port that nothing listens on.
+func freePort(t *testing.T) string {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	defer listener.Close()
+	_, port, _ := net.SplitHostPort(listener.Addr().String())
+	return port
+}
+
+func TestArgs(t *testing.T) {
+	spec := config.Forward{Type: config.LocalForward, Listen: "8080", Target: "localhost:80"}
+	expected := []string{"-N", "-o", "ExitOnForwardFailure=yes", "-o", "BatchMode=yes", "-L", "8080:localhost:80", "web"}
+	if got := Args("web", spec); !reflect.DeepEqual(got, expected) {
+		t.Errorf("Expected %v, got %v", expected, got)
+	}
+}
+
+// keepAlive is a fake ssh that runs until it is stopped. It doesn't exec
+// sleep, so that the process keeps the forward's command line.
+const keepAlive = "while :; do sleep 0.1; done"
+
+func TestManager(t *testing.T) {
+	manager := &Manager{Dir: t.TempDir(), SSHPath: fakeSSH(t, keepAlive), Grace: 50 * time.Millisecond}
+	port := freePort(t)
+	spec := config.Forward{Type: config.LocalForward, Listen: port, Target: "localhost:80"}
+
+	started, err := manager.Start("web", spec)
+	if err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+	if started.ID != 1 || started.PID == 0 {
+		t.Errorf("Expected forward #1 with a PID, got %+v", started)
+	}
+
+	// The fake ssh doesn't listen, so the tracked forward must be detected
+	if _, err := manager.Start("db", spec); !errors.Is(err, ErrPortInUse) {
+		t.Errorf("Expected ErrPortInUse for a tracked port, got %v", err)
+	}
+
+	// Remote forwards don't use a local port
+	remote := config.Forward{Type: config.RemoteForward, Listen: port, Target: "localhost:80"}
+	second, err := manager.Start("db", remote)
+	if err != nil {
+		t.Fatalf("Start failed for remote forward: %v", err)
+	}
+
+	forwards, err := manager.List()
+	if err != nil {
+		t.Fatalf("List failed: %v", err)
+	}
+	if len(forwards) != 2 || forwards[0].Host != "web" || forwards[1].ID != second.ID {
+		t.Errorf("Expected both forwards listed, got %+v", forwards)
+	}
+
+	for _, forward := range forwards {
+		if err := manager.Stop(forward.ID); err != nil {
+			t.Errorf("Stop(%d) failed: %v", forward.ID, err)
+		}
+	}
+	if err := manager.Stop(started.ID); err == nil {
+		t.Errorf("Expected error stopping a forward twice")
+	}
+	if forwards, _ := manager.List(); len(forwards) != 0 {
+		t.Errorf("Expected no forwards after stopping, got %+v", forwards)
+	}
+}
+
+func TestManager_PortInUse(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	defer listener.Close()
+	_, port, _ := net.SplitHostPort(listener.Addr().String())
+
+	manager := &Manager{Dir: t.TempDir(), SSHPath: fakeSSH(t, "exec sleep 30"), Grace: 50 * time.Millisecond}
+	spec := config.Forward{Type: config.DynamicForward, Listen: port}
+	if _, err := manager.Start("web", spec); !errors.Is(err, ErrPortInUse) {
+		t.Errorf("Expected ErrPortInUse, got %v", err)
+	}
+}
+
+func TestManager_SSHFails(t *testing.T) {
+	manager := &Manager{
+		Dir:     t.TempDir(),
+		SSHPath: fakeSSH(t, "echo 'Permission denied (publickey).' >&2; exit 255"),
+		Grace:   time.Second,
+	}
+	spec := config.Forward{Type: config.DynamicForward, Listen: freePort(t)}
+
+	_, err := manager.Start("web", spec)
+	if err == nil || !strings.Contains(err.Error(), "Permission denied") {
+		t.Errorf("Expected ssh's error, got %v", err)
+	}
+	if forwards, _ := manager.List(); len(forwards) != 0 {
+		t.Errorf("Expected failed forward not to be tracked, got %+v", forwards)
+	}
+}
+
+func TestManager_ReusedPID(t *testing.T) {
+	manager := &Manager{Dir: t.TempDir()}
+
+	// The forward's PID now belongs to another process, the test itself
+	spec := config.Forward{Type: config.DynamicForward, Listen: "1080"}
+	state := []Forward{{ID: 1, Host: "web", Spec: spec, PID: os.Getpid(), Started: time.Now()}}
+	data, _ := json.Marshal(state)
+	if err := os.WriteFile(manager.statePath(), data, 0o600); err != nil {
+		t.Fatalf("Failed to write state: %v", err)
+	}
+
+	if forwards, err := manager.List(); err != nil || len(forwards) != 0 {
+		t.Errorf("Expected the stale forward to be dropped, got %+v (%v)", forwards, err)
+	}
+	if err := manager.Stop(1); err == nil {
+		t.Error("Expected an error stopping a forward whose PID was reused")
+	}
+}
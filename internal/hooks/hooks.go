@@ -0,0 +1,112 @@
+// Package hooks runs the commands configured to run before and after a
+// connection, such as loading a key into ssh-agent or checking that a VPN is
+// up. A failing pre-connect hook vetoes the connection.
+package hooks
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/antonjah/ssm/internal/config"
+	"github.com/antonjah/ssm/internal/settings"
+)
+
+// ErrVetoed wraps the error of the pre-connect hook that stopped a
+// connection.
+var ErrVetoed = errors.New("vetoed by hook")
+
+// Runner runs hooks for a connection to Host.
+type Runner struct {
+	// Hooks are the hooks that apply to Host.
+	Hooks []settings.Hook
+	Host  config.Host
+	// Transport is the program the session connects with, e.g. "mosh".
+	Transport string
+	// Stdin, Stdout and Stderr are the hooks' standard streams. They default
+	// to ssm's own, so hooks can prompt for a passphrase or change the
+	// terminal's colours.
+	Stdin          io.Reader
+	Stdout, Stderr io.Writer
+}
+
+// PreConnect runs the pre-connect hooks in order and stops at the first one
+// that fails or times out, returning an error wrapping ErrVetoed.
+func (r *Runner) PreConnect() error {
+	for _, hook := range r.Hooks {
+		if hook.Event != settings.PreConnect {
+			continue
+		}
+		if err := r.run(hook, r.env(hook.Event)); err != nil {
+			return fmt.Errorf("%w %q: %v", ErrVetoed, hook.String(), err)
+		}
+	}
+	return nil
+}
+
+// PostConnect runs the post-connect hooks once the session has ended with
+// code after duration. All of them run; failures are returned together.
+func (r *Runner) PostConnect(code int, duration time.Duration) error {
+	var errs []error
+	for _, hook := range r.Hooks {
+		if hook.Event != settings.PostConnect {
+			continue
+		}
+		env := append(r.env(hook.Event),
+			"SSM_EXIT_CODE="+strconv.Itoa(code),
+			"SSM_DURATION="+strconv.Itoa(int(duration.Seconds())),
+		)
+		if err := r.run(hook, env); err != nil {
+			errs = append(errs, fmt.Errorf("hook %q: %w", hook.String(), err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// run runs a single hook with sh, killing it when its timeout runs out.
+func (r *Runner) run(hook settings.Hook, env []string) error {
+	timeout := hook.Deadline()
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, "sh", "-c", hook.Command)
+	cmd.Env = append(os.Environ(), env...)
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = r.Stdin, r.Stdout, r.Stderr
+	if cmd.Stdin == nil {
+		cmd.Stdin = os.Stdin
+	}
+	if cmd.Stdout == nil {
+		cmd.Stdout = os.Stdout
+	}
+	if cmd.Stderr == nil {
+		cmd.Stderr = os.Stderr
+	}
+	// Don't wait for background processes the hook left holding its output
+	cmd.WaitDelay = time.Second
+
+	err := cmd.Run()
+	if ctx.Err() == context.DeadlineExceeded {
+		return fmt.Errorf("timed out after %s", timeout)
+	}
+	return err
+}
+
+// env returns the variables describing the connection to hooks.
+func (r *Runner) env(event string) []string {
+	return []string{
+		"SSM_HOOK=" + event,
+		"SSM_HOST=" + r.Host.Alias,
+		"SSM_HOSTNAME=" + r.Host.EffectiveHostName(),
+		"SSM_HOST_USER=" + r.Host.EffectiveUser(),
+		"SSM_PORT=" + r.Host.EffectivePort(),
+		"SSM_TAGS=" + strings.Join(r.Host.Tags, ","),
+		"SSM_DESCRIPTION=" + r.Host.Description,
+		"SSM_TRANSPORT=" + r.Transport,
+	}
+}
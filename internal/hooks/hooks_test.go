@@ -0,0 +1,81 @@
+package hooks
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/antonjah/ssm/internal/config"
+	"github.com/antonjah/ssm/internal/settings"
+)
+
+func newRunner(hooks ...settings.Hook) (*Runner, *bytes.Buffer) {
+	var out bytes.Buffer
+	return &Runner{
+		Hooks:     hooks,
+		Host:      config.Host{Alias: "db", HostName: "10.0.0.5", User: "deploy", Tags: []string{"prod", "db"}},
+		Transport: "ssh",
+		Stdin:     strings.NewReader(""),
+		Stdout:    &out,
+		Stderr:    &out,
+	}, &out
+}
+
+func TestRunner_PreConnect(t *testing.T) {
+	runner, out := newRunner(
+		settings.Hook{Event: settings.PreConnect, Command: `echo "$SSM_HOOK $SSM_HOST $SSM_HOST_USER@$SSM_HOSTNAME:$SSM_PORT $SSM_TAGS $SSM_TRANSPORT"`},
+		settings.Hook{Event: settings.PostConnect, Command: "echo post"},
+	)
+	if err := runner.PreConnect(); err != nil {
+		t.Fatalf("PreConnect failed: %v", err)
+	}
+	if got := strings.TrimSpace(out.String()); got != "pre-connect db deploy@10.0.0.5:22 prod,db ssh" {
+		t.Errorf("Unexpected hook output %q", got)
+	}
+}
+
+func TestRunner_PreConnect_Veto(t *testing.T) {
+	runner, out := newRunner(
+		settings.Hook{Name: "vpn", Event: settings.PreConnect, Command: "exit 3"},
+		settings.Hook{Event: settings.PreConnect, Command: "echo second"},
+	)
+	err := runner.PreConnect()
+	if !errors.Is(err, ErrVetoed) || !strings.Contains(err.Error(), `"vpn"`) {
+		t.Errorf("Expected the vpn hook to veto, got %v", err)
+	}
+	if out.Len() != 0 {
+		t.Errorf("Expected no hooks to run after a veto, got %q", out.String())
+	}
+}
+
+func TestRunner_Timeout(t *testing.T) {
+	runner, _ := newRunner(settings.Hook{
+		Event:   settings.PreConnect,
+		Command: "sleep 5",
+		Timeout: settings.Duration(100 * time.Millisecond),
+	})
+	start := time.Now()
+	err := runner.PreConnect()
+	if err == nil || !strings.Contains(err.Error(), "timed out after 100ms") {
+		t.Errorf("Expected a timeout, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("Expected the hook to be stopped, took %s", elapsed)
+	}
+}
+
+func TestRunner_PostConnect(t *testing.T) {
+	runner, out := newRunner(
+		settings.Hook{Event: settings.PostConnect, Command: "false"},
+		settings.Hook{Event: settings.PostConnect, Command: `echo "$SSM_EXIT_CODE $SSM_DURATION"`},
+	)
+	err := runner.PostConnect(255, 90*time.Second)
+	if err == nil || !strings.Contains(err.Error(), `hook "false"`) {
+		t.Errorf("Expected the failing hook to be reported, got %v", err)
+	}
+	if got := strings.TrimSpace(out.String()); got != "255 90" {
+		t.Errorf("Expected all post-connect hooks to run, got %q", got)
+	}
+}
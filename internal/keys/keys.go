@@ -0,0 +1,380 @@
+// Package keys defines the host menu's key bindings: the actions that can be
+// bound in the host list and the views opened from it, the default, vim and
+// emacs presets, and remapping actions from ssm's settings.
+package keys
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/key"
+)
+
+// Presets.
+const (
+	Default = "default"
+	Vim     = "vim"
+	Emacs   = "emacs"
+)
+
+// Presets lists the preset names.
+var Presets = []string{Default, Vim, Emacs}
+
+// Views are the parts of the menu that read keys. A key may be bound to
+// different actions in different views, but to only one in each.
+const (
+	ViewList     = "host list"
+	ViewBrowser  = "file browser"
+	ViewExec     = "command output"
+	ViewForwards = "port forwards"
+	ViewTransfer = "file copy"
+	ViewYank     = "clipboard menu"
+	// ViewConfirm asks a yes or no question.
+	ViewConfirm = "confirmation"
+	// ViewInput reads a line of text, such as a command to run.
+	ViewInput = "text input"
+)
+
+// Views lists the views.
+var Views = []string{ViewList, ViewBrowser, ViewExec, ViewForwards, ViewTransfer, ViewYank, ViewConfirm, ViewInput}
+
+// Map holds the binding of every menu action. The same bindings decide which
+// keys the menu handles and what its help shows.
+type Map struct {
+	Up        key.Binding
+	Down      key.Binding
+	PageUp    key.Binding
+	PageDown  key.Binding
+	Home      key.Binding
+	End       key.Binding
+	Filter    key.Binding
+	Connect   key.Binding
+	Tiled     key.Binding
+	Cluster   key.Binding
+	Toggle    key.Binding
+	ToggleAll key.Binding
+	Exec      key.Binding
+	Forwards  key.Binding
+	Copy      key.Binding
+	Yank      key.Binding
+	Browse    key.Binding
+	Edit      key.Binding
+	Details   key.Binding
+	Note      key.Binding
+	Preview   key.Binding
+	// Table switches between the list and table views, and Sort and
+	// SortReverse order the table by its columns.
+	Table       key.Binding
+	Sort        key.Binding
+	SortReverse key.Binding
+	Help        key.Binding
+	// Back closes the details popup or clears the filter, and quits when
+	// there is neither. In the other views it goes back to the host list.
+	Back key.Binding
+	// Quit also leaves the file browser and the clipboard menu.
+	Quit key.Binding
+
+	// The file browser's actions
+	SwitchPane key.Binding
+	Open       key.Binding
+	Parent     key.Binding
+	CopyAcross key.Binding
+	Rename     key.Binding
+	Delete     key.Binding
+	// Rerun runs a command again on the hosts where it failed.
+	Rerun key.Binding
+	// Select starts or stops a forward, copies, or accepts typed text.
+	Select key.Binding
+	// Complete completes a local path, and PreviousOption and NextOption
+	// change the file copy's direction and tool.
+	Complete       key.Binding
+	PreviousOption key.Binding
+	NextOption     key.Binding
+	// Yes and No answer a yes or no question. Back also says no.
+	Yes key.Binding
+	No  key.Binding
+}
+
+// action describes a bindable action.
+type action struct {
+	name    string
+	help    string
+	binding func(*Map) *key.Binding
+	// views are the views the action is bound in
+	views []string
+}
+
+// Shorthands for the views of actions.
+var (
+	list       = []string{ViewList}
+	moving     = []string{ViewList, ViewBrowser, ViewExec, ViewForwards, ViewTransfer, ViewYank}
+	jumping    = []string{ViewList, ViewBrowser}
+	leaving    = []string{ViewList, ViewBrowser, ViewYank}
+	browser    = []string{ViewBrowser}
+	selecting  = []string{ViewForwards, ViewTransfer, ViewYank, ViewInput}
+	transfer   = []string{ViewTransfer}
+	confirming = []string{ViewConfirm}
+)
+
+// actions lists the actions in the order of Map's fields.
+var actions = []action{
+	{"up", "up", func(m *Map) *key.Binding { return &m.Up }, moving},
+	{"down", "down", func(m *Map) *key.Binding { return &m.Down }, moving},
+	{"page_up", "prev page", func(m *Map) *key.Binding { return &m.PageUp }, list},
+	{"page_down", "next page", func(m *Map) *key.Binding { return &m.PageDown }, list},
+	{"home", "go to start", func(m *Map) *key.Binding { return &m.Home }, jumping},
+	{"end", "go to end", func(m *Map) *key.Binding { return &m.End }, jumping},
+	{"filter", "filter", func(m *Map) *key.Binding { return &m.Filter }, list},
+	{"connect", "select", func(m *Map) *key.Binding { return &m.Connect }, list},
+	{"tiled", "open tiled", func(m *Map) *key.Binding { return &m.Tiled }, list},
+	{"cluster", "cluster", func(m *Map) *key.Binding { return &m.Cluster }, list},
+	{"toggle", "toggle", func(m *Map) *key.Binding { return &m.Toggle }, list},
+	{"toggle_all", "toggle all", func(m *Map) *key.Binding { return &m.ToggleAll }, list},
+	{"exec", "run command", func(m *Map) *key.Binding { return &m.Exec }, list},
+	{"forwards", "port forwards", func(m *Map) *key.Binding { return &m.Forwards }, list},
+	{"copy", "copy files", func(m *Map) *key.Binding { return &m.Copy }, list},
+	{"yank", "copy to clipboard", func(m *Map) *key.Binding { return &m.Yank }, list},
+	{"browse", "browse files", func(m *Map) *key.Binding { return &m.Browse }, list},
+	{"edit", "edit config", func(m *Map) *key.Binding { return &m.Edit }, list},
+	{"details", "view details", func(m *Map) *key.Binding { return &m.Details }, list},
+	{"note", "edit note", func(m *Map) *key.Binding { return &m.Note }, list},
+	{"preview", "toggle preview", func(m *Map) *key.Binding { return &m.Preview }, list},
+	{"table", "toggle table", func(m *Map) *key.Binding { return &m.Table }, list},
+	{"sort", "sort", func(m *Map) *key.Binding { return &m.Sort }, list},
+	{"sort_reverse", "reverse sort", func(m *Map) *key.Binding { return &m.SortReverse }, list},
+	{"help", "more", func(m *Map) *key.Binding { return &m.Help }, list},
+	{"back", "go back", func(m *Map) *key.Binding { return &m.Back }, Views},
+	{"quit", "quit", func(m *Map) *key.Binding { return &m.Quit }, leaving},
+	{"switch_pane", "switch pane", func(m *Map) *key.Binding { return &m.SwitchPane }, browser},
+	{"open", "open", func(m *Map) *key.Binding { return &m.Open }, browser},
+	{"parent", "parent", func(m *Map) *key.Binding { return &m.Parent }, browser},
+	{"copy_across", "copy across", func(m *Map) *key.Binding { return &m.CopyAcross }, browser},
+	{"rename", "rename", func(m *Map) *key.Binding { return &m.Rename }, browser},
+	{"delete", "delete", func(m *Map) *key.Binding { return &m.Delete }, browser},
+	{"rerun", "re-run failed", func(m *Map) *key.Binding { return &m.Rerun }, []string{ViewExec}},
+	{"select", "select", func(m *Map) *key.Binding { return &m.Select }, selecting},
+	{"complete", "complete", func(m *Map) *key.Binding { return &m.Complete }, transfer},
+	{"previous_option", "change", func(m *Map) *key.Binding { return &m.PreviousOption }, transfer},
+	{"next_option", "change", func(m *Map) *key.Binding { return &m.NextOption }, transfer},
+	{"yes", "yes", func(m *Map) *key.Binding { return &m.Yes }, confirming},
+	{"no", "no", func(m *Map) *key.Binding { return &m.No }, confirming},
+}
+
+// Actions returns the names of the actions that can be bound.
+func Actions() []string {
+	names := make([]string, len(actions))
+	for i, a := range actions {
+		names[i] = a.name
+	}
+	return names
+}
+
+// presets maps preset names to the keys of the actions they change from
+// the default preset.
+var presets = map[string]map[string][]string{
+	Default: {
+		"up":           {"up", "k"},
+		"down":         {"down", "j"},
+		"page_up":      {"left", "h", "pgup"},
+		"page_down":    {"right", "l", "pgdown"},
+		"home":         {"home", "g"},
+		"end":          {"end", "G"},
+		"filter":       {"/"},
+		"connect":      {"enter"},
+		"tiled":        {"t"},
+		"cluster":      {"c"},
+		"toggle":       {" "},
+		"toggle_all":   {"*"},
+		"exec":         {"x"},
+		"forwards":     {"f"},
+		"copy":         {"p"},
+		"yank":         {"y"},
+		"browse":       {"b"},
+		"edit":         {"e"},
+		"details":      {"v"},
+		"note":         {"n"},
+		"preview":      {"s"},
+		"table":        {"T"},
+		"sort":         {"o"},
+		"sort_reverse": {"O"},
+		"help":         {"?"},
+		"back":         {"esc"},
+		"quit":         {"q"},
+		// The views opened from the host list
+		"switch_pane":     {"tab"},
+		"open":            {"enter", "right", "l"},
+		"parent":          {"backspace", "left", "h"},
+		"copy_across":     {"c"},
+		"rename":          {"r"},
+		"delete":          {"d", "delete"},
+		"rerun":           {"r"},
+		"select":          {"enter"},
+		"complete":        {"tab"},
+		"previous_option": {"left", "h"},
+		"next_option":     {"right", "l", " "},
+		"yes":             {"y", "Y"},
+		"no":              {"n", "N", "enter"},
+	},
+	Vim: {
+		"page_up":   {"ctrl+b", "ctrl+u", "pgup"},
+		"page_down": {"ctrl+f", "ctrl+d", "pgdown"},
+		"connect":   {"enter", "l"},
+		"details":   {"v", "K"},
+		"edit":      {"e", "i"},
+		"toggle":    {" ", "m"},
+	},
+	Emacs: {
+		"up":        {"up", "ctrl+p"},
+		"down":      {"down", "ctrl+n"},
+		"page_up":   {"pgup", "alt+v"},
+		"page_down": {"pgdown", "ctrl+v"},
+		"home":      {"home", "alt+<"},
+		"end":       {"end", "alt+>"},
+		"filter":    {"/", "ctrl+s"},
+		"toggle":    {" ", "ctrl+@"},
+		"yank":      {"alt+w"},
+		"back":      {"esc", "ctrl+g"},
+	},
+}
+
+// New returns the preset's bindings with the actions in bindings remapped
+// to the given keys. It fails for unknown presets, actions and keys, and
+// when a key is bound to two actions.
+func New(preset string, bindings map[string][]string) (Map, error) {
+	if preset == "" {
+		preset = Default
+	}
+	changes, ok := presets[preset]
+	if !ok {
+		return Map{}, fmt.Errorf("unknown preset %q (expected %s)", preset, strings.Join(Presets, ", "))
+	}
+	var m Map
+	for _, a := range actions {
+		keys := presets[Default][a.name]
+		if changed, ok := changes[a.name]; ok {
+			keys = changed
+		}
+		*a.binding(&m) = newBinding(keys, a.help)
+	}
+	for _, name := range slices.Sorted(maps.Keys(bindings)) {
+		if err := m.Remap(name, bindings[name]); err != nil {
+			return Map{}, err
+		}
+	}
+	return m, m.Conflicts()
+}
+
+// Remap binds the action called name to keys. An empty list unbinds it.
+func (m *Map) Remap(name string, keys []string) error {
+	i := slices.IndexFunc(actions, func(a action) bool { return a.name == name })
+	if i < 0 {
+		return fmt.Errorf("unknown action %q (expected one of %s)", name, strings.Join(Actions(), ", "))
+	}
+	normalized := make([]string, len(keys))
+	for j, k := range keys {
+		var err error
+		if normalized[j], err = Parse(k); err != nil {
+			return fmt.Errorf("%s: %w", name, err)
+		}
+	}
+	*actions[i].binding(m) = newBinding(normalized, actions[i].help)
+	return nil
+}
+
+// Conflicts reports keys that are bound to more than one action in the same
+// view.
+func (m Map) Conflicts() error {
+	var conflicts []string
+	for _, view := range Views {
+		owners := make(map[string]string)
+		for _, a := range actions {
+			if !slices.Contains(a.views, view) {
+				continue
+			}
+			for _, k := range a.binding(&m).Keys() {
+				if owner, ok := owners[k]; ok {
+					conflict := fmt.Sprintf("%s is bound to both %s and %s", Display(k), owner, a.name)
+					// Actions shared by several views would clash in each
+					if !slices.ContainsFunc(conflicts, func(c string) bool { return strings.HasPrefix(c, conflict+" ") }) {
+						conflicts = append(conflicts, fmt.Sprintf("%s in the %s", conflict, view))
+					}
+					continue
+				}
+				owners[k] = a.name
+			}
+		}
+	}
+	if len(conflicts) > 0 {
+		return fmt.Errorf("conflicting key bindings: %s", strings.Join(conflicts, "; "))
+	}
+	return nil
+}
+
+// IsZero reports whether m has no bindings at all, as a Map that was never
+// set up.
+func (m Map) IsZero() bool {
+	for _, a := range actions {
+		if len(a.binding(&m).Keys()) > 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// newBinding returns a binding of keys whose help shows them all.
+func newBinding(keys []string, help string) key.Binding {
+	if len(keys) == 0 {
+		return key.NewBinding(key.WithDisabled())
+	}
+	shown := make([]string, len(keys))
+	for i, k := range keys {
+		shown[i] = Display(k)
+	}
+	return key.NewBinding(key.WithKeys(keys...), key.WithHelp(strings.Join(shown, "/"), help))
+}
+
+// named lists the key names Bubble Tea reports, besides ctrl+ and alt+
+// combinations and single characters.
+var named = []string{
+	"enter", "esc", "tab", "shift+tab", "backspace", "delete", "insert",
+	"up", "down", "left", "right", "home", "end", "pgup", "pgdown",
+	"shift+up", "shift+down", "shift+left", "shift+right",
+	"f1", "f2", "f3", "f4", "f5", "f6", "f7", "f8", "f9", "f10", "f11", "f12",
+}
+
+// Parse checks a key as written in the settings and returns it as Bubble
+// Tea reports it. "space" is accepted for the space bar.
+func Parse(k string) (string, error) {
+	if k == "space" || k == " " {
+		return " ", nil
+	}
+	rest := strings.TrimPrefix(k, "alt+")
+	switch {
+	case len([]rune(rest)) == 1 && rest != " ":
+		return k, nil
+	case slices.Contains(named, rest):
+		return k, nil
+	case strings.HasPrefix(rest, "ctrl+") && len(rest) > len("ctrl+"):
+		return k, nil
+	}
+	return "", fmt.Errorf("unknown key %q (use a character, space, a name such as enter or pgdown, or ctrl+ or alt+ combinations)", k)
+}
+
+// Display returns a key as the help shows it.
+func Display(k string) string {
+	switch k {
+	case " ":
+		return "space"
+	case "up":
+		return "↑"
+	case "down":
+		return "↓"
+	case "left":
+		return "←"
+	case "right":
+		return "→"
+	}
+	return k
+}
@@ -0,0 +1,108 @@
+package keys
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/charmbracelet/bubbles/key"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+func press(k string) tea.KeyMsg {
+	return tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(k)}
+}
+
+func TestNew(t *testing.T) {
+	for _, preset := range Presets {
+		m, err := New(preset, nil)
+		if err != nil {
+			t.Errorf("Preset %s: %v", preset, err)
+			continue
+		}
+		if !key.Matches(press("x"), m.Exec) || !key.Matches(tea.KeyMsg{Type: tea.KeyEnter}, m.Connect) {
+			t.Errorf("Preset %s: expected x to run a command and enter to connect", preset)
+		}
+	}
+
+	vim, _ := New(Vim, nil)
+	if !key.Matches(press("l"), vim.Connect) || key.Matches(press("l"), vim.PageDown) {
+		t.Error("Expected l to connect in the vim preset")
+	}
+	emacs, _ := New(Emacs, nil)
+	if !key.Matches(tea.KeyMsg{Type: tea.KeyCtrlN}, emacs.Down) {
+		t.Error("Expected ctrl+n to move down in the emacs preset")
+	}
+	if m, _ := New("", nil); m.Toggle.Help().Key != "space" || m.Up.Help().Key != "↑/k" {
+		t.Errorf("Unexpected help keys %q and %q", m.Toggle.Help().Key, m.Up.Help().Key)
+	}
+}
+
+func TestNew_Remap(t *testing.T) {
+	m, err := New(Default, map[string][]string{"exec": {"!", "alt+x"}, "edit": {}, "toggle_all": {"space"}, "toggle": {"m"}})
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	if !key.Matches(press("!"), m.Exec) || key.Matches(press("x"), m.Exec) || m.Exec.Help().Key != "!/alt+x" {
+		t.Errorf("Expected exec to be bound to ! and alt+x, got %v", m.Exec.Keys())
+	}
+	if m.Edit.Enabled() {
+		t.Error("Expected an empty list to unbind edit")
+	}
+	if !key.Matches(tea.KeyMsg{Type: tea.KeySpace, Runes: []rune(" ")}, m.ToggleAll) {
+		t.Error("Expected space to toggle all")
+	}
+}
+
+func TestNew_Errors(t *testing.T) {
+	tests := []struct {
+		preset   string
+		bindings map[string][]string
+		expected string
+	}{
+		{"helix", nil, `unknown preset "helix"`},
+		{Default, map[string][]string{"launch": {"l"}}, `unknown action "launch"`},
+		{Default, map[string][]string{"exec": {"ctrl-x"}}, `exec: unknown key "ctrl-x"`},
+		{Default, map[string][]string{"exec": {"e"}}, "e is bound to both exec and edit"},
+		{Default, map[string][]string{"details": {"space"}}, "space is bound to both toggle and details"},
+		{Default, map[string][]string{"rerun": {"k"}}, "k is bound to both up and rerun in the command output"},
+		{Default, map[string][]string{"copy_across": {"r"}}, "r is bound to both copy_across and rename in the file browser"},
+		{Default, map[string][]string{"back": {"y"}}, "y is bound to both yank and back in the host list; y is bound to both back and yes in the confirmation"},
+	}
+	for _, test := range tests {
+		_, err := New(test.preset, test.bindings)
+		if err == nil || !strings.Contains(err.Error(), test.expected) {
+			t.Errorf("New(%q, %v): expected an error containing %q, got %v", test.preset, test.bindings, test.expected, err)
+		}
+	}
+}
+
+func TestNew_Views(t *testing.T) {
+	// Keys only clash with actions of the same view
+	m, err := New(Default, map[string][]string{"rerun": {"c"}, "yes": {"x"}})
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	if !key.Matches(press("c"), m.Rerun) || !key.Matches(press("c"), m.Cluster) || !key.Matches(press("c"), m.CopyAcross) {
+		t.Error("Expected c to re-run commands, cluster hosts and copy files across")
+	}
+	for _, preset := range Presets {
+		m, _ := New(preset, nil)
+		if !key.Matches(tea.KeyMsg{Type: tea.KeyTab}, m.SwitchPane) || !key.Matches(tea.KeyMsg{Type: tea.KeyEsc}, m.Back) {
+			t.Errorf("Preset %s: expected tab to switch panes and esc to go back", preset)
+		}
+	}
+}
+
+func TestParse(t *testing.T) {
+	valid := map[string]string{"space": " ", "x": "x", "ctrl+x": "ctrl+x", "alt+enter": "alt+enter", "pgdown": "pgdown", "é": "é"}
+	for k, expected := range valid {
+		if got, err := Parse(k); err != nil || got != expected {
+			t.Errorf("Parse(%q): expected %q, got %q (%v)", k, expected, got, err)
+		}
+	}
+	for _, k := range []string{"", "ctrl+", "pagedown", "xy"} {
+		if _, err := Parse(k); err == nil {
+			t.Errorf("Parse(%q): expected an error", k)
+		}
+	}
+}
@@ -0,0 +1,711 @@
+package menu
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/antonjah/ssm/internal/audit"
+	"github.com/antonjah/ssm/internal/keys"
+	"github.com/antonjah/ssm/internal/theme"
+	"github.com/antonjah/ssm/internal/transfer"
+
+	"github.com/charmbracelet/bubbles/key"
+	"github.com/charmbracelet/bubbles/progress"
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/pkg/sftp"
+)
+
+// fileSystem is one side of the file browser.
+type fileSystem interface {
+	ReadDir(dir string) ([]os.FileInfo, error)
+	Stat(name string) (os.FileInfo, error)
+	Open(name string) (io.ReadCloser, error)
+	Create(name string) (io.WriteCloser, error)
+	Mkdir(name string) error
+	RemoveAll(name string) error
+	Rename(oldName, newName string) error
+	Getwd() (string, error)
+}
+
+// localFS is the local file system.
+type localFS struct{}
+
+func (localFS) ReadDir(dir string) ([]os.FileInfo, error) {
+	entries, err := os.ReadDir(dir)
+	infos := make([]os.FileInfo, 0, len(entries))
+	for _, entry := range entries {
+		if info, err := entry.Info(); err == nil {
+			infos = append(infos, info)
+		}
+	}
+	return infos, err
+}
+
+func (localFS) Stat(name string) (os.FileInfo, error)      { return os.Stat(name) }
+func (localFS) Open(name string) (io.ReadCloser, error)    { return os.Open(name) }
+func (localFS) Create(name string) (io.WriteCloser, error) { return os.Create(name) }
+func (localFS) Mkdir(name string) error                    { return os.Mkdir(name, 0o755) }
+func (localFS) RemoveAll(name string) error                { return os.RemoveAll(name) }
+func (localFS) Rename(oldName, newName string) error       { return os.Rename(oldName, newName) }
+func (localFS) Getwd() (string, error)                     { return os.Getwd() }
+
+// remoteFS is a host's file system over SFTP.
+type remoteFS struct {
+	client *sftp.Client
+}
+
+func (r remoteFS) ReadDir(dir string) ([]os.FileInfo, error) { return r.client.ReadDir(dir) }
+func (r remoteFS) Stat(name string) (os.FileInfo, error)     { return r.client.Stat(name) }
+func (r remoteFS) Open(name string) (io.ReadCloser, error)   { return r.client.Open(name) }
+func (r remoteFS) Create(name string) (io.WriteCloser, error) {
+	return r.client.Create(name)
+}
+func (r remoteFS) Mkdir(name string) error              { return r.client.Mkdir(name) }
+func (r remoteFS) RemoveAll(name string) error          { return r.client.RemoveAll(name) }
+func (r remoteFS) Rename(oldName, newName string) error { return r.client.Rename(oldName, newName) }
+func (r remoteFS) Getwd() (string, error)               { return r.client.Getwd() }
+
+// sftpSession is an SFTP client running over "ssh alias -s sftp", so the
+// host's ssh_config settings apply.
+type sftpSession struct {
+	client *sftp.Client
+	cmd    *exec.Cmd
+}
+
+// Close ends the SFTP session and waits for ssh to exit.
+func (s *sftpSession) Close() error {
+	s.client.Close()
+	return s.cmd.Wait()
+}
+
+// dialSFTP starts an SFTP session with the host. BatchMode stops ssh from
+// prompting, since the menu owns the terminal.
+func dialSFTP(sshPath, alias string) (*sftpSession, error) {
+	if sshPath == "" {
+		sshPath = "ssh"
+	}
+	cmd := exec.Command(sshPath, "-o", "BatchMode=yes", alias, "-s", "sftp")
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	var stderr strings.Builder
+	cmd.Stderr = &stderr
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("failed to start ssh: %w", err)
+	}
+
+	client, err := sftp.NewClientPipe(stdout, stdin)
+	if err != nil {
+		stdin.Close()
+		cmd.Wait()
+		if message := strings.TrimSpace(stderr.String()); message != "" {
+			return nil, errors.New(message)
+		}
+		return nil, fmt.Errorf("failed to start SFTP session: %w", err)
+	}
+	return &sftpSession{client: client, cmd: cmd}, nil
+}
+
+// browserPane lists one directory of a file system.
+type browserPane struct {
+	title   string
+	fs      fileSystem
+	dir     string
+	entries []os.FileInfo
+	cursor  int
+}
+
+// load lists dir, keeping the cursor position when reloading the same
+// directory. Directories are listed first.
+func (p *browserPane) load(dir string) error {
+	entries, err := p.fs.ReadDir(dir)
+	if err != nil {
+		return err
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].IsDir() != entries[j].IsDir() {
+			return entries[i].IsDir()
+		}
+		return entries[i].Name() < entries[j].Name()
+	})
+	if dir != p.dir {
+		p.cursor = 0
+	}
+	p.dir = dir
+	p.entries = entries
+	p.cursor = max(0, min(p.cursor, len(entries)-1))
+	return nil
+}
+
+// selected returns the highlighted entry.
+func (p *browserPane) selected() (os.FileInfo, bool) {
+	if p.cursor >= len(p.entries) {
+		return nil, false
+	}
+	return p.entries[p.cursor], true
+}
+
+// browserMode is what the browser is waiting for.
+type browserMode int
+
+const (
+	browseFiles browserMode = iota
+	browseConfirmDelete
+	browseRename
+)
+
+// Messages sent to the file browser.
+type (
+	browserConnectedMsg struct {
+		// id is the browser the session was opened for.
+		id     int
+		fs     fileSystem
+		closer io.Closer
+		err    error
+	}
+	browserCopyMsg struct {
+		copied int64
+		total  int64
+		events chan tea.Msg
+	}
+	browserCopyDoneMsg struct{ err error }
+)
+
+// fileBrowser is a two-pane browser with the local file system on the left
+// and the host's, over SFTP, on the right.
+type fileBrowser struct {
+	// id tells the browser's session apart from those of browsers opened
+	// before it.
+	id      int
+	alias   string
+	sshPath string
+	panes   [2]*browserPane
+	active  int
+	mode    browserMode
+	input   textinput.Model
+	closer  io.Closer
+	copying bool
+	copied  int64
+	total   int64
+	cancel  context.CancelFunc
+	message string
+	failed  bool
+	bar     progress.Model
+	width   int
+	height  int
+	// audit logs copies, if set.
+	audit *audit.Logger
+	theme theme.Theme
+	keys  keys.Map
+}
+
+// browserKeyMap provides key bindings for the file browser.
+type browserKeyMap struct {
+	keys keys.Map
+}
+
+func (k browserKeyMap) ShortHelp() []key.Binding {
+	return []key.Binding{k.keys.SwitchPane, k.keys.Open, k.keys.Parent, k.keys.CopyAcross, k.keys.Rename, k.keys.Delete, k.keys.Back}
+}
+
+func (k browserKeyMap) FullHelp() [][]key.Binding {
+	return [][]key.Binding{k.ShortHelp()}
+}
+
+// newFileBrowser creates a browser for alias starting in the local working
+// directory. Call connect to open the remote side.
+func newFileBrowser(t theme.Theme, km keys.Map, alias, sshPath string, width, height int) *fileBrowser {
+	b := &fileBrowser{
+		alias:   alias,
+		sshPath: sshPath,
+		input:   textinput.New(),
+		bar:     progress.New(progress.WithSolidFill(theme.Hex(t.Accent))),
+		theme:   t,
+		keys:    km,
+	}
+	b.input.Prompt = "rename to: "
+	b.input.PromptStyle = b.input.PromptStyle.Foreground(b.theme.Accent)
+	b.setSize(width, height)
+
+	local := &browserPane{title: "local", fs: localFS{}}
+	if dir, err := local.fs.Getwd(); err == nil {
+		b.setError(local.load(dir))
+	}
+	b.panes[0] = local
+	b.message = "connecting to " + alias + " ..."
+	return b
+}
+
+// connect returns a command that opens the SFTP session.
+func (b *fileBrowser) connect() tea.Cmd {
+	id, sshPath, alias := b.id, b.sshPath, b.alias
+	return func() tea.Msg {
+		session, err := dialSFTP(sshPath, alias)
+		if err != nil {
+			return browserConnectedMsg{id: id, err: err}
+		}
+		return browserConnectedMsg{id: id, fs: remoteFS{client: session.client}, closer: session}
+	}
+}
+
+// close cancels any copy and ends the SFTP session.
+func (b *fileBrowser) close() {
+	if b.cancel != nil {
+		b.cancel()
+	}
+	if b.closer != nil {
+		b.closer.Close()
+		b.closer = nil
+	}
+}
+
+// setSize resizes the browser to the terminal size.
+func (b *fileBrowser) setSize(width, height int) {
+	b.width, b.height = width, height
+	b.bar.Width = max(width-20, 10)
+}
+
+// setError shows err as a failure if it isn't nil.
+func (b *fileBrowser) setError(err error) {
+	if err != nil {
+		b.message, b.failed = err.Error(), true
+	}
+}
+
+// setMessage shows a message that isn't a failure.
+func (b *fileBrowser) setMessage(message string) {
+	b.message, b.failed = message, false
+}
+
+// pane returns the active pane.
+func (b *fileBrowser) pane() *browserPane {
+	return b.panes[b.active]
+}
+
+// update handles input for the browser. It returns false when the user
+// leaves it.
+func (b *fileBrowser) update(msg tea.Msg) (bool, tea.Cmd) {
+	switch msg := msg.(type) {
+	case browserConnectedMsg:
+		if msg.err != nil {
+			b.setError(msg.err)
+			return true, nil
+		}
+		b.closer = msg.closer
+		remote := &browserPane{title: b.alias, fs: msg.fs}
+		dir, err := msg.fs.Getwd()
+		if err == nil {
+			err = remote.load(dir)
+		}
+		b.panes[1] = remote
+		b.active = 1
+		b.setMessage("")
+		b.setError(err)
+		return true, nil
+	case browserCopyMsg:
+		b.copied, b.total = msg.copied, msg.total
+		return true, waitForBrowserCopy(msg.events)
+	case browserCopyDoneMsg:
+		b.copying = false
+		if msg.err != nil {
+			b.setError(msg.err)
+		} else {
+			b.setMessage("copied")
+		}
+		b.reload()
+		return true, nil
+	case tea.KeyMsg:
+		return b.handleKey(msg)
+	}
+	return true, nil
+}
+
+// handleKey handles a key press in the current mode.
+func (b *fileBrowser) handleKey(msg tea.KeyMsg) (bool, tea.Cmd) {
+	km := b.keys
+	if b.copying {
+		if key.Matches(msg, km.Back) && b.cancel != nil {
+			b.cancel()
+		}
+		return true, nil
+	}
+
+	switch b.mode {
+	case browseConfirmDelete:
+		b.mode = browseFiles
+		if key.Matches(msg, km.Yes) {
+			b.delete()
+		} else {
+			b.setMessage("")
+		}
+		return true, nil
+	case browseRename:
+		if !typed(msg) {
+			switch {
+			case key.Matches(msg, km.Select):
+				b.mode = browseFiles
+				b.input.Blur()
+				b.rename(strings.TrimSpace(b.input.Value()))
+				return true, nil
+			case key.Matches(msg, km.Back):
+				b.mode = browseFiles
+				b.input.Blur()
+				return true, nil
+			}
+		}
+		var cmd tea.Cmd
+		b.input, cmd = b.input.Update(msg)
+		return true, cmd
+	}
+
+	if key.Matches(msg, km.Back, km.Quit) {
+		b.close()
+		return false, nil
+	}
+	pane := b.pane()
+	if pane == nil {
+		return true, nil
+	}
+
+	switch {
+	case key.Matches(msg, km.SwitchPane):
+		if b.panes[1-b.active] != nil {
+			b.active = 1 - b.active
+		}
+	case key.Matches(msg, km.Up):
+		pane.cursor = max(pane.cursor-1, 0)
+	case key.Matches(msg, km.Down):
+		pane.cursor = max(min(pane.cursor+1, len(pane.entries)-1), 0)
+	case key.Matches(msg, km.Home):
+		pane.cursor = 0
+	case key.Matches(msg, km.End):
+		pane.cursor = max(len(pane.entries)-1, 0)
+	case key.Matches(msg, km.Open):
+		if entry, ok := pane.selected(); ok && entry.IsDir() {
+			b.setError(pane.load(path.Join(pane.dir, entry.Name())))
+		}
+	case key.Matches(msg, km.Parent):
+		b.setError(pane.load(path.Dir(pane.dir)))
+	case key.Matches(msg, km.CopyAcross):
+		return true, b.startCopy()
+	case key.Matches(msg, km.Delete):
+		if entry, ok := pane.selected(); ok {
+			b.mode = browseConfirmDelete
+			b.setMessage(fmt.Sprintf("Delete %s from %s? (%s)", entry.Name(), pane.title, yesNo(km)))
+		}
+	case key.Matches(msg, km.Rename):
+		if entry, ok := pane.selected(); ok {
+			b.mode = browseRename
+			b.input.SetValue(entry.Name())
+			b.input.CursorEnd()
+			return true, b.input.Focus()
+		}
+	}
+	return true, nil
+}
+
+// reload lists both panes' directories again.
+func (b *fileBrowser) reload() {
+	for _, pane := range b.panes {
+		if pane != nil {
+			b.setError(pane.load(pane.dir))
+		}
+	}
+}
+
+// delete removes the highlighted entry, including a directory's contents.
+func (b *fileBrowser) delete() {
+	pane := b.pane()
+	entry, ok := pane.selected()
+	if !ok {
+		return
+	}
+	if err := pane.fs.RemoveAll(path.Join(pane.dir, entry.Name())); err != nil {
+		b.setError(err)
+		return
+	}
+	b.setMessage("deleted " + entry.Name())
+	b.setError(pane.load(pane.dir))
+}
+
+// rename renames the highlighted entry within its directory.
+func (b *fileBrowser) rename(name string) {
+	pane := b.pane()
+	entry, ok := pane.selected()
+	if !ok || name == "" || name == entry.Name() {
+		return
+	}
+	if strings.Contains(name, "/") {
+		b.setError(errors.New("the new name can't contain '/'"))
+		return
+	}
+	if err := pane.fs.Rename(path.Join(pane.dir, entry.Name()), path.Join(pane.dir, name)); err != nil {
+		b.setError(err)
+		return
+	}
+	b.setMessage(fmt.Sprintf("renamed %s to %s", entry.Name(), name))
+	b.setError(pane.load(pane.dir))
+}
+
+// startCopy copies the highlighted entry into the other pane's directory and
+// returns the command that delivers its progress.
+func (b *fileBrowser) startCopy() tea.Cmd {
+	src, dst := b.pane(), b.panes[1-b.active]
+	entry, ok := src.selected()
+	if !ok || dst == nil {
+		return nil
+	}
+	srcPath := path.Join(src.dir, entry.Name())
+	dstPath := path.Join(dst.dir, entry.Name())
+	if _, err := dst.fs.Stat(dstPath); err == nil {
+		b.setError(fmt.Errorf("%s already exists in %s", entry.Name(), dst.dir))
+		return nil
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	b.cancel = cancel
+	b.copying = true
+	b.copied, b.total = 0, 0
+	b.setMessage(fmt.Sprintf("copying %s to %s ...", entry.Name(), dst.title))
+
+	logEntry := audit.CopyEntry(transfer.Transfer{
+		Tool:   transfer.SFTP,
+		Source: b.endpoint(b.active, srcPath),
+		Dest:   b.endpoint(1-b.active, dstPath),
+	})
+	events := make(chan tea.Msg, 1)
+	go func() {
+		start := time.Now()
+		total := treeSize(src.fs, srcPath, entry)
+		var copied int64
+		var last time.Time
+		err := copyTree(ctx, src.fs, srcPath, entry, dst.fs, dstPath, func(n int64) {
+			copied += n
+			// Throttle updates so a fast copy doesn't flood the UI
+			if time.Since(last) > 100*time.Millisecond {
+				last = time.Now()
+				events <- browserCopyMsg{copied: copied, total: total, events: events}
+			}
+		})
+		logEntry.Finish(start, 0, err)
+		b.audit.Log(logEntry)
+		events <- browserCopyDoneMsg{err: err}
+		close(events)
+	}()
+	return waitForBrowserCopy(events)
+}
+
+// endpoint returns path in the given pane as a copy endpoint.
+func (b *fileBrowser) endpoint(pane int, p string) transfer.Endpoint {
+	if pane == 0 {
+		return transfer.Endpoint{Path: p}
+	}
+	return transfer.Endpoint{Host: b.alias, Path: p}
+}
+
+// waitForBrowserCopy returns a command that delivers the next copy event.
+func waitForBrowserCopy(events chan tea.Msg) tea.Cmd {
+	return func() tea.Msg {
+		msg, ok := <-events
+		if !ok {
+			return nil
+		}
+		return msg
+	}
+}
+
+// treeSize returns the total size of the files under name.
+func treeSize(fs fileSystem, name string, info os.FileInfo) int64 {
+	if !info.IsDir() {
+		return info.Size()
+	}
+	entries, err := fs.ReadDir(name)
+	if err != nil {
+		return 0
+	}
+	var total int64
+	for _, entry := range entries {
+		total += treeSize(fs, path.Join(name, entry.Name()), entry)
+	}
+	return total
+}
+
+// copyTree copies a file or directory between file systems, calling
+// onProgress with the number of bytes copied as it goes.
+func copyTree(ctx context.Context, srcFS fileSystem, src string, info os.FileInfo, dstFS fileSystem, dst string, onProgress func(int64)) error {
+	if !info.IsDir() {
+		return copyFile(ctx, srcFS, src, dstFS, dst, onProgress)
+	}
+	if err := dstFS.Mkdir(dst); err != nil {
+		return err
+	}
+	entries, err := srcFS.ReadDir(src)
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		if !entry.IsDir() && !entry.Mode().IsRegular() {
+			continue
+		}
+		if err := copyTree(ctx, srcFS, path.Join(src, entry.Name()), entry, dstFS, path.Join(dst, entry.Name()), onProgress); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// copyFile copies a single file between file systems.
+func copyFile(ctx context.Context, srcFS fileSystem, src string, dstFS fileSystem, dst string, onProgress func(int64)) error {
+	in, err := srcFS.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	out, err := dstFS.Create(dst)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(out, &progressReader{ctx: ctx, reader: in, onRead: onProgress})
+	if closeErr := out.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+// progressReader reports bytes read and stops once its context is done.
+type progressReader struct {
+	ctx    context.Context
+	reader io.Reader
+	onRead func(int64)
+}
+
+func (r *progressReader) Read(p []byte) (int, error) {
+	if err := r.ctx.Err(); err != nil {
+		return 0, err
+	}
+	n, err := r.reader.Read(p)
+	if n > 0 {
+		r.onRead(int64(n))
+	}
+	return n, err
+}
+
+// formatSize formats a byte count for display.
+func formatSize(size int64) string {
+	const unit = 1024
+	if size < unit {
+		return fmt.Sprintf("%d B", size)
+	}
+	value, exp := float64(size)/unit, 0
+	for value >= unit && exp < 4 {
+		value /= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", value, "KMGTP"[exp])
+}
+
+// view renders both panes, the highlighted entry's metadata and the status.
+func (b *fileBrowser) view() string {
+	paneWidth := max((b.width-8)/2, 20)
+	rows := max(b.height-12, 3)
+
+	panes := make([]string, 2)
+	for i, pane := range b.panes {
+		border := lipgloss.NewStyle().
+			Border(lipgloss.RoundedBorder()).
+			BorderForeground(b.theme.Dim).
+			Width(paneWidth).
+			Height(rows + 1)
+		if i == b.active {
+			border = border.Border(b.theme.FocusBorder()).BorderForeground(b.theme.Accent)
+		}
+		panes[i] = border.Render(b.paneView(pane, paneWidth, rows))
+	}
+
+	var status []string
+	if pane := b.pane(); pane != nil {
+		if entry, ok := pane.selected(); ok {
+			status = append(status, lipgloss.NewStyle().Foreground(b.theme.Muted).Render(
+				fmt.Sprintf("%s  %s  %s  %s", entry.Mode(), formatSize(entry.Size()), entry.ModTime().Format("2006-01-02 15:04"), entry.Name())))
+		}
+	}
+	if b.copying {
+		percent := 0.0
+		if b.total > 0 {
+			percent = float64(b.copied) / float64(b.total)
+		}
+		status = append(status, b.bar.ViewAs(percent)+fmt.Sprintf("  %s / %s", formatSize(b.copied), formatSize(b.total)))
+	}
+	if b.mode == browseRename {
+		status = append(status, b.input.View())
+	} else if b.message != "" {
+		style := lipgloss.NewStyle().Foreground(b.theme.Text)
+		if b.failed {
+			style = style.Foreground(b.theme.Error)
+		}
+		status = append(status, style.Render(b.message))
+	}
+
+	return lipgloss.JoinVertical(lipgloss.Left,
+		lipgloss.JoinHorizontal(lipgloss.Top, panes[0], " ", panes[1]),
+		strings.Join(status, "\n"))
+}
+
+// paneView renders a pane's directory listing, scrolled to keep the cursor
+// visible.
+func (b *fileBrowser) paneView(pane *browserPane, width, rows int) string {
+	if pane == nil {
+		return lipgloss.NewStyle().Foreground(b.theme.Dim).Render(b.alias + "\n\nconnecting...")
+	}
+
+	title := lipgloss.NewStyle().Bold(true).Foreground(b.theme.Accent).
+		Render(truncate(pane.title+":"+pane.dir, width))
+	lines := []string{title}
+
+	start := max(0, pane.cursor-rows+1)
+	dirStyle := lipgloss.NewStyle().Foreground(b.theme.Info)
+	selectedStyle := b.theme.Selection()
+	for i := start; i < len(pane.entries) && i < start+rows; i++ {
+		entry := pane.entries[i]
+		name, size := entry.Name(), formatSize(entry.Size())
+		if entry.IsDir() {
+			name, size = name+"/", ""
+		}
+		line := fmt.Sprintf("%-*s %9s", width-10, truncate(name, width-10), size)
+		switch {
+		case i == pane.cursor:
+			line = selectedStyle.Render(line)
+		case entry.IsDir():
+			line = dirStyle.Render(line)
+		}
+		lines = append(lines, line)
+	}
+	if len(pane.entries) == 0 {
+		lines = append(lines, lipgloss.NewStyle().Foreground(b.theme.Dim).Render("(empty)"))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// truncate shortens s to width runes, marking the cut with an ellipsis.
+func truncate(s string, width int) string {
+	runes := []rune(s)
+	if width <= 0 || len(runes) <= width {
+		return s
+	}
+	return string(runes[:width-1]) + "…"
+}
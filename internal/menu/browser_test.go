@@ -0,0 +1,188 @@
+package menu
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/antonjah/ssm/internal/config"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/pkg/sftp"
+)
+
+// newTestSFTP serves dir over an in-process SFTP server and returns a
+// client connected to it.
+func newTestSFTP(t *testing.T, dir string) *sftp.Client {
+	t.Helper()
+	clientReader, serverWriter := io.Pipe()
+	serverReader, clientWriter := io.Pipe()
+
+	server, err := sftp.NewServer(struct {
+		io.Reader
+		io.WriteCloser
+	}{serverReader, serverWriter}, sftp.WithServerWorkingDirectory(dir))
+	if err != nil {
+		t.Fatalf("Failed to create SFTP server: %v", err)
+	}
+	go server.Serve()
+
+	client, err := sftp.NewClientPipe(clientReader, clientWriter)
+	if err != nil {
+		t.Fatalf("Failed to connect SFTP client: %v", err)
+	}
+	t.Cleanup(func() {
+		// Closing the server ends the client's reads so it can close too
+		server.Close()
+		client.Close()
+	})
+	return client
+}
+
+// finishCopy runs cmd, following a copy the browser started until it is
+// done.
+func finishCopy(model tea.Model, cmd tea.Cmd) tea.Model {
+	for cmd != nil {
+		msg := cmd()
+		switch msg.(type) {
+		case browserCopyMsg, browserCopyDoneMsg:
+			model, cmd = model.Update(msg)
+		default:
+			return model
+		}
+	}
+	return model
+}
+
+// names returns the entry names of a pane.
+func names(p *browserPane) string {
+	var names []string
+	for _, entry := range p.entries {
+		names = append(names, entry.Name())
+	}
+	return strings.Join(names, ",")
+}
+
+func TestFileBrowser(t *testing.T) {
+	localDir, remoteDir := t.TempDir(), t.TempDir()
+	os.WriteFile(filepath.Join(localDir, "upload.txt"), []byte("from local"), 0644)
+	os.Mkdir(filepath.Join(remoteDir, "logs"), 0755)
+	os.WriteFile(filepath.Join(remoteDir, "logs", "app.log"), []byte("remote log"), 0644)
+	os.WriteFile(filepath.Join(remoteDir, "zzz.txt"), []byte("x"), 0644)
+
+	b := newFileBrowser(defaultTheme(), defaultKeys(), "web", "", 120, 40)
+	if err := b.panes[0].load(localDir); err != nil {
+		t.Fatalf("Failed to list local directory: %v", err)
+	}
+	b.update(browserConnectedMsg{fs: remoteFS{client: newTestSFTP(t, remoteDir)}})
+	m := newTestModel([]config.Host{{Alias: "web"}}, Options{}, 0).(Model)
+	m.browser = b
+	model := tea.Model(m)
+
+	remote := b.panes[1]
+	if b.active != 1 || remote.dir != remoteDir || names(remote) != "logs,zzz.txt" {
+		t.Fatalf("Expected remote pane on %s listing logs,zzz.txt, got %s listing %s", remoteDir, remote.dir, names(remote))
+	}
+
+	// Download a directory
+	model = finishCopy(sendKeys(model, "c"))
+	data, err := os.ReadFile(filepath.Join(localDir, "logs", "app.log"))
+	if err != nil || string(data) != "remote log" {
+		t.Errorf("Expected logs/app.log to be downloaded, got %q (%v)", data, err)
+	}
+	if names(b.panes[0]) != "logs,upload.txt" {
+		t.Errorf("Expected local pane to be refreshed, got %s", names(b.panes[0]))
+	}
+
+	// Copying again would overwrite
+	model = finishCopy(sendKeys(model, "c"))
+	if !b.failed || !strings.Contains(b.message, "already exists") {
+		t.Errorf("Expected an 'already exists' error, got %q", b.message)
+	}
+
+	// Upload a file into the remote logs directory
+	model = finishCopy(sendKeys(model, "enter", "tab", "down", "c"))
+	data, err = os.ReadFile(filepath.Join(remoteDir, "logs", "upload.txt"))
+	if err != nil || string(data) != "from local" {
+		t.Errorf("Expected upload.txt to be uploaded, got %q (%v)", data, err)
+	}
+
+	// Rename it remotely
+	model, _ = sendKeys(model, "tab", "down", "r")
+	b.input.SetValue("renamed.txt")
+	model, _ = sendKeys(model, "enter")
+	if _, err := os.Stat(filepath.Join(remoteDir, "logs", "renamed.txt")); err != nil {
+		t.Errorf("Expected upload.txt to be renamed: %v (%s)", err, b.message)
+	}
+
+	// Deleting needs confirmation
+	model, _ = sendKeys(model, "left", "d", "n")
+	if _, err := os.Stat(filepath.Join(remoteDir, "logs")); err != nil {
+		t.Error("Expected delete to be cancelled")
+	}
+	model, _ = sendKeys(model, "d", "y")
+	if _, err := os.Stat(filepath.Join(remoteDir, "logs")); !os.IsNotExist(err) {
+		t.Errorf("Expected logs to be deleted, got %v", err)
+	}
+	if names(remote) != "zzz.txt" {
+		t.Errorf("Expected remote pane to list zzz.txt, got %s", names(remote))
+	}
+
+	if model, _ = sendKeys(model, "esc"); model.(Model).browser != nil {
+		t.Error("Expected esc to leave the browser")
+	}
+}
+
+func TestFormatSize(t *testing.T) {
+	tests := map[int64]string{
+		512:             "512 B",
+		2048:            "2.0 KiB",
+		5 * 1024 * 1024: "5.0 MiB",
+	}
+	for size, expected := range tests {
+		if got := formatSize(size); got != expected {
+			t.Errorf("formatSize(%d): expected %q, got %q", size, expected, got)
+		}
+	}
+}
+
+// closeRecorder records whether it was closed.
+type closeRecorder struct{ closed bool }
+
+func (c *closeRecorder) Close() error {
+	c.closed = true
+	return nil
+}
+
+func TestModel_BrowserClosedBeforeConnect(t *testing.T) {
+	model, _ := sendKeys(newTestModel([]config.Host{{Alias: "web"}}, Options{}, 0), "b")
+	first := model.(Model).browser
+	if first == nil {
+		t.Fatal("Expected b to open the file browser")
+	}
+
+	// The browser is left before its session comes up
+	model, _ = sendKeys(model, "esc")
+	late := &closeRecorder{}
+	model, _ = model.Update(browserConnectedMsg{id: first.id, closer: late})
+	if !late.closed || model.(Model).browser != nil {
+		t.Errorf("Expected the late session to be closed, got closed %v and browser %v", late.closed, model.(Model).browser)
+	}
+
+	// A new browser doesn't take the session opened for the old one
+	model, _ = sendKeys(model, "b")
+	stale := &closeRecorder{}
+	model, _ = model.Update(browserConnectedMsg{id: first.id, closer: stale})
+	second := model.(Model).browser
+	if !stale.closed || second == nil || second.panes[1] != nil {
+		t.Errorf("Expected the stale session to be closed and the new browser to keep waiting")
+	}
+	current := &closeRecorder{}
+	model, _ = model.Update(browserConnectedMsg{id: second.id, fs: remoteFS{client: newTestSFTP(t, t.TempDir())}, closer: current})
+	if current.closed || model.(Model).browser.panes[1] == nil {
+		t.Error("Expected the new browser to use its own session")
+	}
+	model.(Model).browser.close()
+}
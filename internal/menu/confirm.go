@@ -0,0 +1,161 @@
+package menu
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/antonjah/ssm/internal/keys"
+	"github.com/antonjah/ssm/internal/settings"
+	"github.com/antonjah/ssm/internal/theme"
+
+	"github.com/charmbracelet/bubbles/key"
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// protectedBadge marks protected hosts in the list.
+const protectedBadge = "⚠ protected"
+
+// confirmView asks before connecting to or running a command on protected
+// hosts, either with a y/N question or by having the alias typed out.
+type confirmView struct {
+	// action describes what is about to happen, e.g. "Connect to".
+	action    string
+	protected []string
+	// expected is the text to type, or "" for a y/N question.
+	expected  string
+	input     textinput.Model
+	mismatch  bool
+	confirmed bool
+	// proceed carries out the action once it is confirmed.
+	proceed func(Model) (Model, tea.Cmd)
+	theme   theme.Theme
+	keys    keys.Map
+}
+
+// confirmKeyMap provides key bindings for the confirmation prompt.
+type confirmKeyMap struct {
+	keys keys.Map
+	// question is set for a y/N question rather than typed text.
+	question bool
+}
+
+func (k confirmKeyMap) ShortHelp() []key.Binding {
+	if k.question {
+		return []key.Binding{k.keys.Yes, k.keys.No, withHelp(k.keys.Back, "cancel")}
+	}
+	return []key.Binding{withHelp(k.keys.Select, "confirm"), withHelp(k.keys.Back, "cancel")}
+}
+
+func (k confirmKeyMap) FullHelp() [][]key.Binding {
+	return [][]key.Binding{k.ShortHelp()}
+}
+
+// newConfirmView asks to confirm action on the protected hosts. With
+// settings.ConfirmAlias a single host's alias has to be typed, and for
+// several hosts their number.
+func newConfirmView(t theme.Theme, km keys.Map, action string, protected []string, mode string, proceed func(Model) (Model, tea.Cmd)) *confirmView {
+	c := &confirmView{action: action, protected: protected, proceed: proceed, theme: t, keys: km}
+	if mode != settings.ConfirmYes {
+		c.expected = protected[0]
+		if len(protected) > 1 {
+			c.expected = strconv.Itoa(len(protected))
+		}
+		c.input = textinput.New()
+		c.input.Prompt = "> "
+		c.input.PromptStyle = c.input.PromptStyle.Foreground(c.theme.Error)
+	}
+	return c
+}
+
+// focus focuses the text input, if there is one.
+func (c *confirmView) focus() tea.Cmd {
+	if c.expected == "" {
+		return nil
+	}
+	return c.input.Focus()
+}
+
+// update handles a message and reports whether the prompt stays open.
+// confirmed is set when it closes because the action was confirmed.
+func (c *confirmView) update(msg tea.Msg) (bool, tea.Cmd) {
+	keyMsg, ok := msg.(tea.KeyMsg)
+	if c.expected == "" {
+		if !ok {
+			return true, nil
+		}
+		switch {
+		case key.Matches(keyMsg, c.keys.Yes):
+			c.confirmed = true
+			return false, nil
+		case key.Matches(keyMsg, c.keys.No, c.keys.Back):
+			return false, nil
+		}
+		return true, nil
+	}
+
+	if ok && !typed(keyMsg) {
+		switch {
+		case key.Matches(keyMsg, c.keys.Select):
+			if strings.TrimSpace(c.input.Value()) == c.expected {
+				c.confirmed = true
+				return false, nil
+			}
+			c.mismatch = true
+			c.input.SetValue("")
+			return true, nil
+		case key.Matches(keyMsg, c.keys.Back):
+			return false, nil
+		}
+	}
+	var cmd tea.Cmd
+	c.input, cmd = c.input.Update(msg)
+	return true, cmd
+}
+
+func (c *confirmView) view() string {
+	warning := lipgloss.NewStyle().Foreground(c.theme.Error).Bold(true)
+	dim := lipgloss.NewStyle().Foreground(c.theme.Dim)
+
+	noun := "host"
+	if len(c.protected) > 1 {
+		noun = "hosts"
+	}
+	lines := []string{
+		warning.Render(fmt.Sprintf("%s %s protected %s: %s", protectedBadge, c.action, noun, strings.Join(c.protected, ", "))),
+		"",
+	}
+	switch {
+	case c.expected == "":
+		lines = append(lines, fmt.Sprintf("Continue? [%s]", yesNo(c.keys)))
+	case len(c.protected) == 1:
+		lines = append(lines, fmt.Sprintf("Type %s to continue:", warning.Render(c.expected)), c.input.View())
+	default:
+		lines = append(lines, fmt.Sprintf("Type the number of protected hosts (%s) to continue:", warning.Render(c.expected)), c.input.View())
+	}
+	if c.mismatch {
+		lines = append(lines, "", dim.Render(fmt.Sprintf("That doesn't match, try again or press %s to cancel.", c.keys.Back.Help().Key)))
+	}
+	return lipgloss.JoinVertical(lipgloss.Left, lines...)
+}
+
+// yesNo shows the keys that answer a yes or no question whose default is
+// no, e.g. "y/N".
+func yesNo(km keys.Map) string {
+	yes, no := firstKey(km.Yes), firstKey(km.No)
+	if len([]rune(no)) == 1 {
+		no = strings.ToUpper(no)
+	}
+	return yes + "/" + no
+}
+
+// firstKey returns the first key bound to b as the help shows it, or "" if
+// it is unbound.
+func firstKey(b key.Binding) string {
+	if len(b.Keys()) == 0 {
+		return ""
+	}
+	return keys.Display(b.Keys()[0])
+}
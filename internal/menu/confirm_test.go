@@ -0,0 +1,93 @@
+package menu
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/antonjah/ssm/internal/config"
+	"github.com/antonjah/ssm/internal/settings"
+)
+
+// protectedHosts are a protected host, db-prod, and an unprotected one.
+var protectedHosts = []config.Host{
+	{Alias: "db-prod", HostName: "10.0.0.5", Tags: []string{"prod"}},
+	{Alias: "db-staging", HostName: "10.1.0.5"},
+}
+
+func TestModel_ConfirmProtected(t *testing.T) {
+	model := newTestModel(protectedHosts, Options{Protect: settings.Protect{Tags: []string{"prod"}}}, 0)
+	if !model.(Model).protected["db-prod"] || model.(Model).protected["db-staging"] {
+		t.Fatalf("Expected only db-prod to be protected, got %v", model.(Model).protected)
+	}
+
+	model, _ = sendKeys(model, "enter")
+	if model.(Model).confirm == nil || model.(Model).done {
+		t.Fatal("Expected a confirmation prompt before connecting to db-prod")
+	}
+	if view := model.View(); !strings.Contains(view, "Type db-prod to continue") {
+		t.Errorf("Expected the prompt to ask for the alias, got %q", view)
+	}
+
+	// A wrong alias keeps the prompt open
+	model, _ = sendKeys(model, "db-staging", "enter")
+	if model.(Model).confirm == nil || !model.(Model).confirm.mismatch {
+		t.Fatal("Expected the prompt to stay open after a mismatch")
+	}
+
+	model, _ = sendKeys(model, "db-prod", "enter")
+	if choice := model.(Model).choice; !model.(Model).done || len(choice.Hosts) != 1 || choice.Hosts[0] != "db-prod" {
+		t.Errorf("Expected db-prod to be chosen, got %+v", choice)
+	}
+}
+
+func TestModel_ConfirmProtected_Cancel(t *testing.T) {
+	model := newTestModel(protectedHosts, Options{Protect: settings.Protect{Tags: []string{"prod"}, Confirm: settings.ConfirmYes}}, 0)
+	model, _ = sendKeys(model, "enter")
+	if view := model.View(); !strings.Contains(view, "[y/N]") {
+		t.Errorf("Expected a y/N question, got %q", view)
+	}
+	model, _ = sendKeys(model, "enter")
+	if model.(Model).confirm != nil || model.(Model).done {
+		t.Error("Expected enter to cancel the connection")
+	}
+
+	model, _ = sendKeys(model, "enter", "y")
+	if !model.(Model).done {
+		t.Error("Expected y to confirm the connection")
+	}
+}
+
+func TestModel_ConfirmProtected_Unprotected(t *testing.T) {
+	model := newTestModel(protectedHosts, Options{Protect: settings.Protect{Tags: []string{"prod"}}}, 0)
+	model, _ = sendKeys(model, "down", "enter")
+	if model.(Model).confirm != nil || !model.(Model).done {
+		t.Error("Expected db-staging to connect without confirmation")
+	}
+}
+
+func TestModel_ConfirmProtected_Command(t *testing.T) {
+	model := newTestModel(protectedHosts, Options{Protect: settings.Protect{Tags: []string{"prod"}}}, 0)
+	model, _ = sendKeys(model, "*", "x", "uptime", "enter")
+
+	confirm := model.(Model).confirm
+	if confirm == nil || model.(Model).exec != nil {
+		t.Fatal("Expected the command to need confirming")
+	}
+	if confirm.expected != "db-prod" {
+		t.Errorf("Expected only db-prod to need confirming, got %q", confirm.expected)
+	}
+	if view := model.View(); !strings.Contains(view, `Run "uptime" on protected host: db-prod`) {
+		t.Errorf("Expected the prompt to name the command, got %q", view)
+	}
+	model, _ = sendKeys(model, "esc")
+	if model.(Model).confirm != nil || model.(Model).exec != nil {
+		t.Error("Expected esc to cancel the command")
+	}
+}
+
+func TestNewConfirmView_Several(t *testing.T) {
+	confirm := newConfirmView(defaultTheme(), defaultKeys(), "Connect to", []string{"db1", "db2", "db3"}, settings.ConfirmAlias, nil)
+	if confirm.expected != "3" {
+		t.Errorf("Expected the number of hosts to be typed, got %q", confirm.expected)
+	}
+}
@@ -0,0 +1,383 @@
+package menu
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/antonjah/ssm/internal/audit"
+	"github.com/antonjah/ssm/internal/keys"
+	"github.com/antonjah/ssm/internal/remote"
+	"github.com/antonjah/ssm/internal/theme"
+
+	"github.com/charmbracelet/bubbles/key"
+	"github.com/charmbracelet/bubbles/viewport"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// runState is the progress of a command on a single host.
+type runState int
+
+const (
+	runPending runState = iota
+	runRunning
+	runOK
+	runFailed
+)
+
+// hostRun tracks the command output and status of a single host.
+type hostRun struct {
+	host    string
+	state   runState
+	started time.Time
+	result  remote.Result
+	lines   []string
+}
+
+// Messages sent from a running command to the exec view. Each carries the
+// ID of the run it belongs to so stale messages from a cancelled run are
+// ignored.
+type (
+	execStartMsg struct {
+		run  int
+		host string
+	}
+	execLineMsg struct {
+		run    int
+		host   string
+		stream remote.Stream
+		line   string
+	}
+	execResultMsg struct {
+		run    int
+		result remote.Result
+	}
+	execDoneMsg  struct{ run int }
+	execTickMsg  struct{}
+	execEventMsg struct {
+		msg    tea.Msg
+		events chan tea.Msg
+	}
+)
+
+// execView runs a command on several hosts and shows their live status on
+// the left and the highlighted host's output on the right.
+type execView struct {
+	command  string
+	runs     []*hostRun
+	cursor   int
+	runID    int
+	running  bool
+	cancel   context.CancelFunc
+	events   chan tea.Msg
+	sshPath  string
+	viewport viewport.Model
+	width    int
+	height   int
+	// audit logs each host's result, if set.
+	audit *audit.Logger
+	theme theme.Theme
+	keys  keys.Map
+}
+
+// execKeyMap provides key bindings for the exec view.
+type execKeyMap struct {
+	keys keys.Map
+}
+
+func (k execKeyMap) ShortHelp() []key.Binding {
+	return []key.Binding{k.keys.Up, k.keys.Down, k.keys.Rerun, k.keys.Back}
+}
+
+func (k execKeyMap) FullHelp() [][]key.Binding {
+	return [][]key.Binding{k.ShortHelp()}
+}
+
+// newExecView creates an exec view for the given hosts. Call start to run it.
+func newExecView(t theme.Theme, km keys.Map, command string, hosts []string, sshPath string, width, height int) *execView {
+	v := &execView{
+		command:  command,
+		sshPath:  sshPath,
+		viewport: viewport.New(0, 0),
+		theme:    t,
+		keys:     km,
+	}
+	for _, host := range hosts {
+		v.runs = append(v.runs, &hostRun{host: host})
+	}
+	v.setSize(width, height)
+	return v
+}
+
+// start runs the command on the given hosts and returns the command that
+// delivers their progress.
+func (v *execView) start(hosts []string) tea.Cmd {
+	v.runID++
+	v.running = true
+	for _, run := range v.runs {
+		for _, host := range hosts {
+			if run.host == host {
+				*run = hostRun{host: host}
+			}
+		}
+	}
+	v.refresh()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	v.cancel = cancel
+
+	id := v.runID
+	events := make(chan tea.Msg, 64)
+	v.events = events
+	opts := remote.Options{
+		SSHPath: v.sshPath,
+		OnStart: func(host string) {
+			events <- execStartMsg{run: id, host: host}
+		},
+		OnLine: func(host string, stream remote.Stream, line string) {
+			events <- execLineMsg{run: id, host: host, stream: stream, line: line}
+		},
+		OnResult: func(result remote.Result) {
+			v.audit.Log(audit.ExecEntry(v.command, result))
+			events <- execResultMsg{run: id, result: result}
+		},
+	}
+
+	go func() {
+		remote.Run(ctx, hosts, []string{v.command}, opts)
+		events <- execDoneMsg{run: id}
+		close(events)
+	}()
+
+	return tea.Batch(waitForExecEvent(events), execTick())
+}
+
+// stop cancels any hosts that are still running and discards their
+// remaining events so the runner can finish.
+func (v *execView) stop() {
+	if v.cancel != nil {
+		v.cancel()
+	}
+	if v.events != nil {
+		go func(events chan tea.Msg) {
+			for range events {
+			}
+		}(v.events)
+	}
+}
+
+// waitForExecEvent returns a command that delivers the next progress event.
+func waitForExecEvent(events chan tea.Msg) tea.Cmd {
+	return func() tea.Msg {
+		msg, ok := <-events
+		if !ok {
+			return nil
+		}
+		return execEventMsg{msg: msg, events: events}
+	}
+}
+
+// execTick refreshes running durations once a second.
+func execTick() tea.Cmd {
+	return tea.Tick(time.Second, func(time.Time) tea.Msg { return execTickMsg{} })
+}
+
+// failedHosts returns the hosts whose command failed.
+func (v *execView) failedHosts() []string {
+	var hosts []string
+	for _, run := range v.runs {
+		if run.state == runFailed {
+			hosts = append(hosts, run.host)
+		}
+	}
+	return hosts
+}
+
+// find returns the run for a host.
+func (v *execView) find(host string) *hostRun {
+	for _, run := range v.runs {
+		if run.host == host {
+			return run
+		}
+	}
+	return nil
+}
+
+// handleEvent applies a progress event from the current run.
+func (v *execView) handleEvent(msg tea.Msg) {
+	switch msg := msg.(type) {
+	case execStartMsg:
+		if msg.run != v.runID {
+			return
+		}
+		if run := v.find(msg.host); run != nil {
+			run.state = runRunning
+			run.started = time.Now()
+		}
+	case execLineMsg:
+		if msg.run != v.runID {
+			return
+		}
+		if run := v.find(msg.host); run != nil {
+			line := msg.line
+			if msg.stream == remote.Stderr {
+				line = lipgloss.NewStyle().Foreground(v.theme.Error).Render(line)
+			}
+			run.lines = append(run.lines, line)
+		}
+	case execResultMsg:
+		if msg.run != v.runID {
+			return
+		}
+		if run := v.find(msg.result.Host); run != nil {
+			run.result = msg.result
+			run.state = runFailed
+			if msg.result.OK() {
+				run.state = runOK
+			}
+			if msg.result.Err != nil {
+				run.lines = append(run.lines, lipgloss.NewStyle().
+					Foreground(v.theme.Error).
+					Render("ssm: "+msg.result.Err.Error()))
+			}
+		}
+	case execDoneMsg:
+		if msg.run == v.runID {
+			v.running = false
+		}
+	}
+	v.refresh()
+}
+
+// update handles input for the exec view. It returns false when the user
+// leaves the view.
+func (v *execView) update(msg tea.Msg) (bool, tea.Cmd) {
+	switch msg := msg.(type) {
+	case execEventMsg:
+		v.handleEvent(msg.msg)
+		return true, waitForExecEvent(msg.events)
+	case execTickMsg:
+		if v.running {
+			return true, execTick()
+		}
+		return true, nil
+	case tea.KeyMsg:
+		switch {
+		case key.Matches(msg, v.keys.Up):
+			if v.cursor > 0 {
+				v.cursor--
+				v.refresh()
+			}
+			return true, nil
+		case key.Matches(msg, v.keys.Down):
+			if v.cursor < len(v.runs)-1 {
+				v.cursor++
+				v.refresh()
+			}
+			return true, nil
+		case key.Matches(msg, v.keys.Rerun):
+			if failed := v.failedHosts(); len(failed) > 0 && !v.running {
+				return true, v.start(failed)
+			}
+			return true, nil
+		case key.Matches(msg, v.keys.Back):
+			v.stop()
+			return false, nil
+		}
+	}
+
+	var cmd tea.Cmd
+	v.viewport, cmd = v.viewport.Update(msg)
+	return true, cmd
+}
+
+// setSize resizes the view to the terminal size.
+func (v *execView) setSize(width, height int) {
+	v.width = width
+	v.height = height
+	v.viewport.Width = max(width-v.sidebarWidth()-6, 10)
+	v.viewport.Height = max(height-6, 3)
+	v.refresh()
+}
+
+// sidebarWidth returns the width of the host status column.
+func (v *execView) sidebarWidth() int {
+	width := 0
+	for _, run := range v.runs {
+		width = max(width, len(run.host))
+	}
+	return width + 22
+}
+
+// refresh shows the highlighted host's output in the viewport.
+func (v *execView) refresh() {
+	if len(v.runs) == 0 {
+		return
+	}
+	atBottom := v.viewport.AtBottom()
+	v.viewport.SetContent(strings.Join(v.runs[v.cursor].lines, "\n"))
+	if atBottom {
+		v.viewport.GotoBottom()
+	}
+}
+
+// statusText returns the status column for a host.
+func (r *hostRun) statusText() string {
+	switch r.state {
+	case runRunning:
+		return fmt.Sprintf("running %s", time.Since(r.started).Round(time.Second))
+	case runOK:
+		return fmt.Sprintf("ok %s", r.result.Duration.Round(10*time.Millisecond))
+	case runFailed:
+		if r.result.TimedOut {
+			return "timed out"
+		}
+		return fmt.Sprintf("exit %d %s", r.result.ExitCode, r.result.Duration.Round(10*time.Millisecond))
+	}
+	return "pending"
+}
+
+// statusColor returns the colour used for a host's status.
+func (r *hostRun) statusColor(t theme.Theme) lipgloss.TerminalColor {
+	switch r.state {
+	case runRunning:
+		return t.Warning
+	case runOK:
+		return t.Success
+	case runFailed:
+		return t.Error
+	}
+	return t.Dim
+}
+
+func (v *execView) view() string {
+	var sidebar strings.Builder
+	for i, run := range v.runs {
+		name := lipgloss.NewStyle().Foreground(v.theme.Text)
+		if i == v.cursor {
+			name = name.Foreground(v.theme.Accent).Bold(true)
+		}
+		status := lipgloss.NewStyle().Foreground(run.statusColor(v.theme)).Render("● " + run.statusText())
+		fmt.Fprintf(&sidebar, "%s\n%s\n", name.Render(run.host), status)
+	}
+
+	paneStyle := lipgloss.NewStyle().
+		Border(lipgloss.RoundedBorder()).
+		BorderForeground(v.theme.Border).
+		Height(v.viewport.Height)
+
+	left := paneStyle.Width(v.sidebarWidth()).Render(strings.TrimRight(sidebar.String(), "\n"))
+	right := paneStyle.
+		BorderForeground(v.theme.Accent).
+		Width(v.viewport.Width).
+		Render(v.viewport.View())
+
+	header := lipgloss.NewStyle().
+		Foreground(v.theme.Accent).
+		Bold(true).
+		Render("$ " + v.command)
+
+	return lipgloss.JoinVertical(lipgloss.Left, header, lipgloss.JoinHorizontal(lipgloss.Top, left, right))
+}
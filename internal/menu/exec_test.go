@@ -0,0 +1,93 @@
+package menu
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/antonjah/ssm/internal/config"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// fakeSSH succeeds for hosts starting with "ok" and fails for the rest.
+const fakeSSH = `#!/bin/sh
+while [ "$1" = "-o" ]; do shift 2; done
+case $1 in
+	ok*) echo "hello from $1" ;;
+	*) echo "no route to $1" >&2; exit 2 ;;
+esac
+`
+
+func writeFakeSSH(t *testing.T) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "ssh")
+	if err := os.WriteFile(path, []byte(fakeSSH), 0755); err != nil {
+		t.Fatalf("Failed to write fake ssh: %v", err)
+	}
+	return path
+}
+
+// drainExec applies events to the view until the current run is done.
+func drainExec(t *testing.T, v *execView) {
+	t.Helper()
+	for msg := range v.events {
+		v.handleEvent(msg)
+		if done, ok := msg.(execDoneMsg); ok && done.run == v.runID {
+			return
+		}
+	}
+}
+
+func TestExecView(t *testing.T) {
+	hosts := []string{"ok1", "bad1", "ok2"}
+	v := newExecView(defaultTheme(), defaultKeys(), "uptime", hosts, writeFakeSSH(t), 120, 40)
+	v.start(hosts)
+	drainExec(t, v)
+
+	expected := []runState{runOK, runFailed, runOK}
+	for i, run := range v.runs {
+		if run.state != expected[i] {
+			t.Errorf("Expected %s to have state %d, got %d", run.host, expected[i], run.state)
+		}
+	}
+	if v.running {
+		t.Error("Expected run to be finished")
+	}
+	if len(v.runs[0].lines) != 1 || v.runs[0].lines[0] != "hello from ok1" {
+		t.Errorf("Expected output 'hello from ok1', got %v", v.runs[0].lines)
+	}
+	if v.runs[1].result.ExitCode != 2 {
+		t.Errorf("Expected bad1 to exit 2, got %d", v.runs[1].result.ExitCode)
+	}
+
+	// Re-running only touches the failed host
+	if stay, cmd := v.update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("r")}); !stay || cmd == nil {
+		t.Fatal("Expected re-run to start")
+	}
+	if v.runs[1].state != runPending || len(v.runs[1].lines) != 0 {
+		t.Errorf("Expected failed host to be reset, got %+v", v.runs[1])
+	}
+	if v.runs[0].state != runOK || len(v.runs[0].lines) != 1 {
+		t.Errorf("Expected successful host to be untouched, got %+v", v.runs[0])
+	}
+	drainExec(t, v)
+	if v.runs[1].state != runFailed {
+		t.Errorf("Expected bad1 to fail again, got state %d", v.runs[1].state)
+	}
+}
+
+func TestModel_ExecPrompt(t *testing.T) {
+	model, _ := sendKeys(newTestModel(nil, Options{}, 0), "x")
+	if model.(Model).prompting {
+		t.Fatal("Expected no prompt without hosts")
+	}
+
+	model, _ = sendKeys(newTestModel([]config.Host{{Alias: "host1"}}, Options{}, 0), "x")
+	if !model.(Model).prompting {
+		t.Fatal("Expected x to open the command prompt")
+	}
+	model, _ = sendKeys(model, "esc")
+	if model.(Model).prompting || model.(Model).done {
+		t.Error("Expected esc to close the prompt without quitting")
+	}
+}
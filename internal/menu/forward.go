@@ -0,0 +1,256 @@
+package menu
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/antonjah/ssm/internal/config"
+	"github.com/antonjah/ssm/internal/forward"
+	"github.com/antonjah/ssm/internal/keys"
+	"github.com/antonjah/ssm/internal/theme"
+
+	"github.com/charmbracelet/bubbles/key"
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// Messages sent when a forward operation finishes.
+type (
+	forwardListMsg struct {
+		running []forward.Forward
+		err     error
+	}
+	forwardDoneMsg struct {
+		message string
+		err     error
+	}
+)
+
+// forwardView starts port forwards through a host, either from its
+// configured forwards or from a spec typed into the form, and stops the
+// forwards already running through it.
+type forwardView struct {
+	host    config.Host
+	manager *forward.Manager
+	running []forward.Forward
+	cursor  int
+	input   textinput.Model
+	busy    bool
+	message string
+	failed  bool
+	theme   theme.Theme
+	keys    keys.Map
+}
+
+// forwardKeyMap provides key bindings for the forward view.
+type forwardKeyMap struct {
+	keys keys.Map
+}
+
+func (k forwardKeyMap) ShortHelp() []key.Binding {
+	return []key.Binding{k.keys.Up, k.keys.Down, withHelp(k.keys.Select, "start/stop"), k.keys.Back}
+}
+
+func (k forwardKeyMap) FullHelp() [][]key.Binding {
+	return [][]key.Binding{k.ShortHelp()}
+}
+
+// newForwardView creates a forward view for host. Call refresh to load the
+// forwards that are already running.
+func newForwardView(t theme.Theme, km keys.Map, host config.Host, manager *forward.Manager) *forwardView {
+	input := textinput.New()
+	input.Prompt = "new: "
+	input.Placeholder = "-L 8080:localhost:80"
+	input.Width = 40
+	input.PromptStyle = input.PromptStyle.Foreground(t.Accent)
+	return &forwardView{host: host, manager: manager, input: input, theme: t, keys: km}
+}
+
+// rows returns the number of selectable rows: configured forwards, running
+// forwards and the input.
+func (v *forwardView) rows() int {
+	return len(v.host.Forwards) + len(v.running) + 1
+}
+
+// onInput reports whether the cursor is on the input row.
+func (v *forwardView) onInput() bool {
+	return v.cursor == v.rows()-1
+}
+
+// refresh returns a command that loads the forwards running through the host.
+func (v *forwardView) refresh() tea.Cmd {
+	manager, alias := v.manager, v.host.Alias
+	return func() tea.Msg {
+		forwards, err := manager.List()
+		var running []forward.Forward
+		for _, fwd := range forwards {
+			if fwd.Host == alias {
+				running = append(running, fwd)
+			}
+		}
+		return forwardListMsg{running: running, err: err}
+	}
+}
+
+// start returns a command that starts spec through the host.
+func (v *forwardView) start(spec config.Forward) tea.Cmd {
+	v.busy = true
+	v.message = "starting " + spec.String() + " ..."
+	v.failed = false
+	manager, alias := v.manager, v.host.Alias
+	return func() tea.Msg {
+		fwd, err := manager.Start(alias, spec)
+		if err != nil {
+			return forwardDoneMsg{err: err}
+		}
+		return forwardDoneMsg{message: fmt.Sprintf("started #%d %s", fwd.ID, spec)}
+	}
+}
+
+// stop returns a command that stops a running forward.
+func (v *forwardView) stop(fwd forward.Forward) tea.Cmd {
+	v.busy = true
+	manager := v.manager
+	return func() tea.Msg {
+		if err := manager.Stop(fwd.ID); err != nil {
+			return forwardDoneMsg{err: err}
+		}
+		return forwardDoneMsg{message: fmt.Sprintf("stopped #%d %s", fwd.ID, fwd.Spec)}
+	}
+}
+
+// setCursor moves the cursor, focusing the input when it reaches it.
+func (v *forwardView) setCursor(cursor int) tea.Cmd {
+	v.cursor = max(0, min(cursor, v.rows()-1))
+	if v.onInput() {
+		return v.input.Focus()
+	}
+	v.input.Blur()
+	return nil
+}
+
+// update handles input for the forward view. It returns false when the user
+// leaves the view.
+func (v *forwardView) update(msg tea.Msg) (bool, tea.Cmd) {
+	switch msg := msg.(type) {
+	case forwardListMsg:
+		// Keep the cursor on the input while the list above it changes
+		onInput := v.onInput()
+		v.running = msg.running
+		if msg.err != nil {
+			v.message, v.failed = msg.err.Error(), true
+		}
+		if onInput {
+			return true, v.setCursor(v.rows() - 1)
+		}
+		return true, v.setCursor(v.cursor)
+	case forwardDoneMsg:
+		v.busy = false
+		if msg.err != nil {
+			v.message, v.failed = msg.err.Error(), true
+		} else {
+			v.message, v.failed = msg.message, false
+		}
+		return true, v.refresh()
+	case tea.KeyMsg:
+		// Keys that type text go to the spec being typed
+		if !v.onInput() || !typed(msg) {
+			switch {
+			case key.Matches(msg, v.keys.Back):
+				return false, nil
+			case key.Matches(msg, v.keys.Up):
+				return true, v.setCursor(v.cursor - 1)
+			case key.Matches(msg, v.keys.Down):
+				return true, v.setCursor(v.cursor + 1)
+			case key.Matches(msg, v.keys.Select):
+				if v.busy {
+					return true, nil
+				}
+				return true, v.activate()
+			}
+		}
+		if !v.onInput() {
+			return true, nil
+		}
+	}
+
+	var cmd tea.Cmd
+	v.input, cmd = v.input.Update(msg)
+	return true, cmd
+}
+
+// activate starts or stops the forward under the cursor.
+func (v *forwardView) activate() tea.Cmd {
+	configured := len(v.host.Forwards)
+	switch {
+	case v.cursor < configured:
+		return v.start(v.host.Forwards[v.cursor])
+	case !v.onInput():
+		return v.stop(v.running[v.cursor-configured])
+	}
+
+	value := strings.TrimSpace(v.input.Value())
+	if value == "" {
+		return nil
+	}
+	spec, err := config.ParseForwardFlag(value)
+	if err != nil {
+		v.message, v.failed = err.Error(), true
+		return nil
+	}
+	v.input.SetValue("")
+	return v.start(spec)
+}
+
+// view renders the forward form.
+func (v *forwardView) view() string {
+	header := lipgloss.NewStyle().Bold(true).Foreground(v.theme.Accent)
+	dim := lipgloss.NewStyle().Foreground(v.theme.Dim)
+	highlight := lipgloss.NewStyle().Foreground(v.theme.Accent)
+
+	var b strings.Builder
+	b.WriteString(header.Render("Port forwards through "+v.host.Alias) + "\n\n")
+
+	row := 0
+	line := func(text string) {
+		if row == v.cursor {
+			b.WriteString(highlight.Render("> "+text) + "\n")
+		} else {
+			b.WriteString("  " + text + "\n")
+		}
+		row++
+	}
+
+	b.WriteString(dim.Render("Configured") + "\n")
+	if len(v.host.Forwards) == 0 {
+		b.WriteString(dim.Render("  none") + "\n")
+	}
+	for _, spec := range v.host.Forwards {
+		line(spec.String())
+	}
+
+	b.WriteString("\n" + dim.Render("Running") + "\n")
+	if len(v.running) == 0 {
+		b.WriteString(dim.Render("  none") + "\n")
+	}
+	for _, fwd := range v.running {
+		line(fmt.Sprintf("#%d %s  pid %d", fwd.ID, fwd.Spec, fwd.PID))
+	}
+
+	b.WriteString("\n")
+	if v.onInput() {
+		b.WriteString(highlight.Render("> ") + v.input.View() + "\n")
+	} else {
+		b.WriteString("  " + v.input.View() + "\n")
+	}
+
+	if v.message != "" {
+		style := lipgloss.NewStyle().Foreground(v.theme.Success)
+		if v.failed {
+			style = style.Foreground(v.theme.Error)
+		}
+		b.WriteString("\n" + style.Render(v.message) + "\n")
+	}
+	return b.String()
+}
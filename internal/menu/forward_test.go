@@ -0,0 +1,88 @@
+package menu
+
+import (
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/antonjah/ssm/internal/config"
+	"github.com/antonjah/ssm/internal/forward"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// runForwardCmd runs cmd and feeds its message back into the view, following
+// the refresh that each finished operation triggers.
+func runForwardCmd(t *testing.T, v *forwardView, cmd tea.Cmd) {
+	t.Helper()
+	for cmd != nil {
+		msg := cmd()
+		switch msg.(type) {
+		case forwardListMsg, forwardDoneMsg:
+			_, cmd = v.update(msg)
+		default:
+			return
+		}
+	}
+}
+
+func TestForwardView(t *testing.T) {
+	sshPath := filepath.Join(t.TempDir(), "ssh")
+	if err := os.WriteFile(sshPath, []byte("#!/bin/sh\nwhile :; do sleep 0.1; done\n"), 0755); err != nil {
+		t.Fatalf("Failed to write fake ssh: %v", err)
+	}
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	_, port, _ := net.SplitHostPort(listener.Addr().String())
+	listener.Close()
+
+	manager := &forward.Manager{Dir: t.TempDir(), SSHPath: sshPath, Grace: 50 * time.Millisecond}
+	host := config.Host{
+		Alias:    "db",
+		Forwards: []config.Forward{{Type: config.LocalForward, Listen: port, Target: "localhost:5432"}},
+	}
+	v := newForwardView(defaultTheme(), defaultKeys(), host, manager)
+	runForwardCmd(t, v, v.refresh())
+
+	// Start the configured forward
+	_, cmd := v.update(tea.KeyMsg{Type: tea.KeyEnter})
+	runForwardCmd(t, v, cmd)
+	if len(v.running) != 1 || v.failed {
+		t.Fatalf("Expected one running forward, got %+v (%s)", v.running, v.message)
+	}
+
+	// Starting it again conflicts with the running one
+	_, cmd = v.update(tea.KeyMsg{Type: tea.KeyEnter})
+	runForwardCmd(t, v, cmd)
+	if !v.failed || !strings.Contains(v.message, "in use") {
+		t.Errorf("Expected a port conflict, got %q", v.message)
+	}
+
+	// Typed specs are validated before starting
+	v.update(tea.KeyMsg{Type: tea.KeyDown})
+	v.update(tea.KeyMsg{Type: tea.KeyDown})
+	if !v.onInput() {
+		t.Fatalf("Expected cursor on the input, got row %d", v.cursor)
+	}
+	v.input.SetValue("-L nonsense")
+	v.update(tea.KeyMsg{Type: tea.KeyEnter})
+	if !v.failed || !strings.Contains(v.message, "invalid forward") {
+		t.Errorf("Expected a parse error, got %q", v.message)
+	}
+
+	// Stop the running forward
+	v.update(tea.KeyMsg{Type: tea.KeyUp})
+	_, cmd = v.update(tea.KeyMsg{Type: tea.KeyEnter})
+	runForwardCmd(t, v, cmd)
+	if len(v.running) != 0 || v.failed {
+		t.Errorf("Expected the forward to be stopped, got %+v (%s)", v.running, v.message)
+	}
+
+	if stay, _ := v.update(tea.KeyMsg{Type: tea.KeyEsc}); stay {
+		t.Error("Expected esc to leave the forward view")
+	}
+}
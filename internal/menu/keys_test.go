@@ -0,0 +1,78 @@
+package menu
+
+import (
+	"testing"
+
+	"github.com/antonjah/ssm/internal/config"
+	"github.com/antonjah/ssm/internal/keys"
+)
+
+var keysHosts = []config.Host{{Alias: "queue"}, {Alias: "edge"}}
+
+// remappedKeys returns the bindings of preset with bindings applied.
+func remappedKeys(t *testing.T, preset string, bindings map[string][]string) keys.Map {
+	t.Helper()
+	km, err := keys.New(preset, bindings)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return km
+}
+
+func TestModel_KeysWhileFiltering(t *testing.T) {
+	model := newTestModel(keysHosts, Options{Keys: remappedKeys(t, keys.Default, nil)}, 0)
+	model, _ = sendKeys(model, "/", "queue")
+	if m := model.(Model); m.done || m.viewing || m.list.FilterValue() != "queue" {
+		t.Fatalf("Expected q and e to be typed into the filter, got %q (done %v)", m.list.FilterValue(), m.done)
+	}
+
+	model, _ = sendKeys(model, "enter")
+	if choice := model.(Model).choice; len(choice.Hosts) != 1 || choice.Hosts[0] != "queue" {
+		t.Errorf("Expected enter to connect to the match, got %+v", choice)
+	}
+}
+
+func TestModel_KeysRemapped(t *testing.T) {
+	km := remappedKeys(t, keys.Default, map[string][]string{"quit": {"ctrl+q"}, "toggle": {"i"}})
+	model, _ := sendKeys(newTestModel(keysHosts, Options{Keys: km}, 0), "q")
+	if model.(Model).done {
+		t.Fatal("Expected q not to quit once quit is remapped")
+	}
+	model, _ = sendKeys(model, "i")
+	if !model.(Model).selected["queue"] {
+		t.Error("Expected i to toggle the host")
+	}
+	model, _ = sendKeys(model, "ctrl+q")
+	if !model.(Model).done {
+		t.Error("Expected ctrl+q to quit")
+	}
+
+	vim, _ := sendKeys(newTestModel(keysHosts, Options{Keys: remappedKeys(t, keys.Vim, nil)}, 0), "l")
+	if choice := vim.(Model).choice; len(choice.Hosts) != 1 || choice.Hosts[0] != "queue" {
+		t.Errorf("Expected l to connect with the vim preset, got %+v", choice)
+	}
+}
+
+func TestModel_BackClearsFilter(t *testing.T) {
+	model := newTestModel(keysHosts, Options{Keys: remappedKeys(t, keys.Default, nil)}, 0)
+	model, _ = sendKeys(model, "/", "edge", "tab", "esc")
+	if m := model.(Model); m.done || m.list.FilterValue() != "" {
+		t.Errorf("Expected esc to clear the applied filter, got %q (done %v)", m.list.FilterValue(), m.done)
+	}
+	model, _ = sendKeys(model, "esc")
+	if !model.(Model).done {
+		t.Error("Expected esc to quit without a filter")
+	}
+}
+
+func TestModel_KeysRemappedInViews(t *testing.T) {
+	km := remappedKeys(t, keys.Default, map[string][]string{"quit": {"ctrl+q"}})
+	model, _ := sendKeys(newTestModel(keysHosts, Options{Keys: km}, 0), "b", "q")
+	if model.(Model).browser == nil {
+		t.Fatal("Expected q not to leave the file browser once quit is remapped")
+	}
+	model, _ = sendKeys(model, "ctrl+q")
+	if m := model.(Model); m.browser != nil || m.done {
+		t.Errorf("Expected ctrl+q to leave the file browser for the host list, got browser %v (done %v)", m.browser, m.done)
+	}
+}
@@ -3,29 +3,37 @@ package menu
 
 import (
 	"bufio"
+	"errors"
 	"fmt"
 	"io"
+	"maps"
 	"os"
 	"os/exec"
 	"path/filepath"
 	"sort"
 	"strings"
+	"time"
 
+	"github.com/antonjah/ssm/internal/audit"
 	"github.com/antonjah/ssm/internal/config"
+	"github.com/antonjah/ssm/internal/forward"
+	"github.com/antonjah/ssm/internal/keys"
+	"github.com/antonjah/ssm/internal/notes"
+	"github.com/antonjah/ssm/internal/probe"
+	"github.com/antonjah/ssm/internal/search"
+	"github.com/antonjah/ssm/internal/settings"
+	"github.com/antonjah/ssm/internal/theme"
 
-	catppuccin "github.com/catppuccin/go"
 	"github.com/charmbracelet/bubbles/help"
 	"github.com/charmbracelet/bubbles/key"
 	"github.com/charmbracelet/bubbles/list"
+	"github.com/charmbracelet/bubbles/textinput"
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
 	"golang.org/x/text/cases"
 	"golang.org/x/text/language"
 )
 
-// Use Catppuccin Mocha flavor
-var mocha = catppuccin.Mocha
-
 var docStyle = lipgloss.NewStyle().Margin(1, 2)
 
 var titleCaser = cases.Title(language.English)
@@ -70,28 +78,35 @@ const (
 // customDelegate provides a custom list delegate with additional keybinding help.
 type customDelegate struct {
 	defaultDelegate list.DefaultDelegate
+	// selected is shared with the Model and holds the multi-selected aliases.
+	selected map[string]bool
+	// status is shared with the Model and holds each host's reachability.
+	status map[string]reachability
+	// protected holds the aliases of protected hosts.
+	protected map[string]bool
+	theme     theme.Theme
+	keys      keys.Map
 }
 
-func newCustomDelegate() customDelegate {
+func newCustomDelegate(t theme.Theme, km keys.Map, selected map[string]bool, status map[string]reachability, protected map[string]bool) customDelegate {
 	d := list.NewDefaultDelegate()
 
-	// Apply Catppuccin Mocha colors
 	d.Styles.SelectedTitle = d.Styles.SelectedTitle.
-		Foreground(lipgloss.Color(mocha.Mauve().Hex)).
-		BorderForeground(lipgloss.Color(mocha.Mauve().Hex))
+		Foreground(t.Accent).
+		BorderForeground(t.Accent)
 	d.Styles.SelectedDesc = d.Styles.SelectedDesc.
-		Foreground(lipgloss.Color(mocha.Subtext0().Hex)).
-		BorderForeground(lipgloss.Color(mocha.Mauve().Hex))
+		Foreground(t.Muted).
+		BorderForeground(t.Accent)
 	d.Styles.NormalTitle = d.Styles.NormalTitle.
-		Foreground(lipgloss.Color(mocha.Text().Hex))
+		Foreground(t.Text)
 	d.Styles.NormalDesc = d.Styles.NormalDesc.
-		Foreground(lipgloss.Color(mocha.Subtext1().Hex))
+		Foreground(t.Subtext)
 	d.Styles.DimmedTitle = d.Styles.DimmedTitle.
-		Foreground(lipgloss.Color(mocha.Overlay0().Hex))
+		Foreground(t.Dim)
 	d.Styles.DimmedDesc = d.Styles.DimmedDesc.
-		Foreground(lipgloss.Color(mocha.Overlay0().Hex))
+		Foreground(t.Dim)
 
-	return customDelegate{defaultDelegate: d}
+	return customDelegate{defaultDelegate: d, selected: selected, status: status, protected: protected, theme: t, keys: km}
 }
 
 func (d customDelegate) Height() int {
@@ -107,49 +122,92 @@ func (d customDelegate) Update(msg tea.Msg, m *list.Model) tea.Cmd {
 }
 
 func (d customDelegate) Render(w io.Writer, m list.Model, index int, item list.Item) {
-	d.defaultDelegate.Render(w, m, index, item)
+	hostItem, ok := item.(HostItem)
+	if !ok {
+		d.defaultDelegate.Render(w, m, index, item)
+		return
+	}
+
+	display := displayHostItem{HostItem: hostItem, title: hostItem.Title(), desc: hostItem.Description()}
+	delegate := d.defaultDelegate
+
+	// The description is styled here rather than by the default delegate
+	// because it may mix colours, which would reset the delegate's style
+	descStyle := delegate.Styles.NormalDesc
+	switch {
+	case m.FilterState() == list.Filtering && m.FilterValue() == "":
+		descStyle = delegate.Styles.DimmedDesc
+	case index == m.Index() && m.FilterState() != list.Filtering:
+		descStyle = delegate.Styles.SelectedDesc
+	}
+	unmatched := lipgloss.NewStyle().Foreground(descStyle.GetForeground())
+	styledDesc := false
+
+	if m.FilterState() != list.Unfiltered {
+		// Show the field that matched when it isn't the alias itself
+		if match, ok := search.Parse(m.FilterValue()).Match(hostItem.host); ok && match.Field != search.FieldAlias {
+			matched := unmatched.Inherit(delegate.Styles.FilterMatch)
+			display.desc = unmatched.Render(match.Field.String()+": ") + lipgloss.StyleRunes(match.Value, match.Indexes, matched, unmatched)
+			styledDesc = true
+		}
+	}
+
+	status, probed := d.status[hostItem.host.Alias]
+	badge := statusBadge(d.theme, status, probed, unmatched)
+	if badge != "" {
+		if !styledDesc {
+			display.desc = unmatched.Render(display.desc)
+		}
+		display.desc = badge + unmatched.Render("  ") + display.desc
+	}
+
+	// Protected hosts are shown in red with a badge, whatever else applies
+	if d.protected[hostItem.host.Alias] {
+		if !styledDesc && badge == "" {
+			display.desc = unmatched.Render(display.desc)
+		}
+		warning := lipgloss.NewStyle().Foreground(d.theme.Error)
+		display.desc = warning.Render(protectedBadge) + unmatched.Render("  ") + display.desc
+		delegate.Styles.NormalTitle = delegate.Styles.NormalTitle.Foreground(warning.GetForeground())
+		delegate.Styles.SelectedTitle = delegate.Styles.SelectedTitle.Foreground(warning.GetForeground())
+	}
+
+	// Mark multi-selected hosts with a suffix so filter highlights stay aligned
+	if d.selected[hostItem.host.Alias] {
+		display.title += " " + selectedMarker
+		delegate.Styles.NormalTitle = delegate.Styles.NormalTitle.
+			Foreground(d.theme.Success)
+	}
+
+	delegate.Render(w, m, index, display)
 }
 
 func (d customDelegate) ShortHelp() []key.Binding {
-	return []key.Binding{
-		key.NewBinding(key.WithKeys("enter"), key.WithHelp("enter", "select")),
-		key.NewBinding(key.WithKeys("e"), key.WithHelp("e", "edit config")),
-		key.NewBinding(key.WithKeys("v"), key.WithHelp("v", "view details")),
-	}
+	return []key.Binding{d.keys.Connect, d.keys.Toggle, d.keys.Edit, d.keys.Details}
 }
 
 func (d customDelegate) FullHelp() [][]key.Binding {
 	return [][]key.Binding{
+		{d.keys.Connect, d.keys.Edit, d.keys.Details, d.keys.Note, d.keys.Preview, d.keys.Table},
 		{
-			key.NewBinding(key.WithKeys("enter"), key.WithHelp("enter", "select")),
-			key.NewBinding(key.WithKeys("e"), key.WithHelp("e", "edit config")),
-			key.NewBinding(key.WithKeys("v"), key.WithHelp("v", "view details")),
-		},
-		{
-			key.NewBinding(key.WithKeys("up", "k"), key.WithHelp("↑/k", "up")),
-			key.NewBinding(key.WithKeys("down", "j"), key.WithHelp("↓/j", "down")),
-			key.NewBinding(key.WithKeys("esc"), key.WithHelp("esc", "quit")),
+			d.keys.Toggle, d.keys.ToggleAll, d.keys.Tiled, d.keys.Cluster,
+			d.keys.Exec, d.keys.Forwards, d.keys.Copy, d.keys.Yank, d.keys.Browse,
 		},
+		{d.keys.Back},
 	}
 }
 
 // popupKeyMap provides key bindings for the popup view.
-type popupKeyMap struct{}
+type popupKeyMap struct {
+	keys keys.Map
+}
 
 func (p popupKeyMap) ShortHelp() []key.Binding {
-	return []key.Binding{
-		key.NewBinding(key.WithKeys("esc"), key.WithHelp("esc", "go back")),
-		key.NewBinding(key.WithKeys("q"), key.WithHelp("q", "quit")),
-	}
+	return []key.Binding{p.keys.Note, p.keys.Back, p.keys.Quit}
 }
 
 func (p popupKeyMap) FullHelp() [][]key.Binding {
-	return [][]key.Binding{
-		{
-			key.NewBinding(key.WithKeys("esc"), key.WithHelp("esc", "go back")),
-			key.NewBinding(key.WithKeys("q"), key.WithHelp("q", "quit")),
-		},
-	}
+	return [][]key.Binding{p.ShortHelp()}
 }
 
 // HostItem represents a selectable SSH host item in the list.
@@ -166,84 +224,496 @@ func (i HostItem) Title() string { return i.host.Alias }
 // Description returns the description for the item.
 func (i HostItem) Description() string { return i.host.HostName }
 
+// selectedMarker is appended to the title of multi-selected hosts.
+const selectedMarker = "✓"
+
+// displayHostItem is a HostItem with a decorated title and description used
+// only for rendering.
+type displayHostItem struct {
+	HostItem
+	title string
+	desc  string
+}
+
+// Title returns the decorated title.
+func (i displayHostItem) Title() string { return i.title }
+
+// Description returns the decorated description.
+func (i displayHostItem) Description() string { return i.desc }
+
+// hostFilter returns a list.FilterFunc that matches the search query language
+// against every field of the given hosts. Targets are host aliases.
+func hostFilter(hosts []config.Host) list.FilterFunc {
+	byAlias := make(map[string]config.Host, len(hosts))
+	for _, host := range hosts {
+		byAlias[host.Alias] = host
+	}
+
+	return func(term string, targets []string) []list.Rank {
+		query := search.Parse(term)
+		type scored struct {
+			rank  list.Rank
+			score int
+		}
+		var results []scored
+		for index, alias := range targets {
+			host, ok := byAlias[alias]
+			if !ok {
+				host = config.Host{Alias: alias}
+			}
+			match, ok := query.Match(host)
+			if !ok {
+				continue
+			}
+			results = append(results, scored{
+				rank:  list.Rank{Index: index, MatchedIndexes: match.AliasIndexes},
+				score: match.Score,
+			})
+		}
+
+		sort.SliceStable(results, func(i, j int) bool {
+			return results[i].score > results[j].score
+		})
+
+		ranks := make([]list.Rank, len(results))
+		for i, result := range results {
+			ranks[i] = result.rank
+		}
+		return ranks
+	}
+}
+
+// Layout describes how the chosen hosts should be opened.
+type Layout int
+
+const (
+	// LayoutWindows opens each host in its own tmux window.
+	LayoutWindows Layout = iota
+	// LayoutTiled opens all hosts as tiled panes in a single tmux window.
+	LayoutTiled
+	// LayoutCluster is LayoutTiled with keystrokes synchronized to every pane.
+	LayoutCluster
+)
+
+// Choice is the outcome of a menu session.
+type Choice struct {
+	// Hosts are the chosen host aliases in list order. It is empty if the
+	// user quit without choosing.
+	Hosts []string
+	// Layout is how the hosts should be opened.
+	Layout Layout
+}
+
 // Model represents the state of the SSH host selection menu.
 type Model struct {
 	list        list.Model
 	help        help.Model
-	choice      string
+	choice      Choice
+	selected    map[string]bool
 	done        bool
 	viewing     bool
 	hostDetails *HostDetails
+	prompting   bool
+	prompt      textinput.Model
+	confirm     *confirmView
+	exec        *execView
+	forward     *forwardView
+	transfer    *transferView
+	browser     *fileBrowser
+	// browsers counts the file browsers opened, to tell their sessions apart.
+	browsers    int
+	yank        *yankView
+	notice      notice
+	sshPath     string
+	hosts       []config.Host
+	opts        Options
+	status      map[string]reachability
+	protected   map[string]bool
+	preview     bool
+	lastConnect map[string]audit.Entry
+	table       bool
+	sort        *hostSort
+	rows        customDelegate
+	columns     tableDelegate
+	lastClick   click
 	width       int
 	height      int
 }
 
+// Options configures optional menu behaviour.
+type Options struct {
+	// Probe checks each host's reachability in the background and shows a
+	// status dot and latency next to it.
+	Probe bool
+	// ProbeBanner also reads each host's SSH banner while probing.
+	ProbeBanner bool
+	// MaxHeight caps the height of the host list, if set.
+	MaxHeight int
+	// HideDescriptions shows only the aliases in the host list.
+	HideDescriptions bool
+	// Preview starts with the highlighted host's details shown beside the
+	// list.
+	Preview bool
+	// Table starts with the hosts shown as a table rather than a list.
+	Table bool
+	// Columns are the table's columns from settings.TableColumns. It
+	// defaults to all of them.
+	Columns []string
+	// Mouse lets hosts be picked with the mouse. The menu then uses the
+	// terminal's alternate screen.
+	Mouse bool
+	// Audit logs remote commands, file copies and config edits, if set.
+	Audit *audit.Logger
+	// Notes keeps the hosts' notes, shown in the details popup and edited
+	// from the menu, if set.
+	Notes *notes.Store
+	// Protect selects the hosts that need confirming before connecting to
+	// them or running commands on them.
+	Protect settings.Protect
+	// Theme colours the menu. The zero value uses theme.Default.
+	Theme theme.Theme
+	// Keys binds the menu's actions. The zero value uses keys.Default.
+	Keys keys.Map
+}
+
 // NewModel creates a new menu model with the given SSH hosts.
-func NewModel(hosts []config.Host) Model {
+func NewModel(hosts []config.Host, opts Options) Model {
 	hostItems := make([]list.Item, len(hosts))
 	for index, host := range hosts {
 		hostItems[index] = HostItem{host: host}
 	}
 
-	hostList := list.New(hostItems, newCustomDelegate(), defaultListWidth, defaultListHeight)
+	selected := make(map[string]bool)
+	status := make(map[string]reachability)
+	if opts.Theme.Name == "" {
+		opts.Theme, _ = theme.Builtin(theme.Default)
+	}
+	t := opts.Theme
+	if opts.Keys.IsZero() {
+		opts.Keys, _ = keys.New(keys.Default, nil)
+	}
+	km := opts.Keys
+	protected := make(map[string]bool)
+	for _, host := range hosts {
+		if opts.Protect.Matches(host) {
+			protected[host.Alias] = true
+		}
+	}
+	delegate := newCustomDelegate(t, km, selected, status, protected)
+	delegate.defaultDelegate.ShowDescription = !opts.HideDescriptions
+	hostList := list.New(hostItems, delegate, defaultListWidth, defaultListHeight)
 	hostList.SetFilteringEnabled(true)
+	sorting := &hostSort{}
+	hostList.Filter = sortedFilter(hostFilter(hosts), sorting)
 	hostList.SetShowTitle(false)
 
-	// Apply Catppuccin Mocha colors to list styles
 	hostList.Styles.Title = hostList.Styles.Title.
-		Foreground(lipgloss.Color(mocha.Mauve().Hex)).
+		Foreground(t.Accent).
 		Bold(true)
 	hostList.Styles.FilterPrompt = hostList.Styles.FilterPrompt.
-		Foreground(lipgloss.Color(mocha.Mauve().Hex))
+		Foreground(t.Accent)
 	hostList.Styles.FilterCursor = hostList.Styles.FilterCursor.
-		Foreground(lipgloss.Color(mocha.Pink().Hex))
+		Foreground(t.Highlight)
+	hostList.Styles.StatusBar = hostList.Styles.StatusBar.Foreground(t.Dim)
+	hostList.Styles.StatusEmpty = hostList.Styles.StatusEmpty.Foreground(t.Dim)
+	hostList.Styles.StatusBarActiveFilter = hostList.Styles.StatusBarActiveFilter.Foreground(t.Text)
+	hostList.Styles.StatusBarFilterCount = hostList.Styles.StatusBarFilterCount.Foreground(t.Dim)
+	hostList.Styles.NoItems = hostList.Styles.NoItems.Foreground(t.Dim)
+	hostList.Styles.ActivePaginationDot = hostList.Styles.ActivePaginationDot.Foreground(t.Muted)
+	hostList.Styles.InactivePaginationDot = hostList.Styles.InactivePaginationDot.Foreground(t.Border)
+	hostList.Styles.ArabicPagination = hostList.Styles.ArabicPagination.Foreground(t.Dim)
+	hostList.Styles.DividerDot = hostList.Styles.DividerDot.Foreground(t.Border)
+	// The paginator renders its dots once, so they are restyled too
+	hostList.Paginator.ActiveDot = hostList.Styles.ActivePaginationDot.String()
+	hostList.Paginator.InactiveDot = hostList.Styles.InactivePaginationDot.String()
+	hostList.Help = newHelp(t)
+	hostList.KeyMap.CursorUp = km.Up
+	hostList.KeyMap.CursorDown = km.Down
+	hostList.KeyMap.PrevPage = km.PageUp
+	hostList.KeyMap.NextPage = km.PageDown
+	hostList.KeyMap.GoToStart = km.Home
+	hostList.KeyMap.GoToEnd = km.End
+	hostList.KeyMap.Filter = km.Filter
+	hostList.KeyMap.ShowFullHelp = km.Help
+	hostList.KeyMap.CloseFullHelp = key.NewBinding(key.WithKeys(km.Help.Keys()...), key.WithHelp(km.Help.Help().Key, "close help"))
+	hostList.KeyMap.ClearFilter = key.NewBinding(key.WithKeys(km.Back.Keys()...), key.WithHelp(km.Back.Help().Key, "clear filter"))
+	hostList.KeyMap.Quit = km.Quit
 
-	return Model{
-		list: hostList,
-		help: help.New(),
+	prompt := textinput.New()
+	prompt.Prompt = "command: "
+	prompt.PromptStyle = prompt.PromptStyle.Foreground(t.Accent)
+
+	lastConnect := make(map[string]audit.Entry)
+	m := Model{
+		list:        hostList,
+		help:        newHelp(t),
+		selected:    selected,
+		prompt:      prompt,
+		hosts:       hosts,
+		opts:        opts,
+		status:      status,
+		protected:   protected,
+		preview:     opts.Preview,
+		lastConnect: lastConnect,
+		sort:        sorting,
+		rows:        delegate,
+		columns:     newTableDelegate(delegate, hosts, validColumns(opts.Columns), sorting, lastConnect),
+	}
+	if opts.Table {
+		m.setTable(true)
 	}
+	return m
+}
+
+// newHelp returns a help view in the theme's colours.
+func newHelp(t theme.Theme) help.Model {
+	h := help.New()
+	h.Styles.ShortKey = h.Styles.ShortKey.Foreground(t.Muted)
+	h.Styles.FullKey = h.Styles.FullKey.Foreground(t.Muted)
+	h.Styles.ShortDesc = h.Styles.ShortDesc.Foreground(t.Dim)
+	h.Styles.FullDesc = h.Styles.FullDesc.Foreground(t.Dim)
+	h.Styles.ShortSeparator = h.Styles.ShortSeparator.Foreground(t.Border)
+	h.Styles.FullSeparator = h.Styles.FullSeparator.Foreground(t.Border)
+	h.Styles.Ellipsis = h.Styles.Ellipsis.Foreground(t.Border)
+	return h
 }
 
 // Init initializes the Bubble Tea model.
 func (m Model) Init() tea.Cmd {
-	return nil
+	var cmds []tea.Cmd
+	if m.opts.Probe {
+		cmds = append(cmds, startProbes(m.hosts, probe.Options{Banner: m.opts.ProbeBanner}))
+	}
+	if m.opts.Audit != nil && m.opts.Audit.Path != "" {
+		cmds = append(cmds, loadLastConnects(m.opts.Audit.Path))
+	}
+	return tea.Batch(cmds...)
 }
 
 // Update handles messages and updates the model state.
 func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	// Probe results arrive in the background whatever view is showing
+	switch msg := msg.(type) {
+	case probeStartMsg:
+		for _, alias := range msg.skipped {
+			m.status[alias] = reachability{skipped: true}
+		}
+		for _, alias := range msg.pending {
+			m.status[alias] = reachability{}
+		}
+		for _, result := range msg.cached {
+			m.status[result.Alias] = reachability{done: true, result: result}
+		}
+		return m, waitForProbe(msg.events)
+	case probeResultMsg:
+		m.status[msg.result.Alias] = reachability{done: true, result: msg.result}
+		return m, waitForProbe(msg.events)
+	case lastConnectMsg:
+		maps.Copy(m.lastConnect, msg)
+		return m, nil
+	case copiedMsg:
+		return m, m.setNotice(copiedNotice(msg))
+	case noteEditedMsg:
+		return m, m.noteEdited(msg)
+	case clearNoticeMsg:
+		if msg.id == m.notice.id {
+			m.notice = notice{id: msg.id}
+		}
+		return m, nil
+	}
+	if msg, ok := msg.(browserConnectedMsg); ok && (m.browser == nil || m.browser.id != msg.id) {
+		// The browser was closed, or another opened, before its session was up
+		if msg.closer != nil {
+			msg.closer.Close()
+		}
+		return m, nil
+	}
+
+	if size, ok := msg.(tea.WindowSizeMsg); ok && m.exec != nil {
+		m.exec.setSize(size.Width, size.Height)
+	}
+	if m.exec != nil {
+		if key, ok := msg.(tea.KeyMsg); ok && key.String() == "ctrl+c" {
+			m.exec.stop()
+		} else if stay, cmd := m.exec.update(msg); stay {
+			return m, cmd
+		} else {
+			m.exec = nil
+			return m, cmd
+		}
+	}
+	if m.forward != nil {
+		if key, ok := msg.(tea.KeyMsg); ok && key.String() == "ctrl+c" {
+			m.forward = nil
+		} else if stay, cmd := m.forward.update(msg); stay {
+			return m, cmd
+		} else {
+			m.forward = nil
+			return m, cmd
+		}
+	}
+	if m.yank != nil {
+		switch msg := msg.(type) {
+		case tea.KeyMsg:
+			if msg.String() == "ctrl+c" {
+				m.yank = nil
+				return m, nil
+			}
+			stay, cmd := m.yank.update(msg)
+			if !stay {
+				m.yank = nil
+			}
+			return m, cmd
+		case tea.MouseMsg:
+			return m, nil
+		}
+	}
+	if m.browser != nil {
+		if size, ok := msg.(tea.WindowSizeMsg); ok {
+			m.browser.setSize(size.Width, size.Height)
+		}
+		if key, ok := msg.(tea.KeyMsg); ok && key.String() == "ctrl+c" {
+			m.browser.close()
+			m.browser = nil
+		} else if stay, cmd := m.browser.update(msg); stay {
+			return m, cmd
+		} else {
+			m.browser = nil
+			return m, cmd
+		}
+	}
+	if m.transfer != nil {
+		if key, ok := msg.(tea.KeyMsg); ok && key.String() == "ctrl+c" {
+			m.transfer.stop()
+			m.transfer = nil
+		} else if stay, cmd := m.transfer.update(msg); stay {
+			return m, cmd
+		} else {
+			m.transfer = nil
+			return m, cmd
+		}
+	}
+	if m.confirm != nil {
+		if key, ok := msg.(tea.KeyMsg); ok && key.String() == "ctrl+c" {
+			m.choice = Choice{}
+			m.done = true
+			return m, tea.Quit
+		}
+		confirm := m.confirm
+		if stay, cmd := confirm.update(msg); stay {
+			return m, cmd
+		}
+		m.confirm = nil
+		if confirm.confirmed {
+			return confirm.proceed(m)
+		}
+		return m, nil
+	}
+	if m.prompting {
+		return m.updatePrompt(msg)
+	}
+
 	switch msg := msg.(type) {
 	case tea.KeyMsg:
-		switch msg.String() {
-		case "enter":
+		if msg.String() == "ctrl+c" {
+			m.choice = Choice{}
+			m.done = true
+			return m, tea.Quit
+		}
+		// While the filter is being typed, keys that type text go to it,
+		// and of the others only connect acts, on the highlighted match
+		if m.list.SettingFilter() {
+			if !typed(msg) && key.Matches(msg, m.opts.Keys.Connect) {
+				return m.connect(LayoutWindows)
+			}
+			break
+		}
+		km := m.opts.Keys
+		switch {
+		case key.Matches(msg, km.Connect):
+			return m.connect(LayoutWindows)
+		case key.Matches(msg, km.Tiled):
+			return m.connect(LayoutTiled)
+		case key.Matches(msg, km.Cluster):
+			return m.connect(LayoutCluster)
+		case key.Matches(msg, km.Toggle):
+			m.toggleSelected()
+			return m, nil
+		case key.Matches(msg, km.ToggleAll):
+			m.toggleAllVisible()
+			return m, nil
+		case key.Matches(msg, km.Exec):
+			if len(m.chosenHosts()) > 0 {
+				m.prompting = true
+				m.prompt.SetValue("")
+				return m, m.prompt.Focus()
+			}
+		case key.Matches(msg, km.Forwards):
+			return m, m.openForwards()
+		case key.Matches(msg, km.Copy):
 			if item, ok := m.list.SelectedItem().(HostItem); ok {
-				m.choice = item.Title()
-				m.done = true
-				return m, tea.Quit
+				m.transfer = newTransferView(m.opts.Theme, m.opts.Keys, item.host)
+				m.transfer.audit = m.opts.Audit
+				return m, m.transfer.focus(transferLocal)
 			}
-		case "e":
+		case key.Matches(msg, km.Yank):
+			if item, ok := m.list.SelectedItem().(HostItem); ok {
+				m.yank = newYankView(m.opts.Theme, m.opts.Keys, item.host)
+				return m, nil
+			}
+		case key.Matches(msg, km.Browse):
+			if item, ok := m.list.SelectedItem().(HostItem); ok {
+				m.browsers++
+				m.browser = newFileBrowser(m.opts.Theme, m.opts.Keys, item.host.Alias, m.sshPath, m.width, m.height)
+				m.browser.id = m.browsers
+				m.browser.audit = m.opts.Audit
+				return m, m.browser.connect()
+			}
+		case key.Matches(msg, km.Edit):
 			return m, m.openEditor()
-		case "v":
+		case key.Matches(msg, km.Details):
 			return m, m.showHostDetails()
-		case "esc":
+		case key.Matches(msg, km.Note):
+			return m, m.editNote()
+		case key.Matches(msg, km.Preview):
+			m.preview = !m.preview
+			m.resize()
+			return m, nil
+		case key.Matches(msg, km.Table):
+			m.setTable(!m.table)
+			return m, nil
+		case m.table && key.Matches(msg, km.Sort):
+			m.sort.column = nextColumn(m.columns.columns, m.sort.column)
+			m.sort.reverse = false
+			return m, m.sortHosts()
+		case m.table && key.Matches(msg, km.SortReverse) && m.sort.column != "":
+			m.sort.reverse = !m.sort.reverse
+			return m, m.sortHosts()
+		case key.Matches(msg, km.Back):
 			if m.viewing {
 				m.viewing = false
 				m.hostDetails = nil
 				return m, nil
 			}
-			// Exit the application when pressing esc at the main menu
-			m.choice = "exit"
+			if m.list.FilterState() == list.FilterApplied {
+				m.list.ResetFilter()
+				return m, nil
+			}
+			// Exit the application when going back from the main menu
+			m.choice = Choice{}
 			m.done = true
 			return m, tea.Quit
-		case "q", "ctrl+c":
-			m.choice = "exit"
+		case key.Matches(msg, km.Quit):
+			m.choice = Choice{}
 			m.done = true
 			return m, tea.Quit
 		}
+	case tea.MouseMsg:
+		return m.updateMouse(msg)
 	case tea.WindowSizeMsg:
-		h, v := docStyle.GetFrameSize()
-		m.list.SetSize(msg.Width-h, msg.Height-v)
 		m.width = msg.Width
 		m.height = msg.Height
+		m.resize()
 	}
 
 	var cmd tea.Cmd
@@ -251,32 +721,249 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	return m, cmd
 }
 
+// connect chooses the highlighted or multi-selected hosts and quits, once
+// any protected ones are confirmed.
+func (m Model) connect(layout Layout) (tea.Model, tea.Cmd) {
+	hosts := m.chosenHosts()
+	if len(hosts) == 0 {
+		return m, nil
+	}
+	choice := Choice{Hosts: hosts, Layout: layout}
+	return m.confirmProtected("Connect to", hosts, func(m Model) (Model, tea.Cmd) {
+		m.choice = choice
+		m.done = true
+		return m, tea.Quit
+	})
+}
+
+// confirmProtected carries out action on hosts, first asking for
+// confirmation if any of them are protected.
+func (m Model) confirmProtected(action string, hosts []string, proceed func(Model) (Model, tea.Cmd)) (tea.Model, tea.Cmd) {
+	var protected []string
+	for _, alias := range hosts {
+		if m.protected[alias] {
+			protected = append(protected, alias)
+		}
+	}
+	if len(protected) == 0 {
+		return proceed(m)
+	}
+	m.confirm = newConfirmView(m.opts.Theme, m.opts.Keys, action, protected, m.opts.Protect.Confirm, proceed)
+	return m, m.confirm.focus()
+}
+
+// updatePrompt handles input while asking for a command to run.
+func (m Model) updatePrompt(msg tea.Msg) (tea.Model, tea.Cmd) {
+	if keyMsg, ok := msg.(tea.KeyMsg); ok && keyMsg.String() == "ctrl+c" {
+		m.choice = Choice{}
+		m.done = true
+		return m, tea.Quit
+	}
+	if keyMsg, ok := msg.(tea.KeyMsg); ok && !typed(keyMsg) {
+		switch {
+		case key.Matches(keyMsg, m.opts.Keys.Select):
+			command := strings.TrimSpace(m.prompt.Value())
+			m.prompting = false
+			m.prompt.Blur()
+			if command == "" {
+				return m, nil
+			}
+			hosts := m.chosenHosts()
+			return m.confirmProtected(fmt.Sprintf("Run %q on", command), hosts, func(m Model) (Model, tea.Cmd) {
+				m.exec = newExecView(m.opts.Theme, m.opts.Keys, command, hosts, m.sshPath, m.width, m.height)
+				m.exec.audit = m.opts.Audit
+				return m, m.exec.start(hosts)
+			})
+		case key.Matches(keyMsg, m.opts.Keys.Back):
+			m.prompting = false
+			m.prompt.Blur()
+			return m, nil
+		}
+	}
+
+	var cmd tea.Cmd
+	m.prompt, cmd = m.prompt.Update(msg)
+	return m, cmd
+}
+
+// withHelp returns b with its help describing it as desc, for views where
+// an action means something more specific.
+func withHelp(b key.Binding, desc string) key.Binding {
+	b.SetHelp(b.Help().Key, desc)
+	return b
+}
+
+// typed reports whether msg types text. Text inputs take such keys
+// whatever they are bound to, so that they can be typed.
+func typed(msg tea.KeyMsg) bool {
+	return (msg.Type == tea.KeyRunes || msg.Type == tea.KeySpace) && !msg.Alt
+}
+
 func (m Model) View() string {
 	if m.done {
 		return ""
 	}
 
-	if m.viewing {
-		popupContent := m.createPopupView()
+	if m.exec != nil {
+		return docStyle.Render(lipgloss.JoinVertical(lipgloss.Left, m.exec.view(), m.help.View(execKeyMap{keys: m.opts.Keys})))
+	}
 
-		popupStyle := lipgloss.NewStyle().
-			Margin(1, 2).
-			Padding(1, 2).
-			Border(lipgloss.RoundedBorder()).
-			BorderForeground(lipgloss.Color(mocha.Mauve().Hex)).
-			Foreground(lipgloss.Color(mocha.Text().Hex)).
-			Background(lipgloss.Color(mocha.Base().Hex)).
-			Width(60) // Fixed width for better centering
+	if m.forward != nil {
+		return docStyle.Render(lipgloss.JoinVertical(lipgloss.Left, m.forward.view(), m.help.View(forwardKeyMap{keys: m.opts.Keys})))
+	}
 
-		styledPopup := popupStyle.Render(popupContent)
-		centeredPopup := lipgloss.Place(m.width, m.height-3, lipgloss.Center, lipgloss.Center, styledPopup)
+	if m.yank != nil {
+		return docStyle.Render(lipgloss.JoinVertical(lipgloss.Left, m.yank.view(), m.help.View(yankKeyMap{keys: m.opts.Keys})))
+	}
+
+	if m.browser != nil {
+		return docStyle.Render(lipgloss.JoinVertical(lipgloss.Left, m.browser.view(), m.help.View(browserKeyMap{keys: m.opts.Keys})))
+	}
 
-		helpView := m.help.View(popupKeyMap{})
+	if m.transfer != nil {
+		return docStyle.Render(lipgloss.JoinVertical(lipgloss.Left, m.transfer.view(), m.help.View(transferKeyMap{keys: m.opts.Keys})))
+	}
+
+	if m.confirm != nil {
+		return docStyle.Render(lipgloss.JoinVertical(lipgloss.Left, m.confirm.view(), "", m.help.View(confirmKeyMap{keys: m.opts.Keys, question: m.confirm.expected == ""})))
+	}
+
+	if m.prompting {
+		hosts := m.chosenHosts()
+		header := fmt.Sprintf("Run on %d host(s): %s", len(hosts), strings.Join(hosts, ", "))
+		return docStyle.Render(lipgloss.JoinVertical(lipgloss.Left, header, "", m.prompt.View()))
+	}
+
+	if m.viewing {
+		centeredPopup := lipgloss.Place(m.width, m.height-3, lipgloss.Center, lipgloss.Center, m.popupView())
+
+		helpView := m.help.View(popupKeyMap{keys: m.opts.Keys})
 
 		return lipgloss.JoinVertical(lipgloss.Left, centeredPopup, helpView)
 	}
 
-	return docStyle.Render(m.list.View())
+	hostList := m.list.View()
+	if notice := m.noticeView(); notice != "" && !m.table && !m.list.SettingFilter() {
+		// The list's title is hidden, leaving its first line free
+		_, rest, _ := strings.Cut(hostList, "\n")
+		hostList = notice + "\n" + rest
+	}
+	if m.table {
+		hostList = lipgloss.JoinVertical(lipgloss.Left, m.tableTitle(), m.columns.header(m.list.Width()), hostList)
+	}
+	if width := m.previewWidth(); width > 0 {
+		// The list's help can run past its width, so it is cut off there
+		hostList = lipgloss.NewStyle().MaxWidth(m.list.Width()).Render(hostList)
+		pane := m.previewView(width, lipgloss.Height(hostList))
+		return docStyle.Render(lipgloss.JoinHorizontal(lipgloss.Top, hostList, pane))
+	}
+	return docStyle.Render(hostList)
+}
+
+// resize fits the host list to the terminal, leaving room for the preview
+// pane if it is shown.
+func (m *Model) resize() {
+	if m.width == 0 {
+		return
+	}
+	h, v := docStyle.GetFrameSize()
+	height := m.height - v
+	if m.opts.MaxHeight > 0 {
+		height = min(height, m.opts.MaxHeight)
+	}
+	if m.table {
+		// The table's title and header are drawn above the list
+		height -= tableHeaderHeight
+	}
+	m.list.SetSize(m.width-h-m.previewWidth(), height)
+}
+
+// openForwards shows the port forward form for the highlighted host.
+func (m *Model) openForwards() tea.Cmd {
+	item, ok := m.list.SelectedItem().(HostItem)
+	if !ok {
+		return nil
+	}
+	manager, err := forward.NewManager(m.sshPath)
+	if err != nil {
+		return nil
+	}
+	m.forward = newForwardView(m.opts.Theme, m.opts.Keys, item.host, manager)
+	return tea.Batch(m.forward.refresh(), m.forward.setCursor(0))
+}
+
+// chosenHosts returns the multi-selected aliases in list order, or the
+// highlighted alias if nothing is selected.
+func (m Model) chosenHosts() []string {
+	var hosts []string
+	for _, item := range m.list.Items() {
+		if hostItem, ok := item.(HostItem); ok && m.selected[hostItem.host.Alias] {
+			hosts = append(hosts, hostItem.host.Alias)
+		}
+	}
+	if len(hosts) > 0 {
+		return hosts
+	}
+	if item, ok := m.list.SelectedItem().(HostItem); ok {
+		return []string{item.host.Alias}
+	}
+	return nil
+}
+
+// toggleSelected toggles the multi-selection of the highlighted host.
+func (m *Model) toggleSelected() {
+	if item, ok := m.list.SelectedItem().(HostItem); ok {
+		if m.selected[item.host.Alias] {
+			delete(m.selected, item.host.Alias)
+		} else {
+			m.selected[item.host.Alias] = true
+		}
+	}
+}
+
+// toggleAllVisible selects every host matching the current filter, or clears
+// them if they are all selected already.
+func (m *Model) toggleAllVisible() {
+	visible := m.list.VisibleItems()
+	allSelected := true
+	for _, item := range visible {
+		if hostItem, ok := item.(HostItem); ok && !m.selected[hostItem.host.Alias] {
+			allSelected = false
+			break
+		}
+	}
+	for _, item := range visible {
+		if hostItem, ok := item.(HostItem); ok {
+			if allSelected {
+				delete(m.selected, hostItem.host.Alias)
+			} else {
+				m.selected[hostItem.host.Alias] = true
+			}
+		}
+	}
+}
+
+// popupMargin is the space around the details popup's border.
+var popupMargin = lipgloss.NewStyle().Margin(1, 2)
+
+// popupWidth is the details popup's fixed width, which centres better than
+// one that follows its content.
+const popupWidth = 60
+
+// popupPadding is the space inside the details popup's border.
+var popupPadding = lipgloss.NewStyle().Padding(1, 2)
+
+// popupView renders the details popup.
+func (m Model) popupView() string {
+	popupStyle := popupMargin.
+		Padding(popupPadding.GetPadding()).
+		Border(lipgloss.RoundedBorder()).
+		BorderForeground(m.opts.Theme.Accent).
+		Foreground(m.opts.Theme.Text).
+		Background(m.opts.Theme.Background).
+		Width(popupWidth)
+
+	return popupStyle.Render(m.createPopupView())
 }
 
 // createPopupView creates a styled popup view for host details
@@ -287,6 +974,16 @@ func (m Model) createPopupView() string {
 
 	var builder strings.Builder
 	builder.WriteString(fmt.Sprintf("%s\n\n", m.hostDetails.Alias))
+	if m.protected[m.hostDetails.Alias] {
+		warning := lipgloss.NewStyle().Foreground(m.opts.Theme.Error).Bold(true)
+		builder.WriteString(warning.Render(protectedBadge) + "\n\n")
+	}
+	if m.hostDetails.Route != "" {
+		builder.WriteString(fmt.Sprintf("Route: %s\n\n", m.hostDetails.Route))
+	}
+	if m.hostDetails.Via != "" {
+		builder.WriteString(fmt.Sprintf("Via: %s\n\n", m.hostDetails.Via))
+	}
 
 	var pairs []struct{ key, value string }
 	for key, value := range m.hostDetails.Details {
@@ -317,6 +1014,18 @@ func (m Model) createPopupView() string {
 		builder.WriteString(fmt.Sprintf("%-*s    %s\n", maxKeyLen, pair.key, pair.value))
 	}
 
+	if m.hostDetails.Note != "" {
+		// The note gets what is left of the screen once the popup's frame,
+		// the details and the help below it are drawn
+		height := 0
+		if m.height > 0 {
+			frame := popupMargin.GetVerticalMargins() + popupPadding.GetVerticalPadding() + 2
+			height = max(m.height-3-frame-lipgloss.Height(builder.String()), 3)
+		}
+		width := popupWidth - popupPadding.GetHorizontalPadding()
+		builder.WriteString("\n" + noteView(m.opts.Theme, m.hostDetails.Alias, m.hostDetails.Note, width, height))
+	}
+
 	return builder.String()
 }
 
@@ -326,10 +1035,25 @@ func (m Model) openEditor() tea.Cmd {
 	if editor == "" {
 		return nil
 	}
-	configPath := getSSHConfigPath()
+	return m.editFile(editor, getSSHConfigPath(), "", func(error) tea.Msg { return nil })
+}
 
-	return tea.ExecProcess(exec.Command(editor, configPath), func(err error) tea.Msg {
-		return nil
+// editFile opens path in editor and logs the edit, against alias if it is
+// a host's file. done turns the editor's failure to start, if any, into the
+// message sent when it exits.
+func (m Model) editFile(editor, path, alias string, done func(error) tea.Msg) tea.Cmd {
+	logger := m.opts.Audit
+	entry := audit.Entry{Action: audit.Edit, Host: alias, Transport: editor, Command: path}
+	start := time.Now()
+	return tea.ExecProcess(exec.Command(editor, path), func(err error) tea.Msg {
+		code := 0
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			code, err = exitErr.ExitCode(), nil
+		}
+		entry.Finish(start, code, err)
+		logger.Log(entry)
+		return done(err)
 	})
 }
 
@@ -340,17 +1064,39 @@ func (m *Model) showHostDetails() tea.Cmd {
 		if err != nil {
 			return nil
 		}
+		details.Route = jumpRoute(m.hosts, item.host)
+		details.Via = item.host.Via
+		details.Note = m.readNote(item.host)
 		m.viewing = true
 		m.hostDetails = details
 	}
 	return nil
 }
 
+// jumpRoute describes how host is reached through its jump hosts, or returns
+// an empty string if it is reached directly.
+func jumpRoute(hosts []config.Host, host config.Host) string {
+	if len(host.Jumps()) == 0 {
+		return ""
+	}
+	chain, err := config.NewGraph(hosts).Chain(host.Alias)
+	if err != nil {
+		return err.Error()
+	}
+	return config.Route(chain)
+}
+
 // HostDetails contains detailed configuration information for an SSH host.
 type HostDetails struct {
 	Alias    string
 	HostName string
 	Details  map[string]string
+	// Route is the jump chain used to reach the host, if any.
+	Route string
+	// Via is the transport from the host's "# ssm:via" metadata, if any.
+	Via string
+	// Note is the host's Markdown note, if any.
+	Note string
 }
 
 func getHostDetails(host config.Host) (*HostDetails, error) {
@@ -414,12 +1160,18 @@ func getSSHConfigPath() string {
 }
 
 // RenderMenu displays an interactive menu for selecting SSH hosts and returns
-// the selected host alias or "exit" if the user chose to quit.
-func RenderMenu(hosts []config.Host) (string, error) {
-	program := tea.NewProgram(NewModel(hosts))
+// the user's choice. The choice has no hosts if the user chose to quit.
+func RenderMenu(hosts []config.Host, opts Options) (Choice, error) {
+	var programOpts []tea.ProgramOption
+	if opts.Mouse {
+		// Clicks are matched to hosts by screen position, which is only
+		// known on the alternate screen
+		programOpts = append(programOpts, tea.WithAltScreen(), tea.WithMouseCellMotion())
+	}
+	program := tea.NewProgram(NewModel(hosts, opts), programOpts...)
 	model, err := program.Run()
 	if err != nil {
-		return "", err
+		return Choice{}, err
 	}
 	menuModel := model.(Model)
 	return menuModel.choice, nil
@@ -1,11 +1,71 @@
 package menu
 
 import (
+	"strings"
 	"testing"
 
 	"github.com/antonjah/ssm/internal/config"
+	"github.com/antonjah/ssm/internal/keys"
+	"github.com/antonjah/ssm/internal/probe"
+	"github.com/antonjah/ssm/internal/theme"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
 )
 
+// defaultTheme returns the theme for views created outside a Model.
+func defaultTheme() theme.Theme {
+	t, _ := theme.Builtin(theme.Default)
+	return t
+}
+
+// defaultKeys returns the key bindings for views created outside a Model.
+func defaultKeys() keys.Map {
+	km, _ := keys.New(keys.Default, nil)
+	return km
+}
+
+// newTestModel returns a menu of hosts. Unless width is 0 it has been sized
+// to width by 30 cells, as it is once the program starts.
+func newTestModel(hosts []config.Host, opts Options, width int) tea.Model {
+	model := tea.Model(NewModel(hosts, opts))
+	if width > 0 {
+		model, _ = model.Update(tea.WindowSizeMsg{Width: width, Height: 30})
+	}
+	return model
+}
+
+// keyTypes are the keys sendKeys knows by name.
+var keyTypes = map[string]tea.KeyType{
+	"enter":     tea.KeyEnter,
+	"tab":       tea.KeyTab,
+	"esc":       tea.KeyEsc,
+	"backspace": tea.KeyBackspace,
+	"space":     tea.KeySpace,
+	"up":        tea.KeyUp,
+	"down":      tea.KeyDown,
+	"left":      tea.KeyLeft,
+	"right":     tea.KeyRight,
+	"ctrl+c":    tea.KeyCtrlC,
+	"ctrl+q":    tea.KeyCtrlQ,
+}
+
+// sendKeys sends each key to model and returns the command of the last one.
+// Keys are named as in keyTypes, and any other text is typed a character at
+// a time.
+func sendKeys(model tea.Model, keys ...string) (tea.Model, tea.Cmd) {
+	var cmd tea.Cmd
+	for _, k := range keys {
+		if keyType, ok := keyTypes[k]; ok {
+			model, cmd = model.Update(tea.KeyMsg{Type: keyType})
+			continue
+		}
+		for _, r := range k {
+			model, cmd = model.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{r}})
+		}
+	}
+	return model, cmd
+}
+
 func TestHostItem_FilterValue(t *testing.T) {
 	item := HostItem{host: config.Host{Alias: "test-host", HostName: "example.com"}}
 	if item.FilterValue() != "test-host" {
@@ -33,7 +93,7 @@ func TestNewModel(t *testing.T) {
 		{Alias: "host2", HostName: "server2.com"},
 		{Alias: "host3", HostName: "server3.com"},
 	}
-	model := NewModel(hosts)
+	model := NewModel(hosts, Options{})
 
 	// Check that the model has the correct number of items (just hosts, no exit)
 	expectedItems := len(hosts)
@@ -67,3 +127,137 @@ func TestNewModel(t *testing.T) {
 		t.Error("Expected filtering to be enabled")
 	}
 }
+
+func TestNewModel_Options(t *testing.T) {
+	hosts := []config.Host{{Alias: "host1", HostName: "server1.com"}}
+	model := newTestModel(hosts, Options{MaxHeight: 8, HideDescriptions: true}, 80)
+	if height := model.(Model).list.Height(); height != 8 {
+		t.Errorf("Expected the list to be 8 lines high, got %d", height)
+	}
+	if view := model.View(); strings.Contains(view, "server1.com") {
+		t.Errorf("Expected descriptions to be hidden, got %q", view)
+	}
+}
+
+func TestNewModel_Theme(t *testing.T) {
+	hosts := []config.Host{{Alias: "host1", HostName: "server1.com"}}
+	if model := NewModel(hosts, Options{}); model.opts.Theme.Name != theme.Default {
+		t.Errorf("Expected the %s theme by default, got %q", theme.Default, model.opts.Theme.Name)
+	}
+
+	mono, _ := theme.Builtin(theme.Mono)
+	model := NewModel(hosts, Options{Theme: mono})
+	if color := model.list.Styles.FilterPrompt.GetForeground(); color != (lipgloss.NoColor{}) {
+		t.Errorf("Expected no colours with the monochrome theme, got %v", color)
+	}
+}
+
+func TestHostFilter(t *testing.T) {
+	hosts := []config.Host{
+		{Alias: "web", HostName: "10.0.0.1", User: "deploy", Tags: []string{"prod"}},
+		{Alias: "db", HostName: "10.0.0.2", User: "postgres", Tags: []string{"prod", "legacy"}},
+		{Alias: "dev", HostName: "dev.example.com", User: "deploy"},
+	}
+	targets := []string{"web", "db", "dev"}
+	filter := hostFilter(hosts)
+
+	tests := []struct {
+		term     string
+		expected []int
+	}{
+		{"10.0.0.2", []int{1}},
+		{"user:deploy", []int{0, 2}},
+		{"tag:prod -tag:legacy", []int{0}},
+		{"example", []int{2}},
+	}
+
+	for _, test := range tests {
+		ranks := filter(test.term, targets)
+		if len(ranks) != len(test.expected) {
+			t.Errorf("Term %q: expected %d results, got %d", test.term, len(test.expected), len(ranks))
+			continue
+		}
+		for i, rank := range ranks {
+			if rank.Index != test.expected[i] {
+				t.Errorf("Term %q: expected result %d to be %d, got %d", test.term, i, test.expected[i], rank.Index)
+			}
+		}
+	}
+}
+
+func TestModel_MultiSelect(t *testing.T) {
+	hosts := []config.Host{
+		{Alias: "host1", HostName: "server1.com"},
+		{Alias: "host2", HostName: "server2.com"},
+		{Alias: "host3", HostName: "server3.com"},
+	}
+	model := newTestModel(hosts, Options{}, 0)
+
+	// Toggle host1, move down twice and toggle host3
+	model, _ = sendKeys(model, "space", "down", "down", "space", "t")
+
+	choice := model.(Model).choice
+	expected := []string{"host1", "host3"}
+	if len(choice.Hosts) != len(expected) {
+		t.Fatalf("Expected hosts %v, got %v", expected, choice.Hosts)
+	}
+	for i, host := range choice.Hosts {
+		if host != expected[i] {
+			t.Errorf("Expected hosts %v, got %v", expected, choice.Hosts)
+		}
+	}
+	if choice.Layout != LayoutTiled {
+		t.Errorf("Expected tiled layout, got %v", choice.Layout)
+	}
+}
+
+func TestModel_SelectAll(t *testing.T) {
+	hosts := []config.Host{
+		{Alias: "host1", HostName: "server1.com"},
+		{Alias: "host2", HostName: "server2.com"},
+	}
+	model := newTestModel(hosts, Options{}, 0)
+
+	model, _ = sendKeys(model, "*")
+	if got := len(model.(Model).chosenHosts()); got != 2 {
+		t.Errorf("Expected 2 chosen hosts, got %d", got)
+	}
+
+	// A second toggle clears the selection back to the highlighted host
+	model, _ = sendKeys(model, "*")
+	if got := model.(Model).chosenHosts(); len(got) != 1 || got[0] != "host1" {
+		t.Errorf("Expected only the highlighted host, got %v", got)
+	}
+}
+
+func TestModel_ProbeResults(t *testing.T) {
+	model := newTestModel([]config.Host{{Alias: "web", HostName: "10.0.0.1"}}, Options{}, 0)
+
+	events := make(chan probe.Result)
+	model, _ = model.Update(probeStartMsg{pending: []string{"web"}, events: events})
+	if status, ok := model.(Model).status["web"]; !ok || status.done {
+		t.Errorf("Expected web to be pending, got %+v", status)
+	}
+
+	model, cmd := model.Update(probeResultMsg{result: probe.Result{Alias: "web", Reachable: true}, events: events})
+	if status := model.(Model).status["web"]; !status.done || !status.result.Reachable {
+		t.Errorf("Expected web to be reachable, got %+v", status)
+	}
+	if cmd == nil {
+		t.Error("Expected the model to keep waiting for probe results")
+	}
+}
+
+func TestJumpRoute(t *testing.T) {
+	hosts := []config.Host{
+		{Alias: "bastion", HostName: "203.0.113.1"},
+		{Alias: "db", HostName: "10.0.1.1", ProxyJump: "bastion"},
+	}
+
+	if route := jumpRoute(hosts, hosts[0]); route != "" {
+		t.Errorf("Expected no route for a direct host, got %q", route)
+	}
+	if route := jumpRoute(hosts, hosts[1]); route != "local → bastion → db" {
+		t.Errorf("Expected route 'local → bastion → db', got %q", route)
+	}
+}
@@ -0,0 +1,147 @@
+package menu
+
+import (
+	"math"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// doubleClickTime is the longest time between two clicks on the same host
+// that counts as a double click.
+const doubleClickTime = 400 * time.Millisecond
+
+// click is a left click on a host in the list.
+type click struct {
+	index int
+	at    time.Time
+}
+
+// updateMouse handles mouse events in the host list and the details popup:
+// clicks select hosts, double clicks connect, the wheel scrolls, clicking a
+// table header sorts by its column, and clicking outside the popup closes it.
+func (m Model) updateMouse(msg tea.MouseMsg) (tea.Model, tea.Cmd) {
+	if msg.Action != tea.MouseActionPress {
+		return m, nil
+	}
+	if m.viewing {
+		if msg.Button == tea.MouseButtonLeft && !m.inPopup(msg.X, msg.Y) {
+			m.viewing = false
+			m.hostDetails = nil
+		}
+		return m, nil
+	}
+	// While the filter is typed the list belongs to the keyboard
+	if m.list.SettingFilter() {
+		return m, nil
+	}
+
+	switch msg.Button {
+	case tea.MouseButtonWheelUp:
+		m.list.CursorUp()
+	case tea.MouseButtonWheelDown:
+		m.list.CursorDown()
+	case tea.MouseButtonLeft:
+		if name, ok := m.headerAt(msg.X, msg.Y); ok {
+			if m.sort.column == name {
+				m.sort.reverse = !m.sort.reverse
+			} else {
+				m.sort.column, m.sort.reverse = name, false
+			}
+			return m, m.sortHosts()
+		}
+		index, ok := m.itemAt(msg.X, msg.Y)
+		if !ok {
+			m.lastClick = click{}
+			return m, nil
+		}
+		m.list.Select(index)
+		last := m.lastClick
+		if last.index == index && !last.at.IsZero() && time.Since(last.at) <= doubleClickTime {
+			m.lastClick = click{}
+			return m.connect(LayoutWindows)
+		}
+		m.lastClick = click{index: index, at: time.Now()}
+	}
+	return m, nil
+}
+
+// listTop returns the line of the screen the host list's first row is on.
+func (m Model) listTop() int {
+	top := docStyle.GetMarginTop()
+	if m.table {
+		return top + tableHeaderHeight
+	}
+	// The list draws an empty title line, as its title is hidden, and its
+	// status bar above the hosts
+	top++
+	if m.list.ShowStatusBar() {
+		top += lipgloss.Height(m.list.Styles.StatusBar.Render(""))
+	}
+	return top
+}
+
+// itemAt returns the index among the visible hosts of the host drawn at x
+// and y, if any.
+func (m Model) itemAt(x, y int) (int, bool) {
+	left := docStyle.GetMarginLeft()
+	if x < left || x >= left+m.list.Width() {
+		return 0, false
+	}
+	row := y - m.listTop()
+	if row < 0 {
+		return 0, false
+	}
+
+	height, spacing := m.rows.Height(), m.rows.Spacing()
+	if m.table {
+		height, spacing = m.columns.Height(), m.columns.Spacing()
+	}
+	if row%(height+spacing) >= height {
+		return 0, false
+	}
+	onPage := row / (height + spacing)
+	paginator := m.list.Paginator
+	if onPage >= paginator.ItemsOnPage(len(m.list.VisibleItems())) {
+		return 0, false
+	}
+	return paginator.Page*paginator.PerPage + onPage, true
+}
+
+// headerAt returns the name of the table column whose title is drawn at x
+// and y, if any.
+func (m Model) headerAt(x, y int) (string, bool) {
+	if !m.table || y != m.listTop()-1 {
+		return "", false
+	}
+	// Columns start after the margin and the gutter the cursor is drawn in
+	start := docStyle.GetMarginLeft() + 2
+	for i, name := range m.columns.columns {
+		end := start + m.columns.widths[i]
+		if x >= start && x < end && x < docStyle.GetMarginLeft()+m.list.Width() {
+			return name, true
+		}
+		start = end + len(tableGap)
+	}
+	return "", false
+}
+
+// inPopup reports whether x and y are inside the details popup's border.
+func (m Model) inPopup(x, y int) bool {
+	width, height := lipgloss.Size(m.popupView())
+	left := centred(m.width, width) + popupMargin.GetMarginLeft()
+	top := centred(m.height-3, height) + popupMargin.GetMarginTop()
+	return x >= left && x < left+width-popupMargin.GetHorizontalMargins() &&
+		y >= top && y < top+height-popupMargin.GetVerticalMargins()
+}
+
+// centred returns where something of the given size starts when centred in
+// space, the way lipgloss.Place centres it.
+func centred(space, size int) int {
+	gap := space - size
+	if gap <= 0 {
+		return 0
+	}
+	return gap - int(math.Round(float64(gap)*float64(lipgloss.Center)))
+}
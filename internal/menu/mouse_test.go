@@ -0,0 +1,105 @@
+package menu
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/antonjah/ssm/internal/config"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+var mouseHosts = []config.Host{{Alias: "web-1", HostName: "10.0.0.1"}, {Alias: "web-2", HostName: "10.0.0.2"}, {Alias: "db", HostName: "10.0.1.1"}}
+
+func clickAt(model tea.Model, x, y int) (tea.Model, tea.Cmd) {
+	return model.Update(tea.MouseMsg{X: x, Y: y, Button: tea.MouseButtonLeft, Action: tea.MouseActionPress})
+}
+
+// lineOf returns the line of the view that text is first drawn on.
+func lineOf(t *testing.T, model tea.Model, text string) int {
+	t.Helper()
+	for i, line := range strings.Split(model.View(), "\n") {
+		if strings.Contains(line, text) {
+			return i
+		}
+	}
+	t.Fatalf("Expected %q in the view", text)
+	return 0
+}
+
+func TestModel_MouseClick(t *testing.T) {
+	for _, table := range []bool{false, true} {
+		model := newTestModel(mouseHosts, Options{Table: table, Mouse: true}, 100)
+		y := lineOf(t, model, "web-2")
+
+		model, _ = clickAt(model, 5, y)
+		if index := model.(Model).list.Index(); index != 1 {
+			t.Errorf("Table %v: expected a click to select web-2, got index %d", table, index)
+		}
+		model, _ = clickAt(model, 5, y-1)
+		if table && model.(Model).list.Index() != 0 {
+			t.Errorf("Expected a click on the row above to select web-1, got index %d", model.(Model).list.Index())
+		}
+
+		// Clicks beside the list select nothing
+		model, _ = clickAt(model, 99, lineOf(t, model, "db"))
+		if model.(Model).list.Index() == 2 {
+			t.Errorf("Table %v: expected a click outside the list to be ignored", table)
+		}
+	}
+}
+
+func TestModel_MouseDoubleClick(t *testing.T) {
+	model := newTestModel(mouseHosts, Options{Table: true, Mouse: true}, 100)
+	y := lineOf(t, model, "db")
+	model, _ = clickAt(model, 5, y)
+	if model.(Model).done {
+		t.Fatal("Expected a single click not to connect")
+	}
+	model, _ = clickAt(model, 5, y)
+	if choice := model.(Model).choice; !model.(Model).done || len(choice.Hosts) != 1 || choice.Hosts[0] != "db" {
+		t.Errorf("Expected a double click to connect to db, got %+v", choice)
+	}
+}
+
+func TestModel_MouseWheel(t *testing.T) {
+	model := newTestModel(mouseHosts, Options{Mouse: true}, 100)
+	model, _ = model.Update(tea.MouseMsg{Button: tea.MouseButtonWheelDown, Action: tea.MouseActionPress})
+	model, _ = model.Update(tea.MouseMsg{Button: tea.MouseButtonWheelDown, Action: tea.MouseActionPress})
+	model, _ = model.Update(tea.MouseMsg{Button: tea.MouseButtonWheelUp, Action: tea.MouseActionPress})
+	if index := model.(Model).list.Index(); index != 1 {
+		t.Errorf("Expected the wheel to move the cursor to web-2, got index %d", index)
+	}
+}
+
+func TestModel_MouseHeader(t *testing.T) {
+	model := newTestModel(mouseHosts, Options{Table: true, Mouse: true}, 100)
+	y := lineOf(t, model, "HOSTNAME")
+	x := strings.Index(strings.Split(model.View(), "\n")[y], "HOSTNAME")
+
+	model, _ = clickAt(model, x+1, y)
+	if got := strings.Join(aliases(model), " "); got != "web-1 web-2 db" || model.(Model).sort.column != "hostname" {
+		t.Errorf("Expected a click on HOSTNAME to sort by it, got %s sorted by %q", got, model.(Model).sort.column)
+	}
+	model, _ = clickAt(model, x+1, y)
+	if got := strings.Join(aliases(model), " "); got != "db web-2 web-1" {
+		t.Errorf("Expected a second click to reverse the order, got %s", got)
+	}
+}
+
+func TestModel_MousePopup(t *testing.T) {
+	model := newTestModel(mouseHosts, Options{Mouse: true}, 100)
+	m := model.(Model)
+	m.viewing = true
+	m.hostDetails = &HostDetails{Alias: "web-1", HostName: "10.0.0.1"}
+	model = m
+
+	y := lineOf(t, model, "10.0.0.1")
+	model, _ = clickAt(model, 50, y)
+	if !model.(Model).viewing {
+		t.Fatal("Expected a click inside the popup to keep it open")
+	}
+	model, _ = clickAt(model, 1, 1)
+	if model.(Model).viewing {
+		t.Error("Expected a click outside the popup to close it")
+	}
+}
@@ -0,0 +1,178 @@
+package menu
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/antonjah/ssm/internal/config"
+	"github.com/antonjah/ssm/internal/theme"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// noteEditedMsg is sent when the editor opened on a host's note exits.
+type noteEditedMsg struct {
+	alias string
+	err   error
+}
+
+// editNote opens the highlighted host's note in the user's editor.
+func (m *Model) editNote() tea.Cmd {
+	item, ok := m.list.SelectedItem().(HostItem)
+	if !ok || m.opts.Notes == nil {
+		return nil
+	}
+	editor := getEditor()
+	if editor == "" {
+		return m.setNotice("Set $EDITOR to edit notes", true)
+	}
+	path, err := m.opts.Notes.Path(item.host)
+	if err != nil {
+		return m.setNotice(err.Error(), true)
+	}
+	alias := item.host.Alias
+	return m.editFile(editor, path, alias, func(err error) tea.Msg {
+		return noteEditedMsg{alias: alias, err: err}
+	})
+}
+
+// noteEdited reloads the note shown in the details popup after it was
+// edited.
+func (m *Model) noteEdited(msg noteEditedMsg) tea.Cmd {
+	if msg.err != nil {
+		return m.setNotice(fmt.Sprintf("Couldn't edit the note for %s: %v", msg.alias, msg.err), true)
+	}
+	if m.hostDetails != nil && m.hostDetails.Alias == msg.alias {
+		m.hostDetails.Note = m.readNote(config.Host{Alias: msg.alias})
+	}
+	return nil
+}
+
+// readNote returns host's note, or "" if it has none or notes are off.
+func (m Model) readNote(host config.Host) string {
+	if m.opts.Notes == nil {
+		return ""
+	}
+	note, err := m.opts.Notes.Read(host.Alias)
+	if err != nil {
+		return err.Error()
+	}
+	return note
+}
+
+// noteView renders a note for the details popup in at most height lines, or
+// any number if height is 0.
+func noteView(t theme.Theme, alias, note string, width, height int) string {
+	label := lipgloss.NewStyle().Foreground(t.Dim)
+	lines := strings.Split(renderMarkdown(t, note, width), "\n")
+	if height > 0 && len(lines) > height-1 {
+		more := label.Render(fmt.Sprintf("… %d more lines, see ssm note %s", len(lines)-max(height-2, 1), alias))
+		lines = append(lines[:max(height-2, 1)], more)
+	}
+	return label.Render("Notes") + "\n" + strings.Join(lines, "\n")
+}
+
+// Markdown syntax recognised in notes.
+var (
+	headingPattern = regexp.MustCompile(`^#{1,6}\s+`)
+	bulletPattern  = regexp.MustCompile(`^([-*+]|\d+[.)])\s+`)
+	codePattern    = regexp.MustCompile("`[^`]+`")
+	boldPattern    = regexp.MustCompile(`\*\*([^*]+)\*\*|__([^_]+)__`)
+	italicPattern  = regexp.MustCompile(`\*([^*\s][^*]*)\*`)
+	linkPattern    = regexp.MustCompile(`\[([^\]]+)\]\(([^)\s]+)\)`)
+)
+
+// renderMarkdown renders a note's Markdown for the terminal, wrapped to
+// width. Headings, lists, quotes, code and emphasis are styled; anything
+// else is shown as written.
+func renderMarkdown(t theme.Theme, text string, width int) string {
+	heading := lipgloss.NewStyle().Bold(true).Foreground(t.Accent)
+	code := lipgloss.NewStyle().Foreground(t.Highlight)
+	quote := lipgloss.NewStyle().Foreground(t.Muted)
+	border := lipgloss.NewStyle().Foreground(t.Border)
+
+	var lines []string
+	fenced := false
+	for _, line := range strings.Split(text, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if strings.HasPrefix(trimmed, "```") {
+			fenced = !fenced
+			continue
+		}
+		if fenced {
+			// Code keeps its layout, so it is cut off rather than wrapped
+			lines = append(lines, code.Render(truncate(line, width)))
+			continue
+		}
+
+		switch {
+		case headingPattern.MatchString(trimmed):
+			title := headingPattern.ReplaceAllString(trimmed, "")
+			lines = append(lines, heading.Width(width).Render(inlineMarkdown(t, title)))
+		case bulletPattern.MatchString(trimmed):
+			marker := bulletPattern.FindString(trimmed)
+			rest := strings.TrimPrefix(trimmed, marker)
+			if marker = strings.TrimSpace(marker); strings.ContainsAny(marker, "-*+") {
+				marker = "•"
+			}
+			indent := strings.Repeat(" ", (len(line)-len(strings.TrimLeft(line, " \t")))/2*2)
+			lines = append(lines, hanging(indent+marker+" ", inlineMarkdown(t, rest), width))
+		case strings.HasPrefix(trimmed, ">"):
+			rest := strings.TrimSpace(strings.TrimPrefix(trimmed, ">"))
+			lines = append(lines, hanging(border.Render("│ "), quote.Render(inlineMarkdown(t, rest)), width))
+		default:
+			lines = append(lines, lipgloss.NewStyle().Width(width).Render(inlineMarkdown(t, trimmed)))
+		}
+	}
+	return strings.TrimRight(strings.Join(lines, "\n"), "\n ")
+}
+
+// hanging wraps text to width after prefix, indenting the lines it wraps
+// onto to line up with the first.
+func hanging(prefix, text string, width int) string {
+	indent := lipgloss.Width(prefix)
+	wrapped := strings.Split(lipgloss.NewStyle().Width(max(width-indent, 1)).Render(text), "\n")
+	for i := range wrapped {
+		if i == 0 {
+			wrapped[i] = prefix + wrapped[i]
+		} else {
+			wrapped[i] = strings.Repeat(" ", indent) + wrapped[i]
+		}
+	}
+	return strings.Join(wrapped, "\n")
+}
+
+// inlineMarkdown styles code spans, bold and italic text and links. Code
+// spans are shown as written.
+func inlineMarkdown(t theme.Theme, text string) string {
+	code := lipgloss.NewStyle().Foreground(t.Highlight)
+	bold := lipgloss.NewStyle().Bold(true)
+	italic := lipgloss.NewStyle().Italic(true)
+	link := lipgloss.NewStyle().Foreground(t.Info).Underline(true)
+	dim := lipgloss.NewStyle().Foreground(t.Dim)
+
+	styleText := func(s string) string {
+		s = linkPattern.ReplaceAllStringFunc(s, func(match string) string {
+			parts := linkPattern.FindStringSubmatch(match)
+			return link.Render(parts[1]) + dim.Render(" <"+parts[2]+">")
+		})
+		s = boldPattern.ReplaceAllStringFunc(s, func(match string) string {
+			return bold.Render(match[2 : len(match)-2])
+		})
+		return italicPattern.ReplaceAllStringFunc(s, func(match string) string {
+			return italic.Render(match[1 : len(match)-1])
+		})
+	}
+
+	var b strings.Builder
+	last := 0
+	for _, span := range codePattern.FindAllStringIndex(text, -1) {
+		b.WriteString(styleText(text[last:span[0]]))
+		b.WriteString(code.Render(text[span[0]+1 : span[1]-1]))
+		last = span[1]
+	}
+	b.WriteString(styleText(text[last:]))
+	return b.String()
+}
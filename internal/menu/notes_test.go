@@ -0,0 +1,89 @@
+package menu
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/antonjah/ssm/internal/config"
+	"github.com/antonjah/ssm/internal/notes"
+	"github.com/antonjah/ssm/internal/theme"
+	"github.com/charmbracelet/lipgloss"
+)
+
+func TestRenderMarkdown(t *testing.T) {
+	colours, _ := theme.Builtin(theme.Default)
+	note := "# Runbook\n\nRestart with `systemctl restart app` and check **both** nodes.\n\n" +
+		"- See [the wiki](https://wiki.example.com/app)\n  - nested item\n1. first\n> careful with `prod`\n\n" +
+		"```\nsudo systemctl restart app --now\n```"
+	rendered := renderMarkdown(colours, note, 30)
+
+	for _, expected := range []string{"Runbook", "systemctl restart app", "• See the wiki", "<https://wiki.example.com", "  • nested item", "1. first", "│ careful with prod", "sudo systemctl restart app --…"} {
+		if !strings.Contains(rendered, expected) {
+			t.Errorf("Expected %q in the rendered note, got:\n%s", expected, rendered)
+		}
+	}
+	for _, syntax := range []string{"# ", "**", "`", "[the wiki]", "```"} {
+		if strings.Contains(rendered, syntax) {
+			t.Errorf("Expected %q to be rendered away, got:\n%s", syntax, rendered)
+		}
+	}
+	for _, line := range strings.Split(rendered, "\n") {
+		if width := lipgloss.Width(line); width > 30 {
+			t.Errorf("Expected lines at most 30 wide, got %d: %q", width, line)
+		}
+	}
+}
+
+var notesHosts = []config.Host{{Alias: "web", HostName: "10.0.0.1"}, {Alias: "db", HostName: "10.0.1.1"}}
+
+// newNotesStore returns an empty notes store.
+func newNotesStore(t *testing.T) *notes.Store {
+	t.Helper()
+	store, err := notes.Open(t.TempDir())
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	return store
+}
+
+func TestModel_NotePopup(t *testing.T) {
+	store := newNotesStore(t)
+	model := newTestModel(notesHosts, Options{Notes: store}, 100)
+	store.Write(config.Host{Alias: "web"}, "Restart with `systemctl restart app`")
+
+	m := model.(Model)
+	m.viewing = true
+	m.hostDetails = &HostDetails{Alias: "web", HostName: "10.0.0.1", Note: m.readNote(config.Host{Alias: "web"})}
+	view := m.View()
+	if !strings.Contains(view, "Notes") || !strings.Contains(view, "Restart with systemctl restart app") {
+		t.Errorf("Expected the note in the popup, got %q", view)
+	}
+
+	// An edit made elsewhere shows once the editor exits
+	store.Write(config.Host{Alias: "web"}, "Moved to the new cluster")
+	model, _ = m.Update(noteEditedMsg{alias: "web"})
+	if view := model.View(); !strings.Contains(view, "Moved to the new cluster") {
+		t.Errorf("Expected the popup to show the edited note, got %q", view)
+	}
+}
+
+func TestModel_NoteLong(t *testing.T) {
+	m := newTestModel(notesHosts, Options{Notes: newNotesStore(t)}, 100).(Model)
+	m.viewing = true
+	m.hostDetails = &HostDetails{Alias: "web", Note: strings.Repeat("line\n\n", 40)}
+	view := m.View()
+	if !strings.Contains(view, "more lines, see ssm note web") {
+		t.Errorf("Expected a long note to be cut off, got %q", view)
+	}
+	if height := lipgloss.Height(m.View()); height > 30 {
+		t.Errorf("Expected the popup to fit the terminal, got %d lines", height)
+	}
+}
+
+func TestModel_NoteWithoutEditor(t *testing.T) {
+	t.Setenv("EDITOR", "")
+	model, _ := sendKeys(newTestModel(notesHosts, Options{Notes: newNotesStore(t)}, 100), "n")
+	if view := model.View(); !strings.Contains(view, "Set $EDITOR to edit notes") {
+		t.Errorf("Expected a hint to set $EDITOR, got %q", view)
+	}
+}
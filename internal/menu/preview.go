@@ -0,0 +1,162 @@
+package menu
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/antonjah/ssm/internal/audit"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// Bounds on the preview pane's width, and the narrowest the host list may
+// get beside it before the pane is hidden.
+const (
+	minPreviewWidth     = 32
+	maxPreviewWidth     = 64
+	minPreviewListWidth = 40
+)
+
+// lastConnectMsg delivers each host's most recent connection from the audit
+// log.
+type lastConnectMsg map[string]audit.Entry
+
+// loadLastConnects returns a command that reads the latest connection to
+// each host from the audit log at p. An unreadable log leaves the preview
+// without last connections.
+func loadLastConnects(p string) tea.Cmd {
+	return func() tea.Msg {
+		entries, _ := audit.Read(p, audit.Filter{Action: audit.Connect})
+		last := make(lastConnectMsg)
+		for _, entry := range entries {
+			last[entry.Host] = entry
+		}
+		return last
+	}
+}
+
+// previewWidth returns the width of the preview pane, or 0 if it is hidden
+// or the terminal is too narrow for it and the list.
+func (m Model) previewWidth() int {
+	if !m.preview || m.width == 0 {
+		return 0
+	}
+	h, _ := docStyle.GetFrameSize()
+	available := m.width - h
+	width := min(max(available*2/5, minPreviewWidth), maxPreviewWidth)
+	if available-width < minPreviewListWidth {
+		return 0
+	}
+	return width
+}
+
+// previewView renders the highlighted host's details in a pane of the given
+// size.
+func (m Model) previewView(width, height int) string {
+	t := m.opts.Theme
+	style := lipgloss.NewStyle().
+		MarginLeft(1).
+		Padding(0, 1).
+		Border(lipgloss.RoundedBorder()).
+		BorderForeground(t.Border).
+		Foreground(t.Text).
+		Width(width - 3).
+		Height(max(height-2, 0)).
+		MaxHeight(height)
+
+	item, ok := m.list.SelectedItem().(HostItem)
+	if !ok {
+		return style.Render(lipgloss.NewStyle().Foreground(t.Dim).Render("No host selected"))
+	}
+	host := item.host
+
+	var b strings.Builder
+	b.WriteString(lipgloss.NewStyle().Foreground(t.Accent).Bold(true).Render(host.Alias) + "\n")
+	if host.Description != "" {
+		b.WriteString(lipgloss.NewStyle().Foreground(t.Muted).Render(host.Description) + "\n")
+	}
+	if m.protected[host.Alias] {
+		b.WriteString(lipgloss.NewStyle().Foreground(t.Error).Bold(true).Render(protectedBadge) + "\n")
+	}
+	b.WriteString("\n")
+
+	// Long values wrap beside their labels
+	label := lipgloss.NewStyle().Foreground(t.Muted).Width(10)
+	value := lipgloss.NewStyle().Width(max(style.GetWidth()-style.GetHorizontalPadding()-label.GetWidth(), 1))
+	row := func(name, text string) {
+		if text != "" {
+			b.WriteString(lipgloss.JoinHorizontal(lipgloss.Top, label.Render(name), value.Render(text)) + "\n")
+		}
+	}
+	row("Address", host.Address())
+	row("User", host.User)
+	row("Route", jumpRoute(m.hosts, host))
+	row("Proxy", host.ProxyCommand)
+	for _, file := range host.IdentityFiles {
+		row("Identity", file)
+	}
+	for _, f := range host.Forwards {
+		row("Forward", f.String())
+	}
+	row("Via", host.Via)
+	row("Tags", strings.Join(host.Tags, ", "))
+	row("Status", m.previewStatus(host.Alias))
+	row("Banner", m.status[host.Alias].result.Banner)
+	row("Last", m.previewLastConnect(host.Alias, time.Now()))
+
+	return style.Render(strings.TrimSuffix(b.String(), "\n"))
+}
+
+// previewStatus describes a host's reachability, or returns "" if it isn't
+// probed.
+func (m Model) previewStatus(alias string) string {
+	status, ok := m.status[alias]
+	t := m.opts.Theme
+	switch {
+	case !ok:
+		return ""
+	case status.skipped:
+		return lipgloss.NewStyle().Foreground(t.Dim).Render("not probed, behind a jump host")
+	case !status.done:
+		return lipgloss.NewStyle().Foreground(t.Dim).Render("probing…")
+	case status.result.Reachable:
+		return lipgloss.NewStyle().Foreground(t.Success).Render("reachable in " + formatLatency(status.result.Latency))
+	default:
+		text := "unreachable"
+		if status.result.Error != "" {
+			text += ": " + status.result.Error
+		}
+		return lipgloss.NewStyle().Foreground(t.Error).Render(text)
+	}
+}
+
+// previewLastConnect describes the most recent connection to a host from
+// the audit log, or returns "" if there is none.
+func (m Model) previewLastConnect(alias string, now time.Time) string {
+	entry, ok := m.lastConnect[alias]
+	if !ok {
+		return ""
+	}
+	text := fmt.Sprintf("%s (%s ago)", entry.Time.Local().Format("2006-01-02 15:04"), formatAge(now.Sub(entry.Time)))
+	if entry.Failed() {
+		text += lipgloss.NewStyle().Foreground(m.opts.Theme.Error).Render(" failed")
+	}
+	return text
+}
+
+// formatAge formats how long ago something happened in its largest unit,
+// e.g. "5m" or "3d".
+func formatAge(age time.Duration) string {
+	switch {
+	case age < time.Minute:
+		return "<1m"
+	case age < time.Hour:
+		return fmt.Sprintf("%dm", int(age.Minutes()))
+	case age < 24*time.Hour:
+		return fmt.Sprintf("%dh", int(age.Hours()))
+	default:
+		return fmt.Sprintf("%dd", int(age.Hours()/24))
+	}
+}
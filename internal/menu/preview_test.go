@@ -0,0 +1,98 @@
+package menu
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/antonjah/ssm/internal/audit"
+	"github.com/antonjah/ssm/internal/config"
+	"github.com/antonjah/ssm/internal/probe"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+var previewHosts = []config.Host{
+	{Alias: "web", HostName: "10.0.0.1", User: "deploy", Tags: []string{"prod", "web"}},
+	{Alias: "db", HostName: "10.0.0.2", Port: "2222", ProxyJump: "web"},
+}
+
+func TestModel_Preview(t *testing.T) {
+	model := newTestModel(previewHosts, Options{Preview: true}, 120)
+	if width := model.(Model).previewWidth(); width == 0 || model.(Model).list.Width()+width != 116 {
+		t.Fatalf("Expected the list and preview to share the width, got %d and %d", model.(Model).list.Width(), width)
+	}
+	view := model.View()
+	for _, expected := range []string{"10.0.0.1:22", "deploy", "prod, web"} {
+		if !strings.Contains(view, expected) {
+			t.Errorf("Expected the preview to show %q, got %q", expected, view)
+		}
+	}
+
+	// The preview follows the cursor
+	model, _ = sendKeys(model, "down")
+	view = model.View()
+	if !strings.Contains(view, "10.0.0.2:2222") || !strings.Contains(view, "Route") {
+		t.Errorf("Expected the preview to show db, got %q", view)
+	}
+
+	model, _ = sendKeys(model, "s")
+	if model.(Model).previewWidth() != 0 || model.(Model).list.Width() != 116 {
+		t.Errorf("Expected s to hide the preview, got a list width of %d", model.(Model).list.Width())
+	}
+	if strings.Contains(model.View(), "10.0.0.2:2222") {
+		t.Error("Expected no preview after hiding it")
+	}
+}
+
+func TestModel_Preview_Narrow(t *testing.T) {
+	model := newTestModel(previewHosts, Options{Preview: true}, 70)
+	if model.(Model).previewWidth() != 0 || model.(Model).list.Width() != 66 {
+		t.Errorf("Expected a single column on a narrow terminal, got a list width of %d", model.(Model).list.Width())
+	}
+
+	// Widening the terminal brings the preview back
+	model, _ = model.Update(tea.WindowSizeMsg{Width: 100, Height: 30})
+	if model.(Model).previewWidth() == 0 {
+		t.Error("Expected the preview on a wider terminal")
+	}
+}
+
+func TestModel_Preview_Status(t *testing.T) {
+	model := newTestModel(previewHosts, Options{Preview: true}, 120)
+	m := model.(Model)
+	m.status["web"] = reachability{done: true, result: probe.Result{Alias: "web", Reachable: true, Latency: 12 * time.Millisecond}}
+
+	now := time.Date(2026, 3, 14, 12, 0, 0, 0, time.UTC)
+	p := filepath.Join(t.TempDir(), "audit.log")
+	logger := audit.New(p, nil)
+	logger.Log(audit.Entry{Time: now.Add(-48 * time.Hour), Action: audit.Connect, Host: "web"})
+	logger.Log(audit.Entry{Time: now.Add(-3 * time.Hour), Action: audit.Connect, Host: "web", ExitCode: 255})
+	logger.Log(audit.Entry{Time: now.Add(-time.Hour), Action: audit.Exec, Host: "web"})
+	model, _ = m.Update(loadLastConnects(p)())
+	m = model.(Model)
+
+	if status := m.previewStatus("web"); !strings.Contains(status, "reachable in") {
+		t.Errorf("Expected web to be reachable, got %q", status)
+	}
+	if last := m.previewLastConnect("web", now); !strings.Contains(last, "(3h ago)") || !strings.Contains(last, "failed") {
+		t.Errorf("Expected the failed connection 3h ago, got %q", last)
+	}
+	if last := m.previewLastConnect("db", now); last != "" {
+		t.Errorf("Expected no last connection to db, got %q", last)
+	}
+}
+
+func TestFormatAge(t *testing.T) {
+	tests := map[time.Duration]string{
+		30 * time.Second: "<1m",
+		5 * time.Minute:  "5m",
+		90 * time.Minute: "1h",
+		50 * time.Hour:   "2d",
+	}
+	for age, expected := range tests {
+		if got := formatAge(age); got != expected {
+			t.Errorf("Expected %q for %v, got %q", expected, age, got)
+		}
+	}
+}
@@ -0,0 +1,139 @@
+package menu
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/antonjah/ssm/internal/config"
+	"github.com/antonjah/ssm/internal/probe"
+	"github.com/antonjah/ssm/internal/theme"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// reachability is the probe status of a single host.
+type reachability struct {
+	// skipped is set for hosts that are not probed because they are only
+	// reachable through a jump host.
+	skipped bool
+	// done is set once result holds a probe outcome.
+	done   bool
+	result probe.Result
+}
+
+// Messages delivering probe results to the model.
+type (
+	probeStartMsg struct {
+		cached  []probe.Result
+		pending []string
+		skipped []string
+		events  chan probe.Result
+	}
+	probeResultMsg struct {
+		result probe.Result
+		events chan probe.Result
+	}
+)
+
+// probeTargets splits hosts into those to probe and those to skip because
+// they are behind a jump host. Patterns name no single host, so they are
+// neither and get no status.
+func probeTargets(hosts []config.Host) ([]probe.Target, []string) {
+	var targets []probe.Target
+	var skipped []string
+	for _, host := range hosts {
+		switch {
+		case host.IsPattern():
+			continue
+		case host.Proxied():
+			skipped = append(skipped, host.Alias)
+			continue
+		}
+		targets = append(targets, probe.Target{Alias: host.Alias, Address: host.Address()})
+	}
+	return targets, skipped
+}
+
+// startProbes returns a command that loads cached results and probes the
+// remaining hosts in the background.
+func startProbes(hosts []config.Host, opts probe.Options) tea.Cmd {
+	return func() tea.Msg {
+		targets, skipped := probeTargets(hosts)
+
+		// Without a cache path every host is simply probed again
+		path, _ := probe.DefaultCachePath()
+		cache := probe.LoadCache(path, probe.DefaultCacheTTL)
+
+		var cached []probe.Result
+		var stale []probe.Target
+		var pending []string
+		for _, target := range targets {
+			if result, ok := cache.Get(target); ok {
+				cached = append(cached, result)
+			} else {
+				stale = append(stale, target)
+				pending = append(pending, target.Alias)
+			}
+		}
+
+		events := make(chan probe.Result, len(stale))
+		go func() {
+			probe.ProbeAll(context.Background(), stale, opts, func(result probe.Result) {
+				cache.Put(result)
+				events <- result
+			})
+			if len(stale) > 0 {
+				cache.Save()
+			}
+			close(events)
+		}()
+
+		return probeStartMsg{cached: cached, pending: pending, skipped: skipped, events: events}
+	}
+}
+
+// waitForProbe returns a command that delivers the next probe result.
+func waitForProbe(events chan probe.Result) tea.Cmd {
+	return func() tea.Msg {
+		result, ok := <-events
+		if !ok {
+			return nil
+		}
+		return probeResultMsg{result: result, events: events}
+	}
+}
+
+// statusBadge renders a host's reachability as a coloured dot and latency.
+// It returns "" for hosts that have no status yet.
+func statusBadge(t theme.Theme, status reachability, ok bool, textStyle lipgloss.Style) string {
+	if !ok {
+		return ""
+	}
+
+	dot := lipgloss.NewStyle()
+	var text string
+	switch {
+	case status.skipped:
+		dot = dot.Foreground(t.Dim)
+		return dot.Render("◌") + textStyle.Render(" via jump")
+	case !status.done:
+		return dot.Foreground(t.Dim).Render("○")
+	case status.result.Reachable:
+		dot = dot.Foreground(t.Success)
+		text = formatLatency(status.result.Latency)
+	default:
+		dot = dot.Foreground(t.Error)
+		text = "down"
+	}
+	return dot.Render("●") + textStyle.Render(" "+text)
+}
+
+// formatLatency formats a latency for display, e.g. "12ms" or "<1ms".
+func formatLatency(latency time.Duration) string {
+	if latency < time.Millisecond {
+		return "<1ms"
+	}
+	return fmt.Sprintf("%dms", latency.Milliseconds())
+}
@@ -0,0 +1,67 @@
+package menu
+
+import (
+	"testing"
+	"time"
+
+	"github.com/antonjah/ssm/internal/config"
+	"github.com/antonjah/ssm/internal/probe"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+func TestProbeTargets(t *testing.T) {
+	hosts := []config.Host{
+		{Alias: "web", HostName: "10.0.0.1", Port: "2222"},
+		{Alias: "internal", HostName: "10.1.0.1", ProxyJump: "bastion"},
+		{Alias: "*.example.com"},
+	}
+
+	targets, skipped := probeTargets(hosts)
+	if len(targets) != 1 || targets[0].Address != "10.0.0.1:2222" {
+		t.Errorf("Expected only web to be probed at 10.0.0.1:2222, got %+v", targets)
+	}
+	if len(skipped) != 1 || skipped[0] != "internal" {
+		t.Errorf("Expected only the host behind a jump host to be skipped, got %v", skipped)
+	}
+}
+
+func TestStatusBadge(t *testing.T) {
+	tests := []struct {
+		name     string
+		status   reachability
+		ok       bool
+		expected string
+	}{
+		{"no status", reachability{}, false, ""},
+		{"skipped", reachability{skipped: true}, true, "◌ via jump"},
+		{"pending", reachability{}, true, "○"},
+		{"reachable", reachability{done: true, result: probe.Result{Reachable: true, Latency: 12 * time.Millisecond}}, true, "● 12ms"},
+		{"down", reachability{done: true}, true, "● down"},
+	}
+	for _, test := range tests {
+		if got := statusBadge(defaultTheme(), test.status, test.ok, lipgloss.NewStyle()); got != test.expected {
+			t.Errorf("%s: expected %q, got %q", test.name, test.expected, got)
+		}
+	}
+}
+
+func TestModel_PatternHasNoStatus(t *testing.T) {
+	hosts := []config.Host{{Alias: "*.example.com"}, {Alias: "internal", ProxyJump: "bastion"}}
+	targets, skipped := probeTargets(hosts)
+	updated, _ := newTestModel(hosts, Options{}, 0).Update(probeStartMsg{skipped: skipped, events: make(chan probe.Result)})
+	if len(targets) != 0 {
+		t.Fatalf("Expected nothing to probe, got %+v", targets)
+	}
+
+	status := updated.(Model).status
+	if _, ok := status["*.example.com"]; ok {
+		t.Error("Expected the pattern to have no status")
+	}
+	if got := statusBadge(defaultTheme(), status["*.example.com"], false, lipgloss.NewStyle()); got != "" {
+		t.Errorf("Expected no badge for the pattern, got %q", got)
+	}
+	if !status["internal"].skipped {
+		t.Errorf("Expected the host behind a jump host to be skipped, got %+v", status["internal"])
+	}
+}
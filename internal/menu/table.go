@@ -0,0 +1,418 @@
+package menu
+
+import (
+	"cmp"
+	"fmt"
+	"io"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/antonjah/ssm/internal/audit"
+	"github.com/antonjah/ssm/internal/config"
+	"github.com/antonjah/ssm/internal/settings"
+
+	"github.com/charmbracelet/bubbles/key"
+	"github.com/charmbracelet/bubbles/list"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// tableGap separates the table's columns.
+const tableGap = "  "
+
+// tableHeaderHeight is the number of lines the table's title and header take
+// above the rows.
+const tableHeaderHeight = 2
+
+// column is a field of the table view.
+type column struct {
+	title string
+	// maxWidth caps the column's width; longer values are truncated.
+	maxWidth int
+	// text returns the cell's plain text.
+	text func(row tableRow) string
+	// compare orders two rows by the column, putting empty values last.
+	compare func(a, b tableRow) int
+}
+
+// tableRow is what the table knows about a host when rendering or sorting.
+type tableRow struct {
+	host   config.Host
+	status reachability
+	probed bool
+	last   audit.Entry
+	// used is set if the audit log has a connection to the host.
+	used bool
+}
+
+// columns maps the names in settings.TableColumns to their definitions.
+var columns = map[string]column{
+	"alias": {
+		title: "ALIAS", maxWidth: 32,
+		text:    func(r tableRow) string { return r.host.Alias },
+		compare: func(a, b tableRow) int { return compareText(a.host.Alias, b.host.Alias) },
+	},
+	"hostname": {
+		title: "HOSTNAME", maxWidth: 32,
+		text:    func(r tableRow) string { return r.host.HostName },
+		compare: func(a, b tableRow) int { return compareText(a.host.HostName, b.host.HostName) },
+	},
+	"user": {
+		title: "USER", maxWidth: 16,
+		text:    func(r tableRow) string { return r.host.User },
+		compare: func(a, b tableRow) int { return compareText(a.host.User, b.host.User) },
+	},
+	"port": {
+		title: "PORT", maxWidth: 5,
+		text:    func(r tableRow) string { return r.host.Port },
+		compare: func(a, b tableRow) int { return cmp.Compare(effectivePort(a.host), effectivePort(b.host)) },
+	},
+	"tags": {
+		title: "TAGS", maxWidth: 24,
+		text: func(r tableRow) string { return strings.Join(r.host.Tags, ",") },
+		compare: func(a, b tableRow) int {
+			return compareText(strings.Join(a.host.Tags, ","), strings.Join(b.host.Tags, ","))
+		},
+	},
+	"last": {
+		title: "LAST USED", maxWidth: 9,
+		text: func(r tableRow) string {
+			if !r.used {
+				return ""
+			}
+			return formatAge(time.Since(r.last.Time)) + " ago"
+		},
+		// Most recently used first
+		compare: func(a, b tableRow) int {
+			if a.used != b.used {
+				return compareMissing(a.used, b.used)
+			}
+			return b.last.Time.Compare(a.last.Time)
+		},
+	},
+	"status": {
+		title: "STATUS", maxWidth: 8,
+		text: func(r tableRow) string {
+			switch {
+			case !r.probed:
+				return ""
+			case r.status.skipped:
+				return "◌ jump"
+			case !r.status.done:
+				return "○"
+			case r.status.result.Reachable:
+				return "● " + formatLatency(r.status.result.Latency)
+			default:
+				return "● down"
+			}
+		},
+		// Fastest first, then hosts that are down, then those not probed
+		compare: func(a, b tableRow) int { return cmp.Compare(statusRank(a), statusRank(b)) },
+	},
+}
+
+// compareText orders strings case-insensitively with empty strings last.
+func compareText(a, b string) int {
+	if (a == "") != (b == "") {
+		return compareMissing(a != "", b != "")
+	}
+	return cmp.Compare(strings.ToLower(a), strings.ToLower(b))
+}
+
+// compareMissing puts present values before missing ones.
+func compareMissing(a, b bool) int {
+	switch {
+	case a == b:
+		return 0
+	case a:
+		return -1
+	default:
+		return 1
+	}
+}
+
+// effectivePort returns the port ssh connects to as a number.
+func effectivePort(host config.Host) int {
+	port, err := strconv.Atoi(host.EffectivePort())
+	if err != nil {
+		return 22
+	}
+	return port
+}
+
+// statusRank orders hosts by reachability: reachable hosts by latency, then
+// unreachable, pending and unprobed ones.
+func statusRank(r tableRow) time.Duration {
+	const never = time.Duration(1<<63 - 1)
+	switch {
+	case r.probed && r.status.done && r.status.result.Reachable:
+		return r.status.result.Latency
+	case r.probed && r.status.done:
+		return never - 2
+	case r.probed && !r.status.skipped:
+		return never - 1
+	default:
+		return never
+	}
+}
+
+// hostSort is the table's ordering. It is shared between the Model and the
+// list's filter so that filtered hosts keep the order.
+type hostSort struct {
+	// column is the name of the sorted column, or "" for the SSH config's
+	// order.
+	column  string
+	reverse bool
+}
+
+// tableDelegate renders each host as a single row of columns.
+type tableDelegate struct {
+	customDelegate
+	columns []string
+	widths  []int
+	sort    *hostSort
+	// lastConnect is shared with the Model and holds each host's most
+	// recent connection.
+	lastConnect map[string]audit.Entry
+}
+
+func newTableDelegate(d customDelegate, hosts []config.Host, names []string, sorting *hostSort, lastConnect map[string]audit.Entry) tableDelegate {
+	t := tableDelegate{customDelegate: d, columns: names, sort: sorting, lastConnect: lastConnect}
+	for _, name := range names {
+		c := columns[name]
+		width := lipgloss.Width(c.title) + 2
+		for _, host := range hosts {
+			text := c.text(tableRow{host: host})
+			if name == "alias" {
+				text += " " + selectedMarker
+			}
+			width = max(width, lipgloss.Width(text))
+		}
+		t.widths = append(t.widths, min(width, max(c.maxWidth, lipgloss.Width(c.title)+2)))
+	}
+	return t
+}
+
+func (d tableDelegate) Height() int {
+	return 1
+}
+
+func (d tableDelegate) Spacing() int {
+	return 0
+}
+
+func (d tableDelegate) Update(msg tea.Msg, m *list.Model) tea.Cmd {
+	return nil
+}
+
+// row gathers what the table shows for host.
+func (d tableDelegate) row(host config.Host) tableRow {
+	status, probed := d.status[host.Alias]
+	last, used := d.lastConnect[host.Alias]
+	return tableRow{host: host, status: status, probed: probed, last: last, used: used}
+}
+
+func (d tableDelegate) Render(w io.Writer, m list.Model, index int, item list.Item) {
+	hostItem, ok := item.(HostItem)
+	if !ok {
+		return
+	}
+	t := d.theme
+	row := d.row(hostItem.host)
+	current := index == m.Index() && m.FilterState() != list.Filtering
+
+	text := lipgloss.NewStyle().Foreground(t.Text)
+	switch {
+	case m.FilterState() == list.Filtering && m.FilterValue() == "":
+		text = text.Foreground(t.Dim)
+	case d.protected[row.host.Alias]:
+		text = text.Foreground(t.Error)
+	case current:
+		text = text.Foreground(t.Accent)
+	case d.selected[row.host.Alias]:
+		text = text.Foreground(t.Success)
+	}
+
+	cells := make([]string, len(d.columns))
+	for i, name := range d.columns {
+		value := truncate(columns[name].text(row), d.widths[i])
+		cell := text
+		switch name {
+		case "alias":
+			if d.selected[row.host.Alias] {
+				value = truncate(row.host.Alias, d.widths[i]-2) + " " + selectedMarker
+			}
+			// Highlight the characters the filter matched, as the list does
+			if matches := m.MatchesForItem(index); len(matches) > 0 && m.FilterState() != list.Unfiltered {
+				matched := text.Inherit(d.defaultDelegate.Styles.FilterMatch)
+				cells[i] = lipgloss.StyleRunes(value, matches, matched, text) + strings.Repeat(" ", max(d.widths[i]-lipgloss.Width(value), 0))
+				continue
+			}
+		case "status":
+			if dot, rest, ok := strings.Cut(value, " "); ok {
+				cells[i] = d.statusStyle(row).Render(dot) + text.Render(" "+rest+strings.Repeat(" ", max(d.widths[i]-lipgloss.Width(value), 0)))
+				continue
+			}
+			cell = cell.Foreground(t.Dim)
+		case "hostname", "user", "port", "tags", "last":
+			if !current && text.GetForeground() == t.Text {
+				cell = cell.Foreground(t.Subtext)
+			}
+		}
+		cells[i] = cell.Width(d.widths[i]).Render(value)
+	}
+
+	gutter := "  "
+	if current {
+		gutter = lipgloss.NewStyle().Foreground(t.Accent).Render("│ ")
+	}
+	line := gutter + strings.Join(cells, tableGap)
+	fmt.Fprint(w, lipgloss.NewStyle().MaxWidth(m.Width()).Render(line))
+}
+
+// statusStyle colours the status column's dot.
+func (d tableDelegate) statusStyle(r tableRow) lipgloss.Style {
+	style := lipgloss.NewStyle()
+	switch {
+	case r.status.skipped || !r.status.done:
+		return style.Foreground(d.theme.Dim)
+	case r.status.result.Reachable:
+		return style.Foreground(d.theme.Success)
+	default:
+		return style.Foreground(d.theme.Error)
+	}
+}
+
+// header renders the column titles, marking the sorted column.
+func (d tableDelegate) header(width int) string {
+	titles := make([]string, len(d.columns))
+	for i, name := range d.columns {
+		title := columns[name].title
+		if name == d.sort.column {
+			if d.sort.reverse {
+				title += " ▼"
+			} else {
+				title += " ▲"
+			}
+		}
+		titles[i] = lipgloss.NewStyle().Width(d.widths[i]).Render(truncate(title, d.widths[i]))
+	}
+	style := lipgloss.NewStyle().Foreground(d.theme.Muted).Bold(true)
+	return style.MaxWidth(width).Render("  " + strings.Join(titles, tableGap))
+}
+
+func (d tableDelegate) ShortHelp() []key.Binding {
+	return []key.Binding{d.keys.Connect, d.keys.Toggle, d.keys.Sort, d.keys.Table}
+}
+
+func (d tableDelegate) FullHelp() [][]key.Binding {
+	help := d.customDelegate.FullHelp()
+	help[0] = append(help[0], d.keys.Sort, d.keys.SortReverse)
+	return help
+}
+
+// validColumns returns the known column names among names, or every column
+// if there are none.
+func validColumns(names []string) []string {
+	var valid []string
+	for _, name := range names {
+		if _, ok := columns[name]; ok {
+			valid = append(valid, name)
+		}
+	}
+	if len(valid) == 0 {
+		return settings.TableColumns
+	}
+	return valid
+}
+
+// nextColumn returns the column to sort by after current: the next one in
+// names, or "" for the SSH config's order after the last.
+func nextColumn(names []string, current string) string {
+	i := slices.Index(names, current)
+	if i+1 < len(names) {
+		return names[i+1]
+	}
+	return ""
+}
+
+// sortedFilter keeps the hosts that filter matches in the table's order
+// rather than ranking them, while a column is sorted.
+func sortedFilter(filter list.FilterFunc, sorting *hostSort) list.FilterFunc {
+	return func(term string, targets []string) []list.Rank {
+		ranks := filter(term, targets)
+		if sorting.column != "" {
+			slices.SortStableFunc(ranks, func(a, b list.Rank) int {
+				return cmp.Compare(a.Index, b.Index)
+			})
+		}
+		return ranks
+	}
+}
+
+// setTable switches between the table and list views.
+func (m *Model) setTable(table bool) {
+	m.table = table
+	if table {
+		m.list.SetDelegate(m.columns)
+	} else {
+		m.list.SetDelegate(m.rows)
+	}
+	// The table draws its own title, filter and count above its header
+	m.list.SetShowStatusBar(!table)
+	m.list.SetShowFilter(!table)
+	m.resize()
+}
+
+// sortHosts orders the hosts by the sorted column, keeping the highlighted
+// host highlighted.
+func (m *Model) sortHosts() tea.Cmd {
+	var current string
+	if item, ok := m.list.SelectedItem().(HostItem); ok {
+		current = item.host.Alias
+	}
+
+	items := make([]list.Item, len(m.hosts))
+	for i, host := range m.hosts {
+		items[i] = HostItem{host: host}
+	}
+	if c, ok := columns[m.sort.column]; ok {
+		slices.SortStableFunc(items, func(a, b list.Item) int {
+			order := c.compare(m.columns.row(a.(HostItem).host), m.columns.row(b.(HostItem).host))
+			if m.sort.reverse {
+				return -order
+			}
+			return order
+		})
+	}
+	cmd := m.list.SetItems(items)
+	index := slices.IndexFunc(items, func(item list.Item) bool {
+		return item.(HostItem).host.Alias == current
+	})
+	if index >= 0 && m.list.FilterState() == list.Unfiltered {
+		m.list.Select(index)
+	}
+	return cmd
+}
+
+// tableTitle renders the line above the table's header: the filter while it
+// is typed, otherwise the number of hosts shown.
+func (m Model) tableTitle() string {
+	if m.list.SettingFilter() {
+		return m.list.FilterInput.View()
+	}
+	if notice := m.noticeView(); notice != "" {
+		return notice
+	}
+	style := lipgloss.NewStyle().Foreground(m.opts.Theme.Dim)
+	total := len(m.list.Items())
+	if m.list.FilterState() == list.FilterApplied {
+		return style.Render(fmt.Sprintf("%q • %d of %d hosts", m.list.FilterValue(), len(m.list.VisibleItems()), total))
+	}
+	if total == 1 {
+		return style.Render("1 host")
+	}
+	return style.Render(fmt.Sprintf("%d hosts", total))
+}
@@ -0,0 +1,126 @@
+package menu
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/antonjah/ssm/internal/audit"
+	"github.com/antonjah/ssm/internal/config"
+	"github.com/antonjah/ssm/internal/probe"
+	"github.com/antonjah/ssm/internal/settings"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+var tableHosts = []config.Host{
+	{Alias: "web-2", HostName: "10.0.0.2", Port: "2222"},
+	{Alias: "db", HostName: "10.0.1.1", User: "postgres", Tags: []string{"prod"}},
+	{Alias: "web-1", HostName: "10.0.0.1", Port: "8022"},
+}
+
+// aliases returns the aliases in the order the list shows them.
+func aliases(model tea.Model) []string {
+	var names []string
+	for _, item := range model.(Model).list.VisibleItems() {
+		names = append(names, item.(HostItem).host.Alias)
+	}
+	return names
+}
+
+func TestModel_Table(t *testing.T) {
+	model := newTestModel(tableHosts, Options{Table: true}, 100)
+	view := model.View()
+	if !strings.Contains(view, "3 hosts") || !strings.Contains(view, "ALIAS") || !strings.Contains(view, "STATUS") {
+		t.Errorf("Expected a title and header, got %q", view)
+	}
+	for _, line := range strings.Split(view, "\n") {
+		if strings.Contains(line, "db") && !strings.Contains(line, "postgres") {
+			t.Errorf("Expected db on a single row with its user, got %q", line)
+		}
+	}
+
+	model, _ = sendKeys(model, "T")
+	if model.(Model).table || strings.Contains(model.View(), "HOSTNAME") {
+		t.Error("Expected T to switch back to the list")
+	}
+}
+
+func TestModel_TableColumns(t *testing.T) {
+	view := newTestModel(tableHosts, Options{Table: true, Columns: []string{"alias", "port"}}, 100).View()
+	if !strings.Contains(view, "PORT") || strings.Contains(view, "HOSTNAME") {
+		t.Errorf("Expected only the alias and port columns, got %q", view)
+	}
+	if columns := validColumns([]string{"nope"}); len(columns) != len(settings.TableColumns) {
+		t.Errorf("Expected every column without valid ones, got %v", columns)
+	}
+	for _, name := range settings.TableColumns {
+		if _, ok := columns[name]; !ok {
+			t.Errorf("Expected a definition for column %q", name)
+		}
+	}
+}
+
+func TestModel_TableSort(t *testing.T) {
+	model := newTestModel(tableHosts, Options{Table: true, Columns: []string{"alias", "port"}}, 100)
+	model, _ = sendKeys(model, "down", "o")
+	if got := strings.Join(aliases(model), " "); got != "db web-1 web-2" {
+		t.Errorf("Expected the hosts sorted by alias, got %s", got)
+	}
+	if item := model.(Model).list.SelectedItem().(HostItem); item.host.Alias != "db" {
+		t.Errorf("Expected db to stay highlighted, got %s", item.host.Alias)
+	}
+	if !strings.Contains(model.View(), "ALIAS ▲") {
+		t.Error("Expected the header to mark the sorted column")
+	}
+
+	model, _ = sendKeys(model, "o")
+	if got := strings.Join(aliases(model), " "); got != "db web-2 web-1" {
+		t.Errorf("Expected the hosts sorted by port with 22 for db, got %s", got)
+	}
+	model, _ = sendKeys(model, "O")
+	if got := strings.Join(aliases(model), " "); got != "web-1 web-2 db" {
+		t.Errorf("Expected the hosts in reverse port order, got %s", got)
+	}
+
+	// After the last column the SSH config's order comes back
+	model, _ = sendKeys(model, "o")
+	if got := strings.Join(aliases(model), " "); got != "web-2 db web-1" {
+		t.Errorf("Expected the config order, got %s", got)
+	}
+}
+
+func TestModel_TableSortStatus(t *testing.T) {
+	model := newTestModel(tableHosts, Options{Table: true, Columns: []string{"alias", "last", "status"}}, 100)
+	m := model.(Model)
+	m.status["web-2"] = reachability{done: true, result: probe.Result{Reachable: true, Latency: 40 * time.Millisecond}}
+	m.status["web-1"] = reachability{done: true, result: probe.Result{Reachable: true, Latency: 5 * time.Millisecond}}
+	m.status["db"] = reachability{done: true}
+	model, _ = m.Update(lastConnectMsg{"db": audit.Entry{Time: time.Now()}, "web-1": audit.Entry{Time: time.Now().Add(-time.Hour)}})
+
+	model, _ = sendKeys(model, "o", "o")
+	if got := strings.Join(aliases(model), " "); got != "db web-1 web-2" {
+		t.Errorf("Expected the most recently used first, got %s", got)
+	}
+	if view := model.View(); !strings.Contains(view, "1h ago") {
+		t.Errorf("Expected the last use to be shown, got %q", view)
+	}
+	model, _ = sendKeys(model, "o")
+	if got := strings.Join(aliases(model), " "); got != "web-1 web-2 db" {
+		t.Errorf("Expected the fastest first and the host that is down last, got %s", got)
+	}
+}
+
+func TestSortedFilter(t *testing.T) {
+	hosts := []config.Host{{Alias: "web"}, {Alias: "my-web-box"}, {Alias: "db"}}
+	targets := []string{"my-web-box", "db", "web"}
+	sorting := &hostSort{}
+	filter := sortedFilter(hostFilter(hosts), sorting)
+
+	if ranks := filter("web", targets); len(ranks) != 2 || ranks[0].Index != 2 {
+		t.Errorf("Expected the best match first without a sort, got %+v", ranks)
+	}
+	sorting.column = "alias"
+	if ranks := filter("web", targets); len(ranks) != 2 || ranks[0].Index != 0 {
+		t.Errorf("Expected the table's order while sorted, got %+v", ranks)
+	}
+}
@@ -0,0 +1,380 @@
+package menu
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/antonjah/ssm/internal/audit"
+	"github.com/antonjah/ssm/internal/config"
+	"github.com/antonjah/ssm/internal/keys"
+	"github.com/antonjah/ssm/internal/theme"
+	"github.com/antonjah/ssm/internal/transfer"
+
+	"github.com/charmbracelet/bubbles/key"
+	"github.com/charmbracelet/bubbles/progress"
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// Fields of the transfer form, in display order.
+const (
+	transferDirection = iota
+	transferTool
+	transferLocal
+	transferRemote
+	transferFields
+)
+
+// Messages sent from a running transfer. Each carries its event channel so
+// the view keeps listening until the transfer finishes.
+type (
+	transferProgressMsg struct {
+		progress transfer.Progress
+		events   chan tea.Msg
+	}
+	transferDoneMsg struct{ err error }
+)
+
+// transferView copies files between the local machine and a host. It asks
+// for the direction, tool and both paths, then shows the tool's progress.
+type transferView struct {
+	host     config.Host
+	field    int
+	upload   bool
+	tool     int
+	local    textinput.Model
+	remote   textinput.Model
+	choices  []string
+	running  bool
+	finished bool
+	percent  float64
+	line     string
+	err      error
+	cancel   context.CancelFunc
+	bar      progress.Model
+	// toolPath overrides the transfer tool's binary in tests.
+	toolPath string
+	// audit logs transfers, if set.
+	audit *audit.Logger
+	theme theme.Theme
+	keys  keys.Map
+}
+
+// transferKeyMap provides key bindings for the transfer form.
+type transferKeyMap struct {
+	keys keys.Map
+}
+
+func (k transferKeyMap) ShortHelp() []key.Binding {
+	return []key.Binding{
+		withHelp(k.keys.Up, "field"), withHelp(k.keys.Down, "field"), k.keys.PreviousOption, k.keys.NextOption,
+		k.keys.Complete, withHelp(k.keys.Select, "copy"), k.keys.Back,
+	}
+}
+
+func (k transferKeyMap) FullHelp() [][]key.Binding {
+	return [][]key.Binding{k.ShortHelp()}
+}
+
+// newTransferView creates a transfer form for host, starting as an upload.
+func newTransferView(t theme.Theme, km keys.Map, host config.Host) *transferView {
+	newInput := func(placeholder string) textinput.Model {
+		input := textinput.New()
+		input.Prompt = ""
+		input.Placeholder = placeholder
+		input.Width = 50
+		return input
+	}
+	v := &transferView{
+		host:   host,
+		upload: true,
+		local:  newInput("./file"),
+		remote: newInput("~ (home directory)"),
+		bar:    progress.New(progress.WithSolidFill(theme.Hex(t.Accent))),
+		theme:  t,
+		keys:   km,
+	}
+	v.bar.Width = 50
+	return v
+}
+
+// focus moves to field, focusing its text input if it has one.
+func (v *transferView) focus(field int) tea.Cmd {
+	v.field = (field + transferFields) % transferFields
+	v.choices = nil
+	v.local.Blur()
+	v.remote.Blur()
+	switch v.field {
+	case transferLocal:
+		return v.local.Focus()
+	case transferRemote:
+		return v.remote.Focus()
+	}
+	return nil
+}
+
+// transfer returns the transfer described by the form.
+func (v *transferView) transfer() (transfer.Transfer, error) {
+	localPath := strings.TrimSpace(v.local.Value())
+	if localPath == "" {
+		return transfer.Transfer{}, fmt.Errorf("enter a local path")
+	}
+	local := transfer.Endpoint{Path: expandHome(localPath)}
+	remote := transfer.Endpoint{Host: v.host.Alias, Path: strings.TrimSpace(v.remote.Value())}
+	if v.upload {
+		return transfer.New(transfer.Tools[v.tool], local, remote)
+	}
+	return transfer.New(transfer.Tools[v.tool], remote, local)
+}
+
+// start runs the transfer and returns the command that delivers its progress.
+func (v *transferView) start() tea.Cmd {
+	t, err := v.transfer()
+	if err != nil {
+		v.err = err
+		return nil
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	v.cancel = cancel
+	v.running, v.finished = true, false
+	v.percent, v.line, v.err = 0, "", nil
+
+	events := make(chan tea.Msg, 64)
+	opts := transfer.Options{
+		Path: v.toolPath,
+		OnProgress: func(p transfer.Progress) {
+			events <- transferProgressMsg{progress: p, events: events}
+		},
+	}
+	go func() {
+		entry := audit.CopyEntry(t)
+		start := time.Now()
+		err := transfer.Run(ctx, t, opts)
+		entry.Finish(start, 0, err)
+		v.audit.Log(entry)
+		events <- transferDoneMsg{err: err}
+		close(events)
+	}()
+	return waitForTransfer(events)
+}
+
+// waitForTransfer returns a command that delivers the next transfer event.
+func waitForTransfer(events chan tea.Msg) tea.Cmd {
+	return func() tea.Msg {
+		msg, ok := <-events
+		if !ok {
+			return nil
+		}
+		return msg
+	}
+}
+
+// stop cancels a running transfer.
+func (v *transferView) stop() {
+	if v.cancel != nil {
+		v.cancel()
+	}
+}
+
+// update handles input for the transfer view. It returns false when the user
+// leaves the view.
+func (v *transferView) update(msg tea.Msg) (bool, tea.Cmd) {
+	switch msg := msg.(type) {
+	case transferProgressMsg:
+		if msg.progress.Percent >= 0 {
+			v.percent = float64(msg.progress.Percent) / 100
+		}
+		v.line = msg.progress.Line
+		return true, waitForTransfer(msg.events)
+	case transferDoneMsg:
+		v.running, v.finished = false, true
+		v.err = msg.err
+		if msg.err == nil {
+			v.percent = 1
+		}
+		return true, nil
+	case tea.KeyMsg:
+		if v.running {
+			if key.Matches(msg, v.keys.Back) {
+				v.stop()
+			}
+			return true, nil
+		}
+		// Keys that type text go to the path being typed
+		if (v.field != transferLocal && v.field != transferRemote) || !typed(msg) {
+			if stay, cmd, handled := v.handleKey(msg); handled {
+				return stay, cmd
+			}
+		}
+	}
+
+	var cmd tea.Cmd
+	switch v.field {
+	case transferLocal:
+		v.local, cmd = v.local.Update(msg)
+		v.choices = nil
+	case transferRemote:
+		v.remote, cmd = v.remote.Update(msg)
+	}
+	return true, cmd
+}
+
+// handleKey handles a key bound to one of the form's actions, and reports
+// whether it was.
+func (v *transferView) handleKey(msg tea.KeyMsg) (bool, tea.Cmd, bool) {
+	switch {
+	case key.Matches(msg, v.keys.Back):
+		return false, nil, true
+	case key.Matches(msg, v.keys.Select):
+		return true, v.start(), true
+	case key.Matches(msg, v.keys.Up):
+		return true, v.focus(v.field - 1), true
+	case key.Matches(msg, v.keys.Down):
+		return true, v.focus(v.field + 1), true
+	case key.Matches(msg, v.keys.Complete):
+		if v.field == transferLocal {
+			v.complete()
+			return true, nil, true
+		}
+		return true, v.focus(v.field + 1), true
+	case key.Matches(msg, v.keys.PreviousOption, v.keys.NextOption):
+		step := 1
+		if key.Matches(msg, v.keys.PreviousOption) {
+			step = -1
+		}
+		switch v.field {
+		case transferDirection:
+			v.upload = !v.upload
+			return true, nil, true
+		case transferTool:
+			v.tool = (v.tool + step + len(transfer.Tools)) % len(transfer.Tools)
+			return true, nil, true
+		}
+	}
+	// Other keys, such as the arrows in a path, go to the paths
+	return true, nil, false
+}
+
+// complete completes the local path and lists the candidates when it is
+// ambiguous.
+func (v *transferView) complete() {
+	completed, choices := completeLocalPath(v.local.Value())
+	v.local.SetValue(completed)
+	v.local.CursorEnd()
+	v.choices = choices
+}
+
+// completeLocalPath completes the last element of a local path. A single
+// match is completed fully, with a trailing slash for directories; several
+// matches are completed to their common prefix and returned as candidates.
+func completeLocalPath(input string) (string, []string) {
+	dir, base := filepath.Split(input)
+	readDir := expandHome(dir)
+	if readDir == "" {
+		readDir = "."
+	}
+	entries, err := os.ReadDir(readDir)
+	if err != nil {
+		return input, nil
+	}
+
+	var matches []string
+	for _, entry := range entries {
+		name := entry.Name()
+		if !strings.HasPrefix(name, base) || (strings.HasPrefix(name, ".") && !strings.HasPrefix(base, ".")) {
+			continue
+		}
+		if entry.IsDir() {
+			name += "/"
+		} else if info, err := os.Stat(filepath.Join(readDir, name)); err == nil && info.IsDir() {
+			// Symlinks to directories complete like directories
+			name += "/"
+		}
+		matches = append(matches, name)
+	}
+	sort.Strings(matches)
+
+	switch len(matches) {
+	case 0:
+		return input, nil
+	case 1:
+		return dir + matches[0], nil
+	}
+	prefix := matches[0]
+	for _, match := range matches[1:] {
+		for !strings.HasPrefix(match, prefix) {
+			prefix = prefix[:len(prefix)-1]
+		}
+	}
+	return dir + prefix, matches
+}
+
+// expandHome expands a leading "~" to the user's home directory.
+func expandHome(path string) string {
+	if path != "~" && !strings.HasPrefix(path, "~/") {
+		return path
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return path
+	}
+	return filepath.Join(home, path[1:])
+}
+
+// view renders the transfer form and progress.
+func (v *transferView) view() string {
+	header := lipgloss.NewStyle().Bold(true).Foreground(v.theme.Accent)
+	label := lipgloss.NewStyle().Width(11).Foreground(v.theme.Dim)
+	active := label.Foreground(v.theme.Accent).Bold(v.theme.Monochrome)
+	dim := lipgloss.NewStyle().Foreground(v.theme.Dim)
+
+	direction := "upload   local → " + v.host.Alias
+	if !v.upload {
+		direction = "download " + v.host.Alias + " → local"
+	}
+	rows := []struct {
+		name  string
+		value string
+	}{
+		{"direction", "‹ " + direction + " ›"},
+		{"tool", "‹ " + string(transfer.Tools[v.tool]) + " ›"},
+		{"local", v.local.View()},
+		{"remote", v.remote.View()},
+	}
+
+	var b strings.Builder
+	b.WriteString(header.Render("Copy files with "+v.host.Alias) + "\n\n")
+	for i, row := range rows {
+		style := label
+		if i == v.field && !v.running {
+			style = active
+		}
+		b.WriteString(style.Render(row.name) + row.value + "\n")
+		if i == transferLocal && len(v.choices) > 0 {
+			b.WriteString(label.Render("") + dim.Render(strings.Join(v.choices, "  ")) + "\n")
+		}
+	}
+
+	if v.running || v.finished {
+		b.WriteString("\n" + v.bar.ViewAs(v.percent) + "\n")
+		if v.line != "" {
+			b.WriteString(dim.Render(v.line) + "\n")
+		}
+	}
+	switch {
+	case v.running:
+		b.WriteString(dim.Render(fmt.Sprintf("copying... %s to cancel", v.keys.Back.Help().Key)) + "\n")
+	case v.err != nil:
+		b.WriteString("\n" + lipgloss.NewStyle().Foreground(v.theme.Error).Render(v.err.Error()) + "\n")
+	case v.finished:
+		b.WriteString(lipgloss.NewStyle().Foreground(v.theme.Success).Render("done") + "\n")
+	}
+	return b.String()
+}
@@ -0,0 +1,91 @@
+package menu
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/antonjah/ssm/internal/config"
+	"github.com/antonjah/ssm/internal/transfer"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+func TestCompleteLocalPath(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"report.csv", "report.pdf", "notes.txt", ".hidden"} {
+		os.WriteFile(filepath.Join(dir, name), nil, 0644)
+	}
+	os.Mkdir(filepath.Join(dir, "logs"), 0755)
+
+	tests := []struct {
+		input    string
+		expected string
+		choices  []string
+	}{
+		{dir + "/no", dir + "/notes.txt", nil},
+		{dir + "/lo", dir + "/logs/", nil},
+		{dir + "/re", dir + "/report.", []string{"report.csv", "report.pdf"}},
+		{dir + "/missing", dir + "/missing", nil},
+		{dir + "/.h", dir + "/.hidden", nil},
+	}
+	for _, test := range tests {
+		completed, choices := completeLocalPath(test.input)
+		if completed != test.expected || !reflect.DeepEqual(choices, test.choices) {
+			t.Errorf("completeLocalPath(%q): expected %q %v, got %q %v", test.input, test.expected, test.choices, completed, choices)
+		}
+	}
+}
+
+func TestTransferView(t *testing.T) {
+	tool := filepath.Join(t.TempDir(), "rsync")
+	script := "#!/bin/sh\necho \"$@\" > \"$(dirname \"$0\")/args\"\nprintf '  512 50%%\\r 1024 100%%\\n'\n"
+	if err := os.WriteFile(tool, []byte(script), 0755); err != nil {
+		t.Fatalf("Failed to write fake rsync: %v", err)
+	}
+
+	v := newTransferView(defaultTheme(), defaultKeys(), config.Host{Alias: "web"})
+	v.toolPath = tool
+	v.focus(transferDirection)
+	v.update(tea.KeyMsg{Type: tea.KeyRight})
+	if v.upload {
+		t.Fatal("Expected right to switch to download")
+	}
+	v.focus(transferTool)
+	v.update(tea.KeyMsg{Type: tea.KeyRight})
+	if transfer.Tools[v.tool] != transfer.Rsync {
+		t.Fatalf("Expected rsync, got %s", transfer.Tools[v.tool])
+	}
+
+	// A local path is required
+	if _, cmd := v.update(tea.KeyMsg{Type: tea.KeyEnter}); cmd != nil || v.err == nil {
+		t.Fatal("Expected an error without a local path")
+	}
+
+	v.local.SetValue("/tmp/x.log")
+	v.remote.SetValue("/var/log/x.log")
+	_, cmd := v.update(tea.KeyMsg{Type: tea.KeyEnter})
+	var percents []float64
+	for cmd != nil {
+		msg := cmd()
+		if msg == nil {
+			break
+		}
+		_, cmd = v.update(msg)
+		if _, ok := msg.(transferProgressMsg); ok {
+			percents = append(percents, v.percent)
+		}
+	}
+
+	if !v.finished || v.err != nil {
+		t.Fatalf("Expected the transfer to finish, got err %v", v.err)
+	}
+	if !reflect.DeepEqual(percents, []float64{0.5, 1}) {
+		t.Errorf("Expected progress 0.5, 1, got %v", percents)
+	}
+	args, _ := os.ReadFile(filepath.Join(filepath.Dir(tool), "args"))
+	if !strings.HasSuffix(strings.TrimSpace(string(args)), "web:/var/log/x.log /tmp/x.log") {
+		t.Errorf("Expected a download from web, got args %q", args)
+	}
+}
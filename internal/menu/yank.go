@@ -0,0 +1,166 @@
+package menu
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/antonjah/ssm/internal/clipboard"
+	"github.com/antonjah/ssm/internal/config"
+	"github.com/antonjah/ssm/internal/keys"
+	"github.com/antonjah/ssm/internal/theme"
+
+	"github.com/charmbracelet/bubbles/key"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// copyToClipboard copies text and returns the methods used. Tests replace it
+// to keep escape sequences off the terminal.
+var copyToClipboard = clipboard.Copy
+
+// noticeLifetime is how long a notice stays in the list's title line.
+const noticeLifetime = 3 * time.Second
+
+// formatLabels names the config.Formats in the copy menu.
+var formatLabels = map[string]string{
+	config.FormatCommand:     "ssh command",
+	config.FormatDestination: "user@hostname",
+	config.FormatHostName:    "hostname",
+	config.FormatBlock:       "config block",
+}
+
+// Messages sent when copying finishes and when its notice expires.
+type (
+	copiedMsg struct {
+		alias   string
+		format  string
+		methods []string
+		err     error
+	}
+	clearNoticeMsg struct{ id int }
+)
+
+// notice is a short message shown in place of the list's title.
+type notice struct {
+	id     int
+	text   string
+	failed bool
+}
+
+// yankView asks how to copy a host to the clipboard: as an ssh command, its
+// destination, its HostName or its config block.
+type yankView struct {
+	host   config.Host
+	texts  []string
+	cursor int
+	theme  theme.Theme
+	keys   keys.Map
+}
+
+// yankKeyMap provides key bindings for the copy menu.
+type yankKeyMap struct {
+	keys keys.Map
+}
+
+func (k yankKeyMap) ShortHelp() []key.Binding {
+	return []key.Binding{
+		k.keys.Up, k.keys.Down,
+		key.NewBinding(key.WithKeys("1", "2", "3", "4"), key.WithHelp("1-4", "copy")),
+		withHelp(k.keys.Select, "copy"), k.keys.Back,
+	}
+}
+
+func (k yankKeyMap) FullHelp() [][]key.Binding {
+	return [][]key.Binding{k.ShortHelp()}
+}
+
+// newYankView creates a copy menu for host.
+func newYankView(t theme.Theme, km keys.Map, host config.Host) *yankView {
+	texts := make([]string, len(config.Formats))
+	for i, format := range config.Formats {
+		texts[i], _ = host.Format(format)
+	}
+	return &yankView{host: host, texts: texts, theme: t, keys: km}
+}
+
+// update handles input for the copy menu. It returns false when the menu
+// closes, with a command that copies the chosen format if one was picked.
+func (v *yankView) update(msg tea.KeyMsg) (bool, tea.Cmd) {
+	switch {
+	case key.Matches(msg, v.keys.Back, v.keys.Quit):
+		return false, nil
+	case key.Matches(msg, v.keys.Up):
+		v.cursor = max(v.cursor-1, 0)
+	case key.Matches(msg, v.keys.Down):
+		v.cursor = min(v.cursor+1, len(config.Formats)-1)
+	case key.Matches(msg, v.keys.Select):
+		return false, v.copy(v.cursor)
+	default:
+		if n, err := strconv.Atoi(msg.String()); err == nil && n >= 1 && n <= len(config.Formats) {
+			return false, v.copy(n - 1)
+		}
+	}
+	return true, nil
+}
+
+// copy returns a command that copies the format at index i.
+func (v *yankView) copy(i int) tea.Cmd {
+	alias, format, text := v.host.Alias, config.Formats[i], v.texts[i]
+	return func() tea.Msg {
+		methods, err := copyToClipboard(text)
+		return copiedMsg{alias: alias, format: format, methods: methods, err: err}
+	}
+}
+
+// view renders the copy menu with the highlighted format in full below it.
+func (v *yankView) view() string {
+	header := lipgloss.NewStyle().Bold(true).Foreground(v.theme.Accent)
+	dim := lipgloss.NewStyle().Foreground(v.theme.Dim)
+	highlight := lipgloss.NewStyle().Foreground(v.theme.Accent)
+
+	var b strings.Builder
+	b.WriteString(header.Render("Copy "+v.host.Alias+" to the clipboard") + "\n\n")
+	for i, format := range config.Formats {
+		first, _, _ := strings.Cut(v.texts[i], "\n")
+		line := fmt.Sprintf("%d  %-14s %s", i+1, formatLabels[format], first)
+		if i == v.cursor {
+			b.WriteString(highlight.Render("> "+line) + "\n")
+		} else {
+			b.WriteString("  " + line + "\n")
+		}
+	}
+	if strings.Contains(v.texts[v.cursor], "\n") {
+		b.WriteString("\n" + dim.Render(strings.TrimRight(v.texts[v.cursor], "\n")) + "\n")
+	}
+	return b.String()
+}
+
+// setNotice shows text in place of the list's title and returns a command
+// that clears it again.
+func (m *Model) setNotice(text string, failed bool) tea.Cmd {
+	id := m.notice.id + 1
+	m.notice = notice{id: id, text: text, failed: failed}
+	return tea.Tick(noticeLifetime, func(time.Time) tea.Msg { return clearNoticeMsg{id: id} })
+}
+
+// copiedNotice describes the outcome of copying a host.
+func copiedNotice(msg copiedMsg) (string, bool) {
+	if msg.err != nil {
+		return fmt.Sprintf("Couldn't copy %s: %v", msg.alias, msg.err), true
+	}
+	return fmt.Sprintf("Copied %s's %s (%s)", msg.alias, formatLabels[msg.format], strings.Join(msg.methods, ", ")), false
+}
+
+// noticeView renders the current notice, or "" if there is none.
+func (m Model) noticeView() string {
+	if m.notice.text == "" {
+		return ""
+	}
+	style := lipgloss.NewStyle().Foreground(m.opts.Theme.Success)
+	if m.notice.failed {
+		style = style.Foreground(m.opts.Theme.Error)
+	}
+	return style.Render(truncate(m.notice.text, max(m.list.Width(), 1)))
+}
@@ -0,0 +1,105 @@
+package menu
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/antonjah/ssm/internal/config"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// fakeClipboard replaces the clipboard for a test and returns what was
+// copied.
+func fakeClipboard(t *testing.T, err error) *[]string {
+	t.Helper()
+	var copied []string
+	original := copyToClipboard
+	copyToClipboard = func(text string) ([]string, error) {
+		copied = append(copied, text)
+		if err != nil {
+			return nil, err
+		}
+		return []string{"OSC 52"}, nil
+	}
+	t.Cleanup(func() { copyToClipboard = original })
+	return &copied
+}
+
+var yankHosts = []config.Host{{Alias: "web", HostName: "10.0.0.1", User: "deploy", Port: "2222"}, {Alias: "db", HostName: "10.0.1.1"}}
+
+// runCmd runs cmd and feeds its message to the model.
+func runCmd(model tea.Model, cmd tea.Cmd) (tea.Model, tea.Cmd) {
+	if cmd == nil {
+		return model, nil
+	}
+	return model.Update(cmd())
+}
+
+func TestModel_Yank(t *testing.T) {
+	for _, table := range []bool{false, true} {
+		copied := fakeClipboard(t, nil)
+		model, _ := sendKeys(newTestModel(yankHosts, Options{Table: table}, 100), "y")
+		view := model.View()
+		if model.(Model).yank == nil || !strings.Contains(view, "ssh -p 2222 deploy@10.0.0.1") {
+			t.Fatalf("Expected the copy menu with the ssh command, got %q", view)
+		}
+
+		model, cmd := sendKeys(model, "2")
+		if model.(Model).yank != nil {
+			t.Error("Expected the copy menu to close")
+		}
+		model, _ = runCmd(model, cmd)
+		if len(*copied) != 1 || (*copied)[0] != "deploy@10.0.0.1" {
+			t.Errorf("Expected deploy@10.0.0.1 to be copied, got %q", *copied)
+		}
+		if view := model.View(); !strings.Contains(view, "Copied web's user@hostname (OSC 52)") {
+			t.Errorf("Table %v: expected a notice, got %q", table, view)
+		}
+		if lineOf(t, model, "10.0.1.1") != lineOf(t, newTestModel(yankHosts, Options{Table: table}, 100), "10.0.1.1") {
+			t.Errorf("Table %v: expected the notice not to move the hosts", table)
+		}
+
+		// The notice clears after a while, unless a newer one replaced it
+		id := model.(Model).notice.id
+		model, _ = model.Update(clearNoticeMsg{id: id - 1})
+		if model.(Model).notice.text == "" {
+			t.Error("Expected an older notice's timer to leave the notice")
+		}
+		model, _ = model.Update(clearNoticeMsg{id: id})
+		if strings.Contains(model.View(), "Copied") {
+			t.Error("Expected the notice to clear")
+		}
+	}
+}
+
+func TestModel_YankBlock(t *testing.T) {
+	copied := fakeClipboard(t, nil)
+	model, _ := sendKeys(newTestModel(yankHosts, Options{}, 100), "y", "down", "down", "down")
+	if view := model.View(); !strings.Contains(view, "HostName 10.0.0.1") {
+		t.Errorf("Expected the whole config block to be shown, got %q", view)
+	}
+	runCmd(sendKeys(model, "enter"))
+	if len(*copied) != 1 || !strings.HasPrefix((*copied)[0], "Host web\n") {
+		t.Errorf("Expected the config block to be copied, got %q", *copied)
+	}
+}
+
+func TestModel_YankCancel(t *testing.T) {
+	copied := fakeClipboard(t, nil)
+	model, cmd := sendKeys(newTestModel(yankHosts, Options{}, 100), "y", "esc")
+	if model.(Model).yank != nil || cmd != nil || model.(Model).done {
+		t.Error("Expected esc to close the copy menu and stay in the list")
+	}
+	if len(*copied) != 0 {
+		t.Errorf("Expected nothing copied, got %q", *copied)
+	}
+}
+
+func TestModel_YankFails(t *testing.T) {
+	fakeClipboard(t, errors.New("no terminal or clipboard tool to copy with"))
+	model, _ := runCmd(sendKeys(newTestModel(yankHosts, Options{}, 100), "y", "enter"))
+	if view := model.View(); !strings.Contains(view, "Couldn't copy web") {
+		t.Errorf("Expected the error to be shown, got %q", view)
+	}
+}
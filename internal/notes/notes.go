@@ -0,0 +1,291 @@
+// Package notes keeps free-form Markdown notes about hosts in ssm's data
+// directory. Each note is stored under a random ID rather than its host's
+// alias, and an index remembers which host the ID belongs to, so that a note
+// follows its host when the alias is renamed in the SSH config.
+package notes
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"syscall"
+
+	"github.com/antonjah/ssm/internal/config"
+	"github.com/antonjah/ssm/internal/xdg"
+)
+
+// Extension is the file extension of notes.
+const Extension = ".md"
+
+// indexFile maps note IDs to their hosts inside the notes directory.
+const indexFile = "index.json"
+
+// DefaultDir returns the directory notes are kept in unless ssm's settings
+// pick another: notes in ssm's data directory.
+func DefaultDir() (string, error) {
+	dir, err := xdg.DataDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "notes"), nil
+}
+
+// entry is what the index knows about the host a note belongs to.
+type entry struct {
+	Alias string `json:"alias"`
+	// Identity is where the host points, used to find it again after its
+	// alias is renamed.
+	Identity string `json:"identity,omitempty"`
+}
+
+// Rename is a note moved to a host's new alias.
+type Rename struct {
+	From string
+	To   string
+}
+
+// Store is a directory of notes and the index of their hosts.
+type Store struct {
+	dir   string
+	index map[string]entry
+}
+
+// Open opens the notes in dir. A missing directory is an empty store; it is
+// created when the first note is added.
+func Open(dir string) (*Store, error) {
+	index, err := load(dir)
+	if err != nil {
+		return nil, err
+	}
+	return &Store{dir: dir, index: index}, nil
+}
+
+// load reads the index in dir. A missing index is an empty one.
+func load(dir string) (map[string]entry, error) {
+	index := make(map[string]entry)
+	data, err := os.ReadFile(filepath.Join(dir, indexFile))
+	if errors.Is(err, os.ErrNotExist) {
+		return index, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read notes index: %w", err)
+	}
+	if err := json.Unmarshal(data, &index); err != nil {
+		return nil, fmt.Errorf("failed to parse notes index %s: %w", filepath.Join(dir, indexFile), err)
+	}
+	if index == nil {
+		index = make(map[string]entry)
+	}
+	return index, nil
+}
+
+// identity describes where host points: "user@hostname:port". Hosts without
+// a HostName have none, as their address is their alias.
+func identity(host config.Host) string {
+	if host.HostName == "" {
+		return ""
+	}
+	return host.User + "@" + host.Address()
+}
+
+// Sync matches the notes to hosts from the SSH config. A note whose alias is
+// gone moves to the one host without a note that points at the same place,
+// which is how a renamed host keeps its note. The renames are returned, and
+// the index is saved if anything changed.
+func (s *Store) Sync(hosts []config.Host) ([]Rename, error) {
+	byAlias := make(map[string]config.Host, len(hosts))
+	for _, host := range hosts {
+		byAlias[host.Alias] = host
+	}
+
+	var renames []Rename
+	err := s.update(func(index map[string]entry) bool {
+		renames = nil
+		changed := false
+		noted := make(map[string]bool, len(index))
+		for id, e := range index {
+			if host, ok := byAlias[e.Alias]; ok {
+				noted[e.Alias] = true
+				if current := identity(host); current != "" && current != e.Identity {
+					index[id] = entry{Alias: e.Alias, Identity: current}
+					changed = true
+				}
+			}
+		}
+
+		for _, id := range slices.Sorted(maps.Keys(index)) {
+			e := index[id]
+			if _, ok := byAlias[e.Alias]; ok || e.Identity == "" {
+				continue
+			}
+			var candidates []string
+			for _, host := range hosts {
+				if !noted[host.Alias] && identity(host) == e.Identity {
+					candidates = append(candidates, host.Alias)
+				}
+			}
+			// Several hosts pointing at the same place can't be told apart
+			if len(candidates) != 1 {
+				continue
+			}
+			index[id] = entry{Alias: candidates[0], Identity: e.Identity}
+			noted[candidates[0]] = true
+			renames = append(renames, Rename{From: e.Alias, To: candidates[0]})
+			changed = true
+		}
+		return changed
+	})
+	if err != nil {
+		return nil, err
+	}
+	return renames, nil
+}
+
+// id returns the ID of alias's note in index.
+func id(index map[string]entry, alias string) (string, bool) {
+	for id, e := range index {
+		if e.Alias == alias {
+			return id, true
+		}
+	}
+	return "", false
+}
+
+// path returns the file of the note with the given ID.
+func (s *Store) path(id string) string {
+	return filepath.Join(s.dir, id+Extension)
+}
+
+// Read returns alias's note, or "" if it has none.
+func (s *Store) Read(alias string) (string, error) {
+	id, ok := id(s.index, alias)
+	if !ok {
+		return "", nil
+	}
+	data, err := os.ReadFile(s.path(id))
+	if errors.Is(err, os.ErrNotExist) {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to read note for %s: %w", alias, err)
+	}
+	return strings.TrimSpace(string(data)), nil
+}
+
+// Aliases returns the aliases that have a note, sorted.
+func (s *Store) Aliases() []string {
+	var aliases []string
+	for id, e := range s.index {
+		if info, err := os.Stat(s.path(id)); err == nil && info.Size() > 0 {
+			aliases = append(aliases, e.Alias)
+		}
+	}
+	slices.Sort(aliases)
+	return aliases
+}
+
+// Path returns the file host's note is kept in, for editing. The host is
+// given an ID and the directory is created if it has no note yet; the file
+// itself is left for the editor to create.
+func (s *Store) Path(host config.Host) (string, error) {
+	if id, ok := id(s.index, host.Alias); ok {
+		return s.path(id), nil
+	}
+	fresh, err := newID()
+	if err != nil {
+		return "", err
+	}
+	// Another ssm may have given the host an ID since the index was read
+	noteID := fresh
+	err = s.update(func(index map[string]entry) bool {
+		if existing, ok := id(index, host.Alias); ok {
+			noteID = existing
+			return false
+		}
+		index[fresh] = entry{Alias: host.Alias, Identity: identity(host)}
+		return true
+	})
+	if err != nil {
+		return "", err
+	}
+	return s.path(noteID), nil
+}
+
+// Write replaces host's note with text.
+func (s *Store) Write(host config.Host, text string) error {
+	path, err := s.Path(host)
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(path, []byte(text), 0o600); err != nil {
+		return fmt.Errorf("failed to write note for %s: %w", host.Alias, err)
+	}
+	return nil
+}
+
+// update reads the index under an exclusive lock, applies fn and saves the
+// result if fn reports a change, so that concurrent ssm instances don't undo
+// each other's changes. The store's index is replaced by the one read.
+func (s *Store) update(fn func(index map[string]entry) bool) error {
+	if err := os.MkdirAll(s.dir, 0o700); err != nil {
+		return fmt.Errorf("failed to create notes directory: %w", err)
+	}
+	lock, err := os.OpenFile(filepath.Join(s.dir, "index.lock"), os.O_CREATE|os.O_RDWR, 0o600)
+	if err != nil {
+		return fmt.Errorf("failed to lock notes index: %w", err)
+	}
+	defer lock.Close()
+	if err := syscall.Flock(int(lock.Fd()), syscall.LOCK_EX); err != nil {
+		return fmt.Errorf("failed to lock notes index: %w", err)
+	}
+
+	index, err := load(s.dir)
+	if err != nil {
+		return err
+	}
+	if fn(index) {
+		if err := save(s.dir, index); err != nil {
+			return err
+		}
+	}
+	s.index = index
+	return nil
+}
+
+// save writes index to dir. It is written to a temporary file first so that
+// readers never see a partially written index.
+func save(dir string, index map[string]entry) error {
+	data, err := json.MarshalIndent(index, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(dir, "index-*.json")
+	if err != nil {
+		return fmt.Errorf("failed to write notes index: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write notes index: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write notes index: %w", err)
+	}
+	return os.Rename(tmp.Name(), filepath.Join(dir, indexFile))
+}
+
+// newID returns a random note ID.
+func newID() (string, error) {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate note ID: %w", err)
+	}
+	return hex.EncodeToString(b), nil
+}
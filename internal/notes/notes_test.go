@@ -0,0 +1,122 @@
+package notes
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/antonjah/ssm/internal/config"
+)
+
+func TestStore(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "notes")
+	store, err := Open(dir)
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	web := config.Host{Alias: "web", HostName: "10.0.0.1", User: "deploy"}
+	if note, err := store.Read("web"); err != nil || note != "" {
+		t.Errorf("Expected no note, got %q (%v)", note, err)
+	}
+	if err := store.Write(web, "# Web\n\nRestart with `systemctl restart app`\n"); err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+
+	// A second store sees the note through the index
+	store, err = Open(dir)
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	if note, _ := store.Read("web"); note != "# Web\n\nRestart with `systemctl restart app`" {
+		t.Errorf("Expected the note back, got %q", note)
+	}
+	if aliases := store.Aliases(); !reflect.DeepEqual(aliases, []string{"web"}) {
+		t.Errorf("Expected [web], got %v", aliases)
+	}
+	path, _ := store.Path(web)
+	if filepath.Dir(path) != dir || filepath.Ext(path) != Extension || filepath.Base(path) == "web"+Extension {
+		t.Errorf("Expected the note stored under an ID in %s, got %s", dir, path)
+	}
+
+	// A host without a note gets a path but isn't listed until it is written
+	if _, err := store.Path(config.Host{Alias: "db"}); err != nil {
+		t.Fatalf("Path failed: %v", err)
+	}
+	if aliases := store.Aliases(); len(aliases) != 1 {
+		t.Errorf("Expected only web to have a note, got %v", aliases)
+	}
+}
+
+func TestStore_Sync(t *testing.T) {
+	dir := t.TempDir()
+	store, _ := Open(dir)
+	store.Write(config.Host{Alias: "web", HostName: "10.0.0.1", User: "deploy"}, "web note")
+	store.Write(config.Host{Alias: "db", HostName: "10.0.1.1"}, "db note")
+	store.Write(config.Host{Alias: "local"}, "local note")
+
+	// web is renamed, db now has two hosts pointing at it, and local, which
+	// has no HostName, is renamed too
+	hosts := []config.Host{
+		{Alias: "web-1", HostName: "10.0.0.1", User: "deploy"},
+		{Alias: "db-a", HostName: "10.0.1.1"},
+		{Alias: "db-b", HostName: "10.0.1.1"},
+		{Alias: "local-2"},
+	}
+	renames, err := store.Sync(hosts)
+	if err != nil {
+		t.Fatalf("Sync failed: %v", err)
+	}
+	if expected := []Rename{{From: "web", To: "web-1"}}; !reflect.DeepEqual(renames, expected) {
+		t.Errorf("Expected renames %v, got %v", expected, renames)
+	}
+
+	store, _ = Open(dir)
+	if note, _ := store.Read("web-1"); note != "web note" {
+		t.Errorf("Expected the note to follow the rename, got %q", note)
+	}
+	for _, alias := range []string{"db-a", "db-b", "local-2"} {
+		if note, _ := store.Read(alias); note != "" {
+			t.Errorf("Expected no note for %s, got %q", alias, note)
+		}
+	}
+
+	// Changing where a host points keeps its note and is remembered
+	hosts[0].HostName = "10.0.0.9"
+	store.Sync(hosts)
+	hosts[0].Alias = "web-2"
+	if renames, _ := store.Sync(hosts); len(renames) != 1 || renames[0].To != "web-2" {
+		t.Errorf("Expected the note to follow the new address, got %v", renames)
+	}
+}
+
+func TestOpen_Corrupt(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, indexFile), []byte("{"), 0o600)
+	if _, err := Open(dir); err == nil {
+		t.Error("Expected an error for a corrupt index")
+	}
+}
+
+func TestStore_Concurrent(t *testing.T) {
+	dir := t.TempDir()
+	first, _ := Open(dir)
+	second, _ := Open(dir)
+
+	// Both stores were opened before either added a note, so each has to pick
+	// up the other's changes rather than overwrite them
+	first.Write(config.Host{Alias: "web"}, "web note")
+	second.Write(config.Host{Alias: "db"}, "db note")
+	second.Write(config.Host{Alias: "web"}, "web note from second")
+
+	store, _ := Open(dir)
+	if aliases := store.Aliases(); !reflect.DeepEqual(aliases, []string{"db", "web"}) {
+		t.Errorf("Expected [db web], got %v", aliases)
+	}
+	if note, _ := store.Read("web"); note != "web note from second" {
+		t.Errorf("Expected both stores to share web's note, got %q", note)
+	}
+	if entries, _ := filepath.Glob(filepath.Join(dir, "index-*")); len(entries) != 0 {
+		t.Errorf("Expected no temporary files left, got %v", entries)
+	}
+}
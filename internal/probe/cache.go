@@ -0,0 +1,113 @@
+package probe
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"syscall"
+	"time"
+
+	"github.com/antonjah/ssm/internal/xdg"
+)
+
+// DefaultCacheTTL is how long a cached result is considered fresh.
+const DefaultCacheTTL = time.Minute
+
+// Cache stores recent probe results on disk so the menu can show them
+// immediately when it opens.
+type Cache struct {
+	path    string
+	ttl     time.Duration
+	results map[string]Result
+}
+
+// DefaultCachePath returns the location of the probe cache file.
+func DefaultCachePath() (string, error) {
+	dir, err := xdg.CacheDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "probe.json"), nil
+}
+
+// LoadCache reads the cache at path. A missing or unreadable cache yields an
+// empty one, since it only exists to speed things up.
+func LoadCache(path string, ttl time.Duration) *Cache {
+	cache := &Cache{path: path, ttl: ttl, results: make(map[string]Result)}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return cache
+	}
+	json.Unmarshal(data, &cache.results)
+	if cache.results == nil {
+		cache.results = make(map[string]Result)
+	}
+	return cache
+}
+
+// Get returns the cached result for a target if it is still fresh and was
+// taken for the same address.
+func (c *Cache) Get(target Target) (Result, bool) {
+	result, ok := c.results[target.Alias]
+	if !ok || result.Address != target.Address || time.Since(result.Checked) > c.ttl {
+		return Result{}, false
+	}
+	return result, true
+}
+
+// Put records a result.
+func (c *Cache) Put(result Result) {
+	c.results[result.Alias] = result
+}
+
+// Save writes the cache back to disk. Results saved by other ssm instances
+// since the cache was loaded are kept unless this cache has a newer one for
+// the same host.
+func (c *Cache) Save() error {
+	if c.path == "" {
+		return errors.New("probe cache has no path")
+	}
+	dir := filepath.Dir(c.path)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return fmt.Errorf("failed to create cache directory: %w", err)
+	}
+	lock, err := os.OpenFile(c.path+".lock", os.O_CREATE|os.O_RDWR, 0o644)
+	if err != nil {
+		return fmt.Errorf("failed to lock probe cache: %w", err)
+	}
+	defer lock.Close()
+	if err := syscall.Flock(int(lock.Fd()), syscall.LOCK_EX); err != nil {
+		return fmt.Errorf("failed to lock probe cache: %w", err)
+	}
+
+	for alias, result := range LoadCache(c.path, c.ttl).results {
+		if current, ok := c.results[alias]; !ok || result.Checked.After(current.Checked) {
+			c.results[alias] = result
+		}
+	}
+	data, err := json.Marshal(c.results)
+	if err != nil {
+		return err
+	}
+
+	// Write to a temporary file first so concurrent ssm instances never see a
+	// partially written cache
+	tmp, err := os.CreateTemp(dir, "."+filepath.Base(c.path)+"-*")
+	if err != nil {
+		return fmt.Errorf("failed to write probe cache: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write probe cache: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write probe cache: %w", err)
+	}
+	if err := os.Chmod(tmp.Name(), 0o644); err != nil {
+		return fmt.Errorf("failed to write probe cache: %w", err)
+	}
+	return os.Rename(tmp.Name(), c.path)
+}
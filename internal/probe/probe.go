@@ -0,0 +1,117 @@
+// Package probe checks whether SSH hosts are reachable over TCP.
+package probe
+
+import (
+	"bufio"
+	"context"
+	"net"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultWorkers is the number of hosts probed at the same time.
+	DefaultWorkers = 16
+	// DefaultTimeout bounds the TCP connect and the banner read.
+	DefaultTimeout = 3 * time.Second
+)
+
+// Options configures probing.
+type Options struct {
+	// Workers bounds how many hosts are probed at once.
+	Workers int
+	// Timeout bounds each connection attempt and the banner read.
+	Timeout time.Duration
+	// Banner reads the server's SSH identification line after connecting.
+	Banner bool
+}
+
+// Target is a host to probe.
+type Target struct {
+	// Alias identifies the host in results.
+	Alias string
+	// Address is the host:port to connect to.
+	Address string
+}
+
+// Result is the outcome of probing a single host.
+type Result struct {
+	Alias     string        `json:"alias"`
+	Address   string        `json:"address"`
+	Reachable bool          `json:"reachable"`
+	Latency   time.Duration `json:"latency_ns"`
+	Banner    string        `json:"banner,omitempty"`
+	Error     string        `json:"error,omitempty"`
+	Checked   time.Time     `json:"checked"`
+}
+
+// Probe connects to a single target and reports how long the TCP handshake
+// took. With Options.Banner set it also reads the SSH identification line;
+// failing to read one does not make the host unreachable.
+func Probe(ctx context.Context, target Target, opts Options) Result {
+	if opts.Timeout <= 0 {
+		opts.Timeout = DefaultTimeout
+	}
+
+	result := Result{Alias: target.Alias, Address: target.Address, Checked: time.Now()}
+
+	dialer := net.Dialer{Timeout: opts.Timeout}
+	start := time.Now()
+	conn, err := dialer.DialContext(ctx, "tcp", target.Address)
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	defer conn.Close()
+
+	result.Reachable = true
+	result.Latency = time.Since(start)
+
+	if opts.Banner {
+		conn.SetReadDeadline(time.Now().Add(opts.Timeout))
+		line, err := bufio.NewReader(conn).ReadString('\n')
+		if err == nil || line != "" {
+			result.Banner = strings.TrimRight(line, "\r\n")
+		}
+	}
+	return result
+}
+
+// ProbeAll probes every target using a bounded pool of workers and calls
+// onResult as each one finishes. Calls to onResult are serialized. It
+// returns the results in the same order as targets.
+func ProbeAll(ctx context.Context, targets []Target, opts Options, onResult func(Result)) []Result {
+	if opts.Workers <= 0 {
+		opts.Workers = DefaultWorkers
+	}
+
+	var mu sync.Mutex
+	results := make([]Result, len(targets))
+	jobs := make(chan int)
+
+	var wg sync.WaitGroup
+	for w := 0; w < opts.Workers && w < len(targets); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for index := range jobs {
+				result := Probe(ctx, targets[index], opts)
+				results[index] = result
+				if onResult != nil {
+					mu.Lock()
+					onResult(result)
+					mu.Unlock()
+				}
+			}
+		}()
+	}
+
+	for index := range targets {
+		jobs <- index
+	}
+	close(jobs)
+	wg.Wait()
+
+	return results
+}
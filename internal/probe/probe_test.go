@@ -0,0 +1,135 @@
+package probe
+
+import (
+	"context"
+	"net"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// listen starts a local listener that greets every connection with banner.
+func listen(t *testing.T, banner string) string {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	t.Cleanup(func() { listener.Close() })
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			conn.Write([]byte(banner))
+			conn.Close()
+		}
+	}()
+	return listener.Addr().String()
+}
+
+// closedAddress returns a local address that refuses connections.
+func closedAddress(t *testing.T) string {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	address := listener.Addr().String()
+	listener.Close()
+	return address
+}
+
+func TestProbe(t *testing.T) {
+	address := listen(t, "SSH-2.0-OpenSSH_9.6\r\n")
+
+	result := Probe(context.Background(), Target{Alias: "local", Address: address}, Options{Banner: true})
+	if !result.Reachable {
+		t.Fatalf("Expected host to be reachable, got error %q", result.Error)
+	}
+	if result.Banner != "SSH-2.0-OpenSSH_9.6" {
+		t.Errorf("Expected banner 'SSH-2.0-OpenSSH_9.6', got '%s'", result.Banner)
+	}
+	if result.Latency <= 0 {
+		t.Errorf("Expected positive latency, got %s", result.Latency)
+	}
+}
+
+func TestProbe_Unreachable(t *testing.T) {
+	result := Probe(context.Background(), Target{Alias: "closed", Address: closedAddress(t)}, Options{Timeout: time.Second})
+	if result.Reachable {
+		t.Error("Expected closed port to be unreachable")
+	}
+	if result.Error == "" {
+		t.Error("Expected an error for an unreachable host")
+	}
+}
+
+func TestProbeAll(t *testing.T) {
+	targets := []Target{
+		{Alias: "up", Address: listen(t, "SSH-2.0-Test\r\n")},
+		{Alias: "down", Address: closedAddress(t)},
+	}
+
+	var seen []string
+	results := ProbeAll(context.Background(), targets, Options{Workers: 2}, func(result Result) {
+		seen = append(seen, result.Alias)
+	})
+
+	if len(seen) != 2 {
+		t.Errorf("Expected 2 callbacks, got %v", seen)
+	}
+	if !results[0].Reachable || results[1].Reachable {
+		t.Errorf("Expected only the first host to be reachable, got %+v", results)
+	}
+}
+
+func TestCache(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "probe.json")
+	target := Target{Alias: "web", Address: "10.0.0.1:22"}
+
+	cache := LoadCache(path, time.Minute)
+	cache.Put(Result{Alias: "web", Address: "10.0.0.1:22", Reachable: true, Checked: time.Now()})
+	cache.Put(Result{Alias: "old", Address: "10.0.0.2:22", Checked: time.Now().Add(-time.Hour)})
+	if err := cache.Save(); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+
+	cache = LoadCache(path, time.Minute)
+	if result, ok := cache.Get(target); !ok || !result.Reachable {
+		t.Errorf("Expected fresh cached result, got %+v (found %v)", result, ok)
+	}
+	if _, ok := cache.Get(Target{Alias: "old", Address: "10.0.0.2:22"}); ok {
+		t.Error("Expected stale result to be ignored")
+	}
+	if _, ok := cache.Get(Target{Alias: "web", Address: "10.0.0.9:22"}); ok {
+		t.Error("Expected result for a different address to be ignored")
+	}
+}
+
+func TestCache_Concurrent(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "probe.json")
+	first := LoadCache(path, time.Minute)
+	second := LoadCache(path, time.Minute)
+
+	now := time.Now()
+	first.Put(Result{Alias: "web", Address: "10.0.0.1:22", Reachable: true, Checked: now})
+	first.Put(Result{Alias: "db", Address: "10.0.0.2:22", Checked: now.Add(-time.Second)})
+	first.Save()
+	second.Put(Result{Alias: "db", Address: "10.0.0.2:22", Reachable: true, Checked: now})
+	second.Save()
+
+	// The second save keeps the first's results, and the newest one for db
+	cache := LoadCache(path, time.Minute)
+	if result, ok := cache.Get(Target{Alias: "web", Address: "10.0.0.1:22"}); !ok || !result.Reachable {
+		t.Errorf("Expected web's result to be kept, got %+v (found %v)", result, ok)
+	}
+	if result, _ := cache.Get(Target{Alias: "db", Address: "10.0.0.2:22"}); !result.Reachable {
+		t.Errorf("Expected the newest result for db, got %+v", result)
+	}
+	if entries, _ := filepath.Glob(filepath.Join(filepath.Dir(path), ".probe.json-*")); len(entries) != 0 {
+		t.Errorf("Expected no temporary files left, got %v", entries)
+	}
+}
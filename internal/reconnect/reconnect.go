@@ -0,0 +1,229 @@
+// Package reconnect supervises an interactive session and reconnects it with
+// exponential backoff when the connection drops.
+package reconnect
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/charmbracelet/x/term"
+	"golang.org/x/sys/unix"
+)
+
+// Defaults for a Supervisor's zero fields.
+const (
+	DefaultInitial = time.Second
+	DefaultMax     = time.Minute
+	DefaultStable  = time.Minute
+	DefaultShort   = 10 * time.Second
+	DefaultGiveUp  = 3
+)
+
+// ExitConnectionLost is the exit status ssh uses for connection errors.
+const ExitConnectionLost = 255
+
+// Supervisor runs a session's command until it exits normally, reconnecting
+// after the connection is lost.
+type Supervisor struct {
+	// Host is the alias shown in status messages.
+	Host string
+	// Command is the program and arguments that open the session.
+	Command []string
+	// Initial is the first delay before reconnecting. Defaults to DefaultInitial.
+	Initial time.Duration
+	// Max caps the delay between attempts. Defaults to DefaultMax.
+	Max time.Duration
+	// Stable is how long a session must last for the delay to start over
+	// from Initial. Defaults to DefaultStable.
+	Stable time.Duration
+	// Short is how soon a session must end to count as failing to connect,
+	// as when authentication is refused. Defaults to DefaultShort.
+	Short time.Duration
+	// GiveUp is how many sessions in a row may fail to connect before the
+	// supervisor stops reconnecting. Defaults to DefaultGiveUp.
+	GiveUp int
+	// Stdin, Stdout and Stderr are connected to the command. They default to
+	// the process's own.
+	Stdin          *os.File
+	Stdout, Stderr io.Writer
+	// Runner runs the command once and returns its exit status, e.g. to
+	// record each connection. Defaults to running it on the terminal.
+	Runner func(command []string) (int, error)
+	// wait shows the countdown before a reconnect and returns false if the
+	// user aborted it. Tests replace it.
+	wait func(delay time.Duration) bool
+}
+
+// Lost reports whether an exit status means the connection was lost rather
+// than the session ending normally.
+func Lost(code int) bool {
+	return code == ExitConnectionLost
+}
+
+// Backoff returns the delay before the given reconnect attempt, starting at
+// 0: initial doubled per attempt, capped at max.
+func Backoff(attempt int, initial, max time.Duration) time.Duration {
+	delay := initial
+	for i := 0; i < attempt && delay < max; i++ {
+		delay *= 2
+	}
+	return min(delay, max)
+}
+
+// Run runs the command, reconnecting while it exits with ExitConnectionLost
+// and the user doesn't abort the countdown. ssh also exits with
+// ExitConnectionLost when it can't connect at all, so Run gives up once
+// GiveUp sessions in a row ended within Short. It returns the last exit
+// status.
+func (s *Supervisor) Run() (int, error) {
+	if len(s.Command) == 0 {
+		return 0, errors.New("no command to run")
+	}
+	s.defaults()
+
+	attempt, failed := 0, 0
+	for {
+		started := time.Now()
+		code, err := s.Runner(s.Command)
+		if err != nil {
+			return code, err
+		}
+		if !Lost(code) {
+			return code, nil
+		}
+
+		lasted := time.Since(started)
+		if lasted >= s.Stable {
+			attempt = 0
+		}
+		if lasted < s.Short {
+			failed++
+		} else {
+			failed = 0
+		}
+		if failed >= s.GiveUp {
+			fmt.Fprintf(s.Stderr, "\r\nCouldn't connect to %s %d times in a row (exit %d), not reconnecting.\r\n", s.Host, failed, code)
+			return code, nil
+		}
+		delay := Backoff(attempt, s.Initial, s.Max)
+		attempt++
+		fmt.Fprintf(s.Stderr, "\r\nConnection to %s lost (exit %d).\r\n", s.Host, code)
+		if !s.wait(delay) {
+			fmt.Fprintf(s.Stderr, "Not reconnecting.\r\n")
+			return code, nil
+		}
+		fmt.Fprintf(s.Stderr, "Reconnecting to %s (attempt %d) ...\r\n", s.Host, attempt)
+	}
+}
+
+// defaults fills in zero fields.
+func (s *Supervisor) defaults() {
+	if s.Initial <= 0 {
+		s.Initial = DefaultInitial
+	}
+	if s.Max <= 0 {
+		s.Max = DefaultMax
+	}
+	if s.Stable <= 0 {
+		s.Stable = DefaultStable
+	}
+	if s.Short <= 0 {
+		s.Short = DefaultShort
+	}
+	if s.GiveUp <= 0 {
+		s.GiveUp = DefaultGiveUp
+	}
+	if s.Stdin == nil {
+		s.Stdin = os.Stdin
+	}
+	if s.Stdout == nil {
+		s.Stdout = os.Stdout
+	}
+	if s.Stderr == nil {
+		s.Stderr = os.Stderr
+	}
+	if s.Runner == nil {
+		s.Runner = s.runOnce
+	}
+	if s.wait == nil {
+		s.wait = s.countdown
+	}
+}
+
+// runOnce runs the command once on the supervisor's terminal.
+func (s *Supervisor) runOnce(command []string) (int, error) {
+	return runAttached(command, s.Stdin, s.Stdout, s.Stderr)
+}
+
+// RunAttached runs command once on the process's terminal and returns its
+// exit status. Interrupts are left to the command while it runs.
+func RunAttached(command []string) (int, error) {
+	return runAttached(command, os.Stdin, os.Stdout, os.Stderr)
+}
+
+// runAttached runs command with the given standard streams and returns its
+// exit status.
+func runAttached(command []string, stdin *os.File, stdout, stderr io.Writer) (int, error) {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGQUIT)
+	defer signal.Stop(signals)
+
+	cmd := exec.Command(command[0], command[1:]...)
+	cmd.Stdin = stdin
+	cmd.Stdout = stdout
+	cmd.Stderr = stderr
+	err := cmd.Run()
+
+	var exitErr *exec.ExitError
+	switch {
+	case err == nil:
+		return 0, nil
+	case errors.As(err, &exitErr):
+		return exitErr.ExitCode(), nil
+	}
+	return 1, err
+}
+
+// countdown counts down delay on stderr and returns false if a key is
+// pressed first. Without a terminal to read keys from it just sleeps.
+func (s *Supervisor) countdown(delay time.Duration) bool {
+	fd := s.Stdin.Fd()
+	state, err := term.MakeRaw(fd)
+	if err != nil {
+		time.Sleep(delay)
+		return true
+	}
+	defer term.Restore(fd, state)
+
+	deadline := time.Now().Add(delay)
+	for {
+		remaining := time.Until(deadline)
+		if remaining <= 0 {
+			fmt.Fprint(s.Stderr, "\r\x1b[K")
+			return true
+		}
+		fmt.Fprintf(s.Stderr, "\r\x1b[KReconnecting in %ds, press any key to give up", int(remaining.Round(time.Second)/time.Second))
+		if keyPressed(fd, min(remaining, time.Second)) {
+			fmt.Fprint(s.Stderr, "\r\x1b[K")
+			return false
+		}
+	}
+}
+
+// keyPressed waits up to timeout for input on fd and consumes it.
+func keyPressed(fd uintptr, timeout time.Duration) bool {
+	fds := []unix.PollFd{{Fd: int32(fd), Events: unix.POLLIN}}
+	// Errors such as EINTR from a window resize count as no key
+	if n, err := unix.Poll(fds, int(timeout/time.Millisecond)); err != nil || n == 0 {
+		return false
+	}
+	buf := make([]byte, 64)
+	unix.Read(int(fd), buf)
+	return true
+}
@@ -0,0 +1,85 @@
+package reconnect
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestBackoff(t *testing.T) {
+	var delays []time.Duration
+	for attempt := 0; attempt < 5; attempt++ {
+		delays = append(delays, Backoff(attempt, time.Second, 5*time.Second))
+	}
+	expected := []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 5 * time.Second, 5 * time.Second}
+	if !reflect.DeepEqual(delays, expected) {
+		t.Errorf("Expected %v, got %v", expected, delays)
+	}
+}
+
+// fakeSession writes a script that exits with each of codes in turn, one
+// per run.
+func fakeSession(t *testing.T, codes ...string) []string {
+	t.Helper()
+	dir := t.TempDir()
+	script := "#!/bin/sh\nn=$(cat \"$0.runs\" 2>/dev/null || echo 0)\necho $((n+1)) > \"$0.runs\"\n" +
+		"set -- " + strings.Join(codes, " ") + "\nshift $n\nexit $1\n"
+	path := filepath.Join(dir, "ssh")
+	if err := os.WriteFile(path, []byte(script), 0755); err != nil {
+		t.Fatalf("Failed to write fake session: %v", err)
+	}
+	return []string{path}
+}
+
+func TestSupervisor_Run(t *testing.T) {
+	tests := []struct {
+		name     string
+		codes    []string
+		abortAt  int
+		expected int
+		waits    []time.Duration
+	}{
+		{"normal exit", []string{"0"}, -1, 0, nil},
+		{"command failed", []string{"1"}, -1, 1, nil},
+		{"reconnects", []string{"255", "255", "0"}, -1, 0, []time.Duration{time.Millisecond, 2 * time.Millisecond}},
+		{"aborted", []string{"255", "255", "0"}, 1, 255, []time.Duration{time.Millisecond, 2 * time.Millisecond}},
+		{"never connects", []string{"255", "255", "255", "0"}, -1, 255, []time.Duration{time.Millisecond, 2 * time.Millisecond}},
+	}
+	for _, test := range tests {
+		var waits []time.Duration
+		var stderr bytes.Buffer
+		s := &Supervisor{
+			Host:    "web",
+			Command: fakeSession(t, test.codes...),
+			Initial: time.Millisecond,
+			Stdin:   os.Stdin,
+			Stdout:  &bytes.Buffer{},
+			Stderr:  &stderr,
+			wait: func(delay time.Duration) bool {
+				waits = append(waits, delay)
+				return len(waits)-1 != test.abortAt
+			},
+		}
+		code, err := s.Run()
+		if err != nil || code != test.expected {
+			t.Errorf("%s: expected exit %d, got %d (%v)", test.name, test.expected, code, err)
+		}
+		if !reflect.DeepEqual(waits, test.waits) {
+			t.Errorf("%s: expected waits %v, got %v", test.name, test.waits, waits)
+		}
+		if len(test.waits) > 0 && !strings.Contains(stderr.String(), "Connection to web lost (exit 255)") {
+			t.Errorf("%s: expected a connection lost message, got %q", test.name, stderr.String())
+		}
+	}
+}
+
+func TestSupervisor_RunMissingCommand(t *testing.T) {
+	s := &Supervisor{Host: "web", Command: []string{filepath.Join(t.TempDir(), "missing")}}
+	if _, err := s.Run(); err == nil {
+		t.Error("Expected an error for a missing command")
+	}
+}
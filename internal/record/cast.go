@@ -0,0 +1,168 @@
+package record
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"unicode/utf8"
+)
+
+// Event types of the asciicast v2 format.
+const (
+	EventOutput = "o"
+	EventInput  = "i"
+	EventResize = "r"
+	EventMarker = "m"
+)
+
+// Header is the first line of an asciicast v2 file.
+type Header struct {
+	Version   int               `json:"version"`
+	Width     int               `json:"width"`
+	Height    int               `json:"height"`
+	Timestamp int64             `json:"timestamp,omitempty"`
+	Title     string            `json:"title,omitempty"`
+	Env       map[string]string `json:"env,omitempty"`
+}
+
+// Event is a single timed event, written as [time, type, data].
+type Event struct {
+	// Time is the number of seconds since the recording started.
+	Time float64
+	Type string
+	Data string
+}
+
+// MarshalJSON encodes the event as a JSON array.
+func (e Event) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode([]any{e.Time, e.Type, e.Data}); err != nil {
+		return nil, err
+	}
+	return bytes.TrimRight(buf.Bytes(), "\n"), nil
+}
+
+// UnmarshalJSON decodes an event from a JSON array.
+func (e *Event) UnmarshalJSON(data []byte) error {
+	var fields []json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return err
+	}
+	if len(fields) != 3 {
+		return fmt.Errorf("expected 3 fields, got %d", len(fields))
+	}
+	if err := json.Unmarshal(fields[0], &e.Time); err != nil {
+		return fmt.Errorf("time: %w", err)
+	}
+	if err := json.Unmarshal(fields[1], &e.Type); err != nil {
+		return fmt.Errorf("type: %w", err)
+	}
+	if err := json.Unmarshal(fields[2], &e.Data); err != nil {
+		return fmt.Errorf("data: %w", err)
+	}
+	return nil
+}
+
+// Writer writes an asciicast v2 stream.
+type Writer struct {
+	w io.Writer
+	// pending holds the start of a UTF-8 sequence split across writes
+	pending []byte
+}
+
+// NewWriter writes header to w and returns a Writer for the events.
+func NewWriter(w io.Writer, header Header) (*Writer, error) {
+	header.Version = 2
+	cw := &Writer{w: w}
+	if err := cw.encode(header); err != nil {
+		return nil, err
+	}
+	return cw, nil
+}
+
+// Output writes terminal output seconds after the start. A multi-byte
+// character split across calls is held back until it is complete.
+func (cw *Writer) Output(seconds float64, data []byte) error {
+	data = append(cw.pending, data...)
+	complete, rest := splitUTF8(data)
+	cw.pending = append([]byte(nil), rest...)
+	if len(complete) == 0 {
+		return nil
+	}
+	return cw.write(Event{Time: seconds, Type: EventOutput, Data: string(complete)})
+}
+
+// Resize records that the terminal changed size seconds after the start.
+func (cw *Writer) Resize(seconds float64, width, height int) error {
+	return cw.write(Event{Time: seconds, Type: EventResize, Data: fmt.Sprintf("%dx%d", width, height)})
+}
+
+// write writes a single event line.
+func (cw *Writer) write(event Event) error {
+	// Round to microseconds like asciinema does
+	event.Time = float64(int64(event.Time*1e6+0.5)) / 1e6
+	return cw.encode(event)
+}
+
+// encode writes v as a line of JSON, leaving characters such as < and >
+// unescaped.
+func (cw *Writer) encode(v any) error {
+	encoder := json.NewEncoder(cw.w)
+	encoder.SetEscapeHTML(false)
+	return encoder.Encode(v)
+}
+
+// splitUTF8 splits data before a trailing incomplete UTF-8 sequence.
+func splitUTF8(data []byte) ([]byte, []byte) {
+	for i := 1; i <= utf8.UTFMax-1 && i <= len(data); i++ {
+		start := len(data) - i
+		if !utf8.RuneStart(data[start]) {
+			continue
+		}
+		if !utf8.FullRune(data[start:]) {
+			return data[:start], data[start:]
+		}
+		break
+	}
+	return data, nil
+}
+
+// Read parses an asciicast v2 stream.
+func Read(r io.Reader) (Header, []Event, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+
+	var header Header
+	if !scanner.Scan() {
+		if err := scanner.Err(); err != nil {
+			return header, nil, err
+		}
+		return header, nil, errors.New("empty recording")
+	}
+	if err := json.Unmarshal(scanner.Bytes(), &header); err != nil {
+		return header, nil, fmt.Errorf("invalid header: %w", err)
+	}
+	if header.Version != 2 {
+		return header, nil, fmt.Errorf("unsupported asciicast version %d", header.Version)
+	}
+
+	var events []Event
+	for line := 2; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" {
+			continue
+		}
+		var event Event
+		if err := json.Unmarshal([]byte(text), &event); err != nil {
+			return header, events, fmt.Errorf("line %d: %w", line, err)
+		}
+		events = append(events, event)
+	}
+	return header, events, scanner.Err()
+}
@@ -0,0 +1,63 @@
+package record
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestWriter(t *testing.T) {
+	var buf bytes.Buffer
+	w, err := NewWriter(&buf, Header{Width: 80, Height: 24, Title: "ssh web"})
+	if err != nil {
+		t.Fatalf("NewWriter failed: %v", err)
+	}
+	w.Output(0.5, []byte("hello\r\n> "))
+	// "é" split across two reads is written once it is complete
+	w.Output(1.25, []byte{'c', 'a', 'f', 0xc3})
+	w.Output(1.5, []byte{0xa9, '\n'})
+	w.Resize(2.0000004, 100, 30)
+
+	expected := `{"version":2,"width":80,"height":24,"title":"ssh web"}
+[0.5,"o","hello\r\n> "]
+[1.25,"o","caf"]
+[1.5,"o","é\n"]
+[2,"r","100x30"]
+`
+	if buf.String() != expected {
+		t.Errorf("Expected:\n%s\ngot:\n%s", expected, buf.String())
+	}
+
+	header, events, err := Read(&buf)
+	if err != nil {
+		t.Fatalf("Read failed: %v", err)
+	}
+	if header.Version != 2 || header.Width != 80 || header.Title != "ssh web" {
+		t.Errorf("Unexpected header %+v", header)
+	}
+	expectedEvents := []Event{
+		{0.5, EventOutput, "hello\r\n> "},
+		{1.25, EventOutput, "caf"},
+		{1.5, EventOutput, "é\n"},
+		{2, EventResize, "100x30"},
+	}
+	if !reflect.DeepEqual(events, expectedEvents) {
+		t.Errorf("Expected %v, got %v", expectedEvents, events)
+	}
+}
+
+func TestRead_Invalid(t *testing.T) {
+	tests := map[string]string{
+		"":                                    "empty recording",
+		`{"version":1,"width":80}`:            "unsupported asciicast version 1",
+		"{\"version\":2}\n[1,\"o\"]\n":        "line 2: expected 3 fields, got 2",
+		"{\"version\":2}\n[\"x\",\"o\",\"\"]": "line 2: time:",
+	}
+	for input, expected := range tests {
+		_, _, err := Read(strings.NewReader(input))
+		if err == nil || !strings.Contains(err.Error(), expected) {
+			t.Errorf("Read(%q): expected error containing %q, got %v", input, expected, err)
+		}
+	}
+}
@@ -0,0 +1,181 @@
+// Package record records interactive sessions to asciinema v2 (.cast) files
+// and plays them back.
+package record
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/antonjah/ssm/internal/config"
+	"github.com/antonjah/ssm/internal/xdg"
+	"github.com/charmbracelet/x/term"
+	"github.com/creack/pty"
+	"github.com/muesli/cancelreader"
+)
+
+// Extension is the file extension of recordings.
+const Extension = ".cast"
+
+// DefaultDir returns the directory recordings are saved in unless ssm's
+// settings pick another: recordings in ssm's data directory.
+func DefaultDir() (string, error) {
+	dir, err := xdg.DataDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "recordings"), nil
+}
+
+// Path returns the file a session to host started at start is recorded to,
+// <dir>/<host>/<date>_<time>.cast. A numeric suffix is added if the file
+// already exists.
+func Path(dir, host string, start time.Time) string {
+	base := filepath.Join(dir, filepath.Base(host), start.Format("2006-01-02_150405"))
+	path := base + Extension
+	for i := 2; ; i++ {
+		if _, err := os.Stat(path); os.IsNotExist(err) {
+			return path
+		}
+		path = fmt.Sprintf("%s-%d%s", base, i, Extension)
+	}
+}
+
+// Required reports whether sessions to host must be recorded because it has
+// one of the forced tags.
+func Required(host config.Host, forced []string) bool {
+	for _, tag := range host.Tags {
+		for _, f := range forced {
+			if strings.EqualFold(tag, f) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Recorder runs a command under a pseudo-terminal owned by ssm, passing the
+// terminal through and recording the command's output to a cast file.
+type Recorder struct {
+	// Path is the cast file to write. Its directory is created if needed.
+	Path string
+	// Title is stored in the recording's header.
+	Title string
+	// Command is the program and arguments to run.
+	Command []string
+	// Stdin and Stdout are the user's terminal. They default to the
+	// process's own.
+	Stdin  *os.File
+	Stdout io.Writer
+}
+
+// Run runs the command until it exits and returns its exit status.
+func (r *Recorder) Run() (int, error) {
+	if len(r.Command) == 0 {
+		return 0, errors.New("no command to run")
+	}
+	stdin, stdout := r.Stdin, r.Stdout
+	if stdin == nil {
+		stdin = os.Stdin
+	}
+	if stdout == nil {
+		stdout = os.Stdout
+	}
+
+	if err := os.MkdirAll(filepath.Dir(r.Path), 0o700); err != nil {
+		return 1, fmt.Errorf("failed to create recording directory: %w", err)
+	}
+	file, err := os.OpenFile(r.Path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o600)
+	if err != nil {
+		return 1, fmt.Errorf("failed to create recording: %w", err)
+	}
+	defer file.Close()
+
+	width, height, err := term.GetSize(stdin.Fd())
+	if err != nil {
+		width, height = 80, 24
+	}
+	start := time.Now()
+	cast, err := NewWriter(file, Header{
+		Width:     width,
+		Height:    height,
+		Timestamp: start.Unix(),
+		Title:     r.Title,
+		Env:       map[string]string{"TERM": os.Getenv("TERM"), "SHELL": os.Getenv("SHELL")},
+	})
+	if err != nil {
+		return 1, fmt.Errorf("failed to write recording: %w", err)
+	}
+
+	cmd := exec.Command(r.Command[0], r.Command[1:]...)
+	ptmx, err := pty.StartWithSize(cmd, &pty.Winsize{Cols: uint16(width), Rows: uint16(height)})
+	if err != nil {
+		return 1, err
+	}
+	defer ptmx.Close()
+
+	if state, err := term.MakeRaw(stdin.Fd()); err == nil {
+		defer term.Restore(stdin.Fd(), state)
+	}
+
+	// Events are written from the output loop and the resize handler
+	var mu sync.Mutex
+	elapsed := func() float64 { return time.Since(start).Seconds() }
+
+	resizes := make(chan os.Signal, 1)
+	signal.Notify(resizes, syscall.SIGWINCH)
+	defer signal.Stop(resizes)
+	go func() {
+		for range resizes {
+			if err := pty.InheritSize(stdin, ptmx); err != nil {
+				continue
+			}
+			if w, h, err := term.GetSize(stdin.Fd()); err == nil {
+				mu.Lock()
+				cast.Resize(elapsed(), w, h)
+				mu.Unlock()
+			}
+		}
+	}()
+
+	// Input goes straight to the command. The reader is cancelled when the
+	// command exits so it doesn't swallow the next keystroke.
+	input, err := cancelreader.NewReader(stdin)
+	if err == nil {
+		defer input.Cancel()
+		go io.Copy(ptmx, input)
+	}
+
+	buf := make([]byte, 32*1024)
+	for {
+		n, err := ptmx.Read(buf)
+		if n > 0 {
+			stdout.Write(buf[:n])
+			mu.Lock()
+			cast.Output(elapsed(), buf[:n])
+			mu.Unlock()
+		}
+		if err != nil {
+			// Linux reports EIO once the command has exited
+			break
+		}
+	}
+
+	err = cmd.Wait()
+	var exitErr *exec.ExitError
+	switch {
+	case err == nil:
+		return 0, nil
+	case errors.As(err, &exitErr):
+		return exitErr.ExitCode(), nil
+	}
+	return 1, err
+}
@@ -0,0 +1,92 @@
+package record
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/antonjah/ssm/internal/config"
+)
+
+func TestDefaultDir(t *testing.T) {
+	t.Setenv("XDG_DATA_HOME", "/tmp/data")
+	if dir, err := DefaultDir(); err != nil || dir != "/tmp/data/ssm/recordings" {
+		t.Errorf("Expected /tmp/data/ssm/recordings, got %s (%v)", dir, err)
+	}
+}
+
+func TestPath(t *testing.T) {
+	dir := t.TempDir()
+	start := time.Date(2026, 3, 14, 9, 26, 53, 0, time.Local)
+
+	path := Path(dir, "web", start)
+	if path != filepath.Join(dir, "web", "2026-03-14_092653.cast") {
+		t.Errorf("Unexpected path %s", path)
+	}
+	os.MkdirAll(filepath.Dir(path), 0o700)
+	os.WriteFile(path, nil, 0o600)
+	if second := Path(dir, "web", start); second != filepath.Join(dir, "web", "2026-03-14_092653-2.cast") {
+		t.Errorf("Expected a numbered path, got %s", second)
+	}
+}
+
+func TestRequired(t *testing.T) {
+	forced := []string{"prod", "pci"}
+	if !Required(config.Host{Alias: "db", Tags: []string{"db", "Prod"}}, forced) {
+		t.Error("Expected a prod host to be recorded")
+	}
+	if Required(config.Host{Alias: "dev", Tags: []string{"dev"}}, forced) {
+		t.Error("Expected a dev host not to be recorded")
+	}
+}
+
+func TestRecorder_Run(t *testing.T) {
+	stdin, input, err := os.Pipe()
+	if err != nil {
+		t.Fatalf("Failed to create pipe: %v", err)
+	}
+	defer stdin.Close()
+	input.Close()
+
+	var out bytes.Buffer
+	recorder := &Recorder{
+		Path:    filepath.Join(t.TempDir(), "web", "session.cast"),
+		Title:   "ssh web",
+		Command: []string{"sh", "-c", "printf 'hello\\n'; exit 3"},
+		Stdin:   stdin,
+		Stdout:  &out,
+	}
+	code, err := recorder.Run()
+	if err != nil || code != 3 {
+		t.Fatalf("Expected exit 3, got %d (%v)", code, err)
+	}
+	if !strings.Contains(out.String(), "hello") {
+		t.Errorf("Expected output to be passed through, got %q", out.String())
+	}
+
+	file, err := os.Open(recorder.Path)
+	if err != nil {
+		t.Fatalf("Failed to open recording: %v", err)
+	}
+	defer file.Close()
+	header, events, err := Read(file)
+	if err != nil {
+		t.Fatalf("Failed to read recording: %v", err)
+	}
+	if header.Title != "ssh web" || header.Width != 80 || header.Height != 24 {
+		t.Errorf("Unexpected header %+v", header)
+	}
+	var recorded strings.Builder
+	for _, event := range events {
+		recorded.WriteString(event.Data)
+	}
+	if !strings.Contains(recorded.String(), "hello") {
+		t.Errorf("Expected the output to be recorded, got %v", events)
+	}
+	if info, _ := os.Stat(recorder.Path); info.Mode().Perm() != 0o600 {
+		t.Errorf("Expected the recording to be private, got %v", info.Mode().Perm())
+	}
+}
@@ -0,0 +1,128 @@
+package record
+
+import (
+	"io"
+	"os"
+	"time"
+
+	"github.com/charmbracelet/x/term"
+	"golang.org/x/sys/unix"
+)
+
+// Speed limits for the player's controls.
+const (
+	MinSpeed = 0.25
+	MaxSpeed = 16.0
+)
+
+// Player plays recorded events back to a terminal. While it plays, space
+// pauses, + and - double and halve the speed, . skips the current pause and
+// q quits.
+type Player struct {
+	// Speed multiplies the playback speed. Defaults to 1.
+	Speed float64
+	// MaxIdle caps pauses between events, if set.
+	MaxIdle time.Duration
+	// Out receives the recorded output.
+	Out io.Writer
+	// In is the terminal the controls are read from. Without it the
+	// recording plays straight through.
+	In *os.File
+	// sleep waits between events when there is no terminal. Tests replace it.
+	sleep func(time.Duration)
+}
+
+// Play writes events' output to Out with their original timing. It returns
+// when the events run out or the user quits.
+func (p *Player) Play(events []Event) error {
+	if p.Speed <= 0 {
+		p.Speed = 1
+	}
+	if p.sleep == nil {
+		p.sleep = time.Sleep
+	}
+	if p.In != nil {
+		if state, err := term.MakeRaw(p.In.Fd()); err == nil {
+			defer term.Restore(p.In.Fd(), state)
+		} else {
+			p.In = nil
+		}
+	}
+
+	last := 0.0
+	for _, event := range events {
+		delay := time.Duration((event.Time - last) * float64(time.Second))
+		last = event.Time
+		if p.MaxIdle > 0 {
+			delay = min(delay, p.MaxIdle)
+		}
+		if delay > 0 && !p.wait(delay) {
+			return nil
+		}
+		if event.Type != EventOutput {
+			continue
+		}
+		if _, err := io.WriteString(p.Out, event.Data); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// wait waits for delay at the current speed while handling the controls. It
+// returns false if the user quit.
+func (p *Player) wait(delay time.Duration) bool {
+	if p.In == nil {
+		p.sleep(time.Duration(float64(delay) / p.Speed))
+		return true
+	}
+
+	paused := false
+	remaining := delay
+	for paused || remaining > 0 {
+		timeout := time.Duration(float64(remaining) / p.Speed)
+		if paused {
+			timeout = -1
+		}
+		started := time.Now()
+		key, ok := readKey(p.In.Fd(), timeout)
+		if !paused {
+			remaining -= time.Duration(float64(time.Since(started)) * p.Speed)
+		}
+		if !ok {
+			continue
+		}
+		switch key {
+		case 'q', 3: // q or ctrl+c
+			return false
+		case ' ':
+			paused = !paused
+		case '+', '=':
+			p.Speed = min(p.Speed*2, MaxSpeed)
+		case '-', '_':
+			p.Speed = max(p.Speed/2, MinSpeed)
+		case '.':
+			remaining, paused = 0, false
+		}
+	}
+	return true
+}
+
+// readKey waits up to timeout for a key on fd, forever if timeout is
+// negative, and returns the first byte read.
+func readKey(fd uintptr, timeout time.Duration) (byte, bool) {
+	ms := -1
+	if timeout >= 0 {
+		ms = int(timeout / time.Millisecond)
+	}
+	fds := []unix.PollFd{{Fd: int32(fd), Events: unix.POLLIN}}
+	// Errors such as EINTR from a window resize count as no key
+	if n, err := unix.Poll(fds, ms); err != nil || n == 0 {
+		return 0, false
+	}
+	buf := make([]byte, 16)
+	if n, err := unix.Read(int(fd), buf); err != nil || n == 0 {
+		return 0, false
+	}
+	return buf[0], true
+}
@@ -0,0 +1,45 @@
+package record
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestPlayer_Play(t *testing.T) {
+	events := []Event{
+		{0.5, EventOutput, "a"},
+		{1, EventResize, "100x30"},
+		{11, EventOutput, "b"},
+	}
+
+	tests := []struct {
+		speed    float64
+		maxIdle  time.Duration
+		expected []time.Duration
+	}{
+		{1, 0, []time.Duration{500 * time.Millisecond, 500 * time.Millisecond, 10 * time.Second}},
+		{2, 0, []time.Duration{250 * time.Millisecond, 250 * time.Millisecond, 5 * time.Second}},
+		{1, 2 * time.Second, []time.Duration{500 * time.Millisecond, 500 * time.Millisecond, 2 * time.Second}},
+	}
+	for _, test := range tests {
+		var out bytes.Buffer
+		var sleeps []time.Duration
+		player := &Player{
+			Speed:   test.speed,
+			MaxIdle: test.maxIdle,
+			Out:     &out,
+			sleep:   func(d time.Duration) { sleeps = append(sleeps, d) },
+		}
+		if err := player.Play(events); err != nil {
+			t.Fatalf("Play failed: %v", err)
+		}
+		if out.String() != "ab" {
+			t.Errorf("Expected output 'ab', got %q", out.String())
+		}
+		if !reflect.DeepEqual(sleeps, test.expected) {
+			t.Errorf("Speed %g, max idle %s: expected pauses %v, got %v", test.speed, test.maxIdle, test.expected, sleeps)
+		}
+	}
+}
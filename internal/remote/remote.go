@@ -0,0 +1,238 @@
+// Package remote runs commands on many SSH hosts concurrently.
+package remote
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultWorkers is the number of hosts contacted at the same time.
+	DefaultWorkers = 8
+	// DefaultTimeout is how long a single host may take before it is killed.
+	DefaultTimeout = 30 * time.Second
+)
+
+// Stream identifies an output stream of a remote command.
+type Stream int
+
+const (
+	// Stdout is the standard output of the remote command.
+	Stdout Stream = iota
+	// Stderr is the standard error of the remote command and of ssh itself.
+	Stderr
+)
+
+// Options configures a parallel run.
+type Options struct {
+	// SSHPath is the ssh binary to use. Defaults to "ssh".
+	SSHPath string
+	// Workers bounds how many hosts run at once. Defaults to DefaultWorkers.
+	Workers int
+	// Timeout bounds each host's run. Zero means DefaultTimeout.
+	Timeout time.Duration
+	// OnStart, if set, is called when a host starts running.
+	OnStart func(host string)
+	// OnLine, if set, is called for every complete line of output. Calls are
+	// serialized, so the callback doesn't need its own locking.
+	OnLine func(host string, stream Stream, line string)
+	// OnResult, if set, is called when a host finishes. Calls are serialized.
+	OnResult func(result Result)
+}
+
+// Result is the outcome of running the command on a single host.
+type Result struct {
+	Host     string
+	ExitCode int
+	Duration time.Duration
+	Stdout   string
+	Stderr   string
+	// TimedOut is true if the host was killed after Options.Timeout.
+	TimedOut bool
+	// Err is set if ssh could not be started or exited abnormally.
+	Err error
+}
+
+// OK reports whether the command ran and exited with status zero.
+func (r Result) OK() bool {
+	return r.Err == nil && r.ExitCode == 0
+}
+
+// MarshalJSON encodes the result with the duration in milliseconds and the
+// error as a string.
+func (r Result) MarshalJSON() ([]byte, error) {
+	var errText string
+	if r.Err != nil {
+		errText = r.Err.Error()
+	}
+	return json.Marshal(struct {
+		Host       string `json:"host"`
+		ExitCode   int    `json:"exit_code"`
+		DurationMS int64  `json:"duration_ms"`
+		Stdout     string `json:"stdout"`
+		Stderr     string `json:"stderr"`
+		TimedOut   bool   `json:"timed_out,omitempty"`
+		Error      string `json:"error,omitempty"`
+	}{r.Host, r.ExitCode, r.Duration.Milliseconds(), r.Stdout, r.Stderr, r.TimedOut, errText})
+}
+
+// Run executes command on every host using a bounded pool of workers and
+// returns the results in the same order as hosts. Cancelling ctx stops hosts
+// that are still running.
+func Run(ctx context.Context, hosts []string, command []string, opts Options) []Result {
+	if opts.SSHPath == "" {
+		opts.SSHPath = "ssh"
+	}
+	if opts.Workers <= 0 {
+		opts.Workers = DefaultWorkers
+	}
+	if opts.Timeout <= 0 {
+		opts.Timeout = DefaultTimeout
+	}
+
+	var mu sync.Mutex
+	results := make([]Result, len(hosts))
+	jobs := make(chan int)
+
+	var wg sync.WaitGroup
+	for w := 0; w < opts.Workers && w < len(hosts); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for index := range jobs {
+				if opts.OnStart != nil {
+					mu.Lock()
+					opts.OnStart(hosts[index])
+					mu.Unlock()
+				}
+				result := runHost(ctx, hosts[index], command, opts, &mu)
+				results[index] = result
+				if opts.OnResult != nil {
+					mu.Lock()
+					opts.OnResult(result)
+					mu.Unlock()
+				}
+			}
+		}()
+	}
+
+	for index := range hosts {
+		jobs <- index
+	}
+	close(jobs)
+	wg.Wait()
+
+	return results
+}
+
+// Args returns the ssh arguments used to run command on host. BatchMode keeps
+// ssh from prompting, and the connect timeout stops unreachable hosts from
+// using up the whole per-host timeout.
+func Args(host string, command []string, timeout time.Duration) []string {
+	connectTimeout := int(timeout.Seconds())
+	if connectTimeout < 1 {
+		connectTimeout = 1
+	}
+	args := []string{
+		"-o", "BatchMode=yes",
+		"-o", "ConnectTimeout=" + strconv.Itoa(connectTimeout),
+		host, "--",
+	}
+	return append(args, command...)
+}
+
+// runHost runs the command on a single host.
+func runHost(ctx context.Context, host string, command []string, opts Options, mu *sync.Mutex) Result {
+	ctx, cancel := context.WithTimeout(ctx, opts.Timeout)
+	defer cancel()
+
+	var stdout, stderr bytes.Buffer
+	stdoutLines := &lineWriter{buf: &stdout, emit: lineEmitter(host, Stdout, opts, mu)}
+	stderrLines := &lineWriter{buf: &stderr, emit: lineEmitter(host, Stderr, opts, mu)}
+
+	cmd := exec.CommandContext(ctx, opts.SSHPath, Args(host, command, opts.Timeout)...)
+	cmd.Stdout = stdoutLines
+	cmd.Stderr = stderrLines
+	// Don't wait forever on pipes held open by orphaned children
+	cmd.WaitDelay = time.Second
+
+	start := time.Now()
+	err := cmd.Run()
+	stdoutLines.Flush()
+	stderrLines.Flush()
+
+	result := Result{
+		Host:     host,
+		ExitCode: -1,
+		Duration: time.Since(start),
+		Stdout:   stdout.String(),
+		Stderr:   stderr.String(),
+	}
+
+	var exitErr *exec.ExitError
+	switch {
+	case errors.Is(ctx.Err(), context.DeadlineExceeded):
+		result.TimedOut = true
+		result.Err = fmt.Errorf("timed out after %s", opts.Timeout)
+	case ctx.Err() != nil:
+		result.Err = ctx.Err()
+	case err == nil:
+		result.ExitCode = 0
+	case errors.As(err, &exitErr) && exitErr.Exited():
+		result.ExitCode = exitErr.ExitCode()
+	default:
+		result.Err = err
+	}
+	return result
+}
+
+// lineEmitter returns a function passing lines to opts.OnLine under mu.
+func lineEmitter(host string, stream Stream, opts Options, mu *sync.Mutex) func(string) {
+	if opts.OnLine == nil {
+		return nil
+	}
+	return func(line string) {
+		mu.Lock()
+		defer mu.Unlock()
+		opts.OnLine(host, stream, line)
+	}
+}
+
+// lineWriter records everything written to it and calls emit once per line.
+type lineWriter struct {
+	buf     *bytes.Buffer
+	partial []byte
+	emit    func(string)
+}
+
+func (w *lineWriter) Write(p []byte) (int, error) {
+	w.buf.Write(p)
+	if w.emit == nil {
+		return len(p), nil
+	}
+	w.partial = append(w.partial, p...)
+	for {
+		i := bytes.IndexByte(w.partial, '\n')
+		if i < 0 {
+			break
+		}
+		w.emit(string(bytes.TrimSuffix(w.partial[:i], []byte("\r"))))
+		w.partial = w.partial[i+1:]
+	}
+	return len(p), nil
+}
+
+// Flush emits any trailing output that did not end with a newline.
+func (w *lineWriter) Flush() {
+	if w.emit != nil && len(w.partial) > 0 {
+		w.emit(string(w.partial))
+		w.partial = nil
+	}
+}
@@ -0,0 +1,100 @@
+package remote
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeSSH is a stand-in for ssh that behaves according to the host name.
+const fakeSSH = `#!/bin/sh
+while [ "$1" = "-o" ]; do shift 2; done
+host=$1
+shift 2
+case $host in
+	ok*) echo "$host: $*"; echo "warning" >&2 ;;
+	fail*) echo "boom" >&2; exit 3 ;;
+	hang*) sleep 10 ;;
+	down*) echo "ssh: connect to host $host port 22: Connection refused" >&2; exit 255 ;;
+esac
+`
+
+func writeFakeSSH(t *testing.T) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "ssh")
+	if err := os.WriteFile(path, []byte(fakeSSH), 0755); err != nil {
+		t.Fatalf("Failed to write fake ssh: %v", err)
+	}
+	return path
+}
+
+func TestRun(t *testing.T) {
+	hosts := []string{"ok1", "fail1", "down1", "ok2"}
+	var lines []string
+	opts := Options{
+		SSHPath: writeFakeSSH(t),
+		Workers: 2,
+		OnLine: func(host string, stream Stream, line string) {
+			if stream == Stdout {
+				lines = append(lines, line)
+			}
+		},
+	}
+
+	results := Run(context.Background(), hosts, []string{"uptime", "-p"}, opts)
+
+	if len(results) != len(hosts) {
+		t.Fatalf("Expected %d results, got %d", len(hosts), len(results))
+	}
+
+	expectedCodes := []int{0, 3, 255, 0}
+	for i, result := range results {
+		if result.Host != hosts[i] {
+			t.Errorf("Expected result %d for %s, got %s", i, hosts[i], result.Host)
+		}
+		if result.ExitCode != expectedCodes[i] {
+			t.Errorf("Expected %s to exit %d, got %d", hosts[i], expectedCodes[i], result.ExitCode)
+		}
+	}
+
+	if results[0].Stdout != "ok1: uptime -p\n" {
+		t.Errorf("Expected stdout 'ok1: uptime -p', got %q", results[0].Stdout)
+	}
+	if results[1].Stderr != "boom\n" {
+		t.Errorf("Expected stderr 'boom', got %q", results[1].Stderr)
+	}
+	if !results[0].OK() || results[1].OK() {
+		t.Error("Expected only successful hosts to be OK")
+	}
+	if len(lines) != 2 {
+		t.Errorf("Expected 2 stdout lines, got %v", lines)
+	}
+}
+
+func TestRun_Timeout(t *testing.T) {
+	opts := Options{SSHPath: writeFakeSSH(t), Timeout: 200 * time.Millisecond}
+
+	start := time.Now()
+	results := Run(context.Background(), []string{"hang1", "ok1"}, []string{"true"}, opts)
+
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("Expected hanging host to be killed, run took %s", elapsed)
+	}
+	if !results[0].TimedOut || results[0].OK() {
+		t.Errorf("Expected hang1 to time out, got %+v", results[0])
+	}
+	if !results[1].OK() {
+		t.Errorf("Expected ok1 to succeed, got %+v", results[1])
+	}
+}
+
+func TestArgs(t *testing.T) {
+	args := Args("web", []string{"uptime"}, 5*time.Second)
+	expected := "-o BatchMode=yes -o ConnectTimeout=5 web -- uptime"
+	if strings.Join(args, " ") != expected {
+		t.Errorf("Expected '%s', got '%s'", expected, strings.Join(args, " "))
+	}
+}
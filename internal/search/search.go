@@ -0,0 +1,269 @@
+// Package search implements the host query language used to filter SSH hosts.
+//
+// A query is a whitespace-separated list of terms that must all match. A term
+// is either free text, which is fuzzy-matched against every searchable field,
+// or a fielded term such as "user:deploy", "tag:prod" or "port:2222". Any term
+// can be negated with a leading "-", e.g. "-tag:legacy".
+package search
+
+import (
+	"strings"
+
+	"github.com/antonjah/ssm/internal/config"
+	"github.com/sahilm/fuzzy"
+)
+
+// Field identifies a searchable attribute of a host.
+type Field int
+
+const (
+	// FieldAny matches free-text terms against every field.
+	FieldAny Field = iota
+	// FieldAlias is the host alias.
+	FieldAlias
+	// FieldHostName is the configured HostName.
+	FieldHostName
+	// FieldUser is the configured User.
+	FieldUser
+	// FieldPort is the configured Port.
+	FieldPort
+	// FieldTag is one of the host's ssm tags.
+	FieldTag
+	// FieldDescription is the host's ssm description.
+	FieldDescription
+)
+
+// fieldNames maps query prefixes to the field they select.
+var fieldNames = map[string]Field{
+	"alias":       FieldAlias,
+	"host":        FieldHostName,
+	"hostname":    FieldHostName,
+	"user":        FieldUser,
+	"port":        FieldPort,
+	"tag":         FieldTag,
+	"tags":        FieldTag,
+	"desc":        FieldDescription,
+	"description": FieldDescription,
+}
+
+// String returns the display name of the field.
+func (f Field) String() string {
+	switch f {
+	case FieldAlias:
+		return "Alias"
+	case FieldHostName:
+		return "HostName"
+	case FieldUser:
+		return "User"
+	case FieldPort:
+		return "Port"
+	case FieldTag:
+		return "Tag"
+	case FieldDescription:
+		return "Description"
+	}
+	return "Any"
+}
+
+// Term is a single parsed query term.
+type Term struct {
+	Field  Field
+	Value  string
+	Negate bool
+}
+
+// Query is a parsed host query.
+type Query struct {
+	Terms []Term
+}
+
+// Match describes how a host matched a query.
+type Match struct {
+	// Score ranks free-text matches; higher is better.
+	Score int
+	// Field is the first non-alias field that matched, or FieldAlias.
+	Field Field
+	// Value is the text of Field that matched.
+	Value string
+	// Indexes are the matched rune positions within Value.
+	Indexes []int
+	// AliasIndexes are the matched rune positions within the alias.
+	AliasIndexes []int
+}
+
+// Parse parses a query string. Prefixes that are not known field names are
+// treated as free text so that values such as IPv6 addresses still work.
+func Parse(query string) Query {
+	var q Query
+	for _, word := range strings.Fields(query) {
+		term := Term{Field: FieldAny, Value: word}
+		if strings.HasPrefix(word, "-") && len(word) > 1 {
+			term.Negate = true
+			term.Value = word[1:]
+		}
+		if name, value, ok := strings.Cut(term.Value, ":"); ok {
+			if field, known := fieldNames[strings.ToLower(name)]; known {
+				term.Field = field
+				term.Value = value
+			}
+		}
+		if term.Value == "" {
+			continue
+		}
+		q.Terms = append(q.Terms, term)
+	}
+	return q
+}
+
+// Empty reports whether the query has no terms.
+func (q Query) Empty() bool {
+	return len(q.Terms) == 0
+}
+
+// Match reports whether the host satisfies every term of the query.
+func (q Query) Match(host config.Host) (Match, bool) {
+	match := Match{Field: FieldAlias, Value: host.Alias}
+	for _, term := range q.Terms {
+		field, value, indexes, score, ok := matchTerm(term, host)
+		if term.Negate {
+			if ok {
+				return Match{}, false
+			}
+			continue
+		}
+		if !ok {
+			return Match{}, false
+		}
+		match.Score += score
+		if field == FieldAlias {
+			match.AliasIndexes = mergeIndexes(match.AliasIndexes, indexes)
+		} else if match.Field == FieldAlias {
+			match.Field = field
+			match.Value = value
+			match.Indexes = indexes
+		}
+	}
+	return match, true
+}
+
+// matchTerm matches a single term and returns the field it matched on.
+func matchTerm(term Term, host config.Host) (Field, string, []int, int, bool) {
+	if term.Field != FieldAny {
+		for _, value := range fieldValues(host, term.Field) {
+			if indexes, ok := matchField(term.Field, term.Value, value); ok {
+				return term.Field, value, indexes, 0, true
+			}
+		}
+		return term.Field, "", nil, 0, false
+	}
+
+	if term.Negate {
+		for _, field := range []Field{FieldAlias, FieldHostName, FieldUser, FieldPort, FieldTag, FieldDescription} {
+			for _, value := range fieldValues(host, field) {
+				if containsFold(value, term.Value) {
+					return field, value, nil, 0, true
+				}
+			}
+		}
+		return FieldAny, "", nil, 0, false
+	}
+
+	best := fuzzy.Match{Score: -1}
+	bestField := FieldAny
+	for _, field := range []Field{FieldAlias, FieldHostName, FieldUser, FieldPort, FieldTag, FieldDescription} {
+		for _, value := range fieldValues(host, field) {
+			matches := fuzzy.Find(term.Value, []string{value})
+			if len(matches) == 0 {
+				continue
+			}
+			// Prefer alias matches when scores tie so the title gets highlighted.
+			if matches[0].Score > best.Score {
+				best = matches[0]
+				bestField = field
+			}
+		}
+	}
+	if bestField == FieldAny {
+		return FieldAny, "", nil, 0, false
+	}
+	return bestField, best.Str, best.MatchedIndexes, best.Score, true
+}
+
+// matchField matches a fielded term against a single field value.
+// Ports and tags must match exactly; other fields match on substrings.
+func matchField(field Field, want, value string) ([]int, bool) {
+	switch field {
+	case FieldPort, FieldTag:
+		if !strings.EqualFold(want, value) {
+			return nil, false
+		}
+		return runeRange(0, len([]rune(value))), true
+	}
+	start := indexFold(value, want)
+	if start < 0 {
+		return nil, false
+	}
+	return runeRange(start, len([]rune(want))), true
+}
+
+// fieldValues returns the searchable values of a host for the given field.
+func fieldValues(host config.Host, field Field) []string {
+	var value string
+	switch field {
+	case FieldAlias:
+		value = host.Alias
+	case FieldHostName:
+		value = host.HostName
+	case FieldUser:
+		value = host.User
+	case FieldPort:
+		value = host.Port
+	case FieldTag:
+		return host.Tags
+	case FieldDescription:
+		value = host.Description
+	}
+	if value == "" {
+		return nil
+	}
+	return []string{value}
+}
+
+// indexFold returns the rune index of the first case-insensitive occurrence
+// of substr in s, or -1 if there is none.
+func indexFold(s, substr string) int {
+	runes := []rune(strings.ToLower(s))
+	needle := []rune(strings.ToLower(substr))
+	for i := 0; i+len(needle) <= len(runes); i++ {
+		if string(runes[i:i+len(needle)]) == string(needle) {
+			return i
+		}
+	}
+	return -1
+}
+
+func containsFold(s, substr string) bool {
+	return indexFold(s, substr) >= 0
+}
+
+func runeRange(start, n int) []int {
+	indexes := make([]int, n)
+	for i := range indexes {
+		indexes[i] = start + i
+	}
+	return indexes
+}
+
+func mergeIndexes(a, b []int) []int {
+	seen := make(map[int]bool, len(a))
+	for _, i := range a {
+		seen[i] = true
+	}
+	for _, i := range b {
+		if !seen[i] {
+			a = append(a, i)
+			seen[i] = true
+		}
+	}
+	return a
+}
@@ -0,0 +1,86 @@
+package search
+
+import (
+	"testing"
+
+	"github.com/antonjah/ssm/internal/config"
+)
+
+var testHost = config.Host{
+	Alias:       "web-1",
+	HostName:    "10.0.0.5",
+	User:        "deploy",
+	Port:        "2222",
+	Tags:        []string{"prod", "nginx"},
+	Description: "Frontend server",
+}
+
+func TestParse(t *testing.T) {
+	query := Parse("web user:deploy -tag:legacy fe80::1 -")
+
+	expected := []Term{
+		{Field: FieldAny, Value: "web"},
+		{Field: FieldUser, Value: "deploy"},
+		{Field: FieldTag, Value: "legacy", Negate: true},
+		{Field: FieldAny, Value: "fe80::1"},
+		{Field: FieldAny, Value: "-"},
+	}
+
+	if len(query.Terms) != len(expected) {
+		t.Fatalf("Expected %d terms, got %d", len(expected), len(query.Terms))
+	}
+	for i, term := range query.Terms {
+		if term != expected[i] {
+			t.Errorf("Expected term %+v, got %+v", expected[i], term)
+		}
+	}
+}
+
+func TestQuery_Match(t *testing.T) {
+	tests := []struct {
+		query string
+		match bool
+		field Field
+	}{
+		{"web", true, FieldAlias},
+		{"10.0.0", true, FieldHostName},
+		{"user:deploy", true, FieldUser},
+		{"user:root", false, FieldAny},
+		{"tag:prod", true, FieldTag},
+		{"tag:pro", false, FieldAny},
+		{"port:2222", true, FieldPort},
+		{"port:22", false, FieldAny},
+		{"-tag:legacy", true, FieldAlias},
+		{"-tag:prod", false, FieldAny},
+		{"desc:frontend", true, FieldDescription},
+		{"tag:nginx -user:root", true, FieldTag},
+		{"nomatch", false, FieldAny},
+	}
+
+	for _, test := range tests {
+		match, ok := Parse(test.query).Match(testHost)
+		if ok != test.match {
+			t.Errorf("Query %q: expected match %v, got %v", test.query, test.match, ok)
+			continue
+		}
+		if ok && match.Field != test.field {
+			t.Errorf("Query %q: expected field %v, got %v", test.query, test.field, match.Field)
+		}
+	}
+}
+
+func TestQuery_MatchIndexes(t *testing.T) {
+	match, ok := Parse("user:PLO").Match(testHost)
+	if !ok {
+		t.Fatal("Expected query to match")
+	}
+	expected := []int{2, 3, 4}
+	if len(match.Indexes) != len(expected) {
+		t.Fatalf("Expected indexes %v, got %v", expected, match.Indexes)
+	}
+	for i, index := range match.Indexes {
+		if index != expected[i] {
+			t.Errorf("Expected indexes %v, got %v", expected, match.Indexes)
+		}
+	}
+}
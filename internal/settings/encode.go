@@ -0,0 +1,185 @@
+package settings
+
+import (
+	"bufio"
+	"encoding"
+	"fmt"
+	"io"
+	"reflect"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Encode writes the settings to w in the settings file's format.
+func (s Settings) Encode(w io.Writer) error {
+	b := bufio.NewWriter(w)
+	v := reflect.ValueOf(s)
+	first := true
+	for i := range v.NumField() {
+		name := v.Type().Field(i).Tag.Get("toml")
+		field := v.Field(i)
+		if !first {
+			b.WriteString("\n")
+		}
+		first = false
+		switch {
+		case field.Kind() == reflect.Struct:
+			fmt.Fprintf(b, "[%s]\n", quoteKey(name))
+			if err := encodeTable(b, quoteKey(name), field); err != nil {
+				return fmt.Errorf("%s: %w", name, err)
+			}
+		case field.Kind() == reflect.Map && field.Type().Elem().Kind() == reflect.Map:
+			fmt.Fprintf(b, "[%s]\n", quoteKey(name))
+			for _, key := range sortedKeys(field) {
+				fmt.Fprintf(b, "\n[%s.%s]\n", quoteKey(name), quoteKey(key))
+				if err := encodeMap(b, field.MapIndex(reflect.ValueOf(key))); err != nil {
+					return fmt.Errorf("%s.%s: %w", name, key, err)
+				}
+			}
+		case field.Kind() == reflect.Map:
+			fmt.Fprintf(b, "[%s]\n", quoteKey(name))
+			if err := encodeMap(b, field); err != nil {
+				return fmt.Errorf("%s: %w", name, err)
+			}
+		case field.Kind() == reflect.Slice && field.Type().Elem().Kind() == reflect.Struct:
+			if field.Len() == 0 {
+				fmt.Fprintf(b, "# no [[%s]]\n", name)
+			}
+			for j := range field.Len() {
+				if j > 0 {
+					b.WriteString("\n")
+				}
+				fmt.Fprintf(b, "[[%s]]\n", quoteKey(name))
+				if err := encodeTable(b, "", field.Index(j)); err != nil {
+					return fmt.Errorf("%s[%d]: %w", name, j, err)
+				}
+			}
+		default:
+			return fmt.Errorf("%s: unsupported setting type %s", name, field.Type())
+		}
+	}
+	return b.Flush()
+}
+
+// encodeTable writes a struct's fields as key = value lines, followed by
+// its maps as tables nested in the table called path.
+func encodeTable(w io.Writer, path string, v reflect.Value) error {
+	var maps []int
+	for i := range v.NumField() {
+		name := v.Type().Field(i).Tag.Get("toml")
+		if name == "" || name == "-" {
+			continue
+		}
+		if v.Field(i).Kind() == reflect.Map && path != "" {
+			maps = append(maps, i)
+			continue
+		}
+		value, err := encodeValue(v.Field(i))
+		if err != nil {
+			return fmt.Errorf("%s: %w", name, err)
+		}
+		fmt.Fprintf(w, "%s = %s\n", quoteKey(name), value)
+	}
+	for _, i := range maps {
+		name := v.Type().Field(i).Tag.Get("toml")
+		fmt.Fprintf(w, "\n[%s.%s]\n", path, quoteKey(name))
+		if err := encodeMap(w, v.Field(i)); err != nil {
+			return fmt.Errorf("%s: %w", name, err)
+		}
+	}
+	return nil
+}
+
+// encodeMap writes a map's entries as key = value lines, sorted by key.
+func encodeMap(w io.Writer, v reflect.Value) error {
+	for _, key := range sortedKeys(v) {
+		value, err := encodeValue(v.MapIndex(reflect.ValueOf(key)))
+		if err != nil {
+			return fmt.Errorf("%s: %w", key, err)
+		}
+		fmt.Fprintf(w, "%s = %s\n", quoteKey(key), value)
+	}
+	return nil
+}
+
+func sortedKeys(v reflect.Value) []string {
+	keys := make([]string, 0, v.Len())
+	for _, key := range v.MapKeys() {
+		keys = append(keys, key.String())
+	}
+	slices.Sort(keys)
+	return keys
+}
+
+// encodeValue formats a value the way it would be written in the file.
+func encodeValue(v reflect.Value) (string, error) {
+	if marshaler, ok := v.Interface().(encoding.TextMarshaler); ok {
+		text, err := marshaler.MarshalText()
+		if err != nil {
+			return "", err
+		}
+		return quoteString(string(text)), nil
+	}
+	switch v.Kind() {
+	case reflect.String:
+		return quoteString(v.String()), nil
+	case reflect.Bool:
+		return strconv.FormatBool(v.Bool()), nil
+	case reflect.Int, reflect.Int64:
+		return strconv.FormatInt(v.Int(), 10), nil
+	case reflect.Float64:
+		return strconv.FormatFloat(v.Float(), 'g', -1, 64), nil
+	case reflect.Slice:
+		items := make([]string, v.Len())
+		for i := range v.Len() {
+			item, err := encodeValue(v.Index(i))
+			if err != nil {
+				return "", err
+			}
+			items[i] = item
+		}
+		return "[" + strings.Join(items, ", ") + "]", nil
+	}
+	return "", fmt.Errorf("unsupported setting type %s", v.Type())
+}
+
+// bareKey matches the keys that needn't be quoted.
+var bareKey = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+// quoteKey quotes key unless it is a bare key.
+func quoteKey(key string) string {
+	if bareKey.MatchString(key) {
+		return key
+	}
+	return quoteString(key)
+}
+
+// quoteString returns s as a basic string.
+func quoteString(s string) string {
+	var b strings.Builder
+	b.WriteByte('"')
+	for _, r := range s {
+		switch r {
+		case '"':
+			b.WriteString(`\"`)
+		case '\\':
+			b.WriteString(`\\`)
+		case '\n':
+			b.WriteString(`\n`)
+		case '\t':
+			b.WriteString(`\t`)
+		case '\r':
+			b.WriteString(`\r`)
+		default:
+			if r < 0x20 || r == 0x7f {
+				fmt.Fprintf(&b, `\u%04X`, r)
+			} else {
+				b.WriteRune(r)
+			}
+		}
+	}
+	b.WriteByte('"')
+	return b.String()
+}
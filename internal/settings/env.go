@@ -0,0 +1,76 @@
+package settings
+
+import (
+	"encoding"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// EnvName returns the environment variable that overrides key in section,
+// e.g. SSM_RECORD_DIR for dir in [record].
+func EnvName(section, key string) string {
+	return "SSM_" + strings.ToUpper(section+"_"+key)
+}
+
+// overrideFromEnv sets the settings of s's sections that have a variable in
+// vars.
+func overrideFromEnv(s *Settings, vars map[string]string) error {
+	v := reflect.ValueOf(s).Elem()
+	for i := range v.NumField() {
+		section := v.Field(i)
+		if section.Kind() != reflect.Struct {
+			continue
+		}
+		sectionName := v.Type().Field(i).Tag.Get("toml")
+		for j := range section.NumField() {
+			name := EnvName(sectionName, section.Type().Field(j).Tag.Get("toml"))
+			value, ok := vars[name]
+			if !ok {
+				continue
+			}
+			if err := setFromString(section.Field(j), value); err != nil {
+				return fmt.Errorf("%s: %w", name, err)
+			}
+		}
+	}
+	return nil
+}
+
+// setFromString parses value into field. Lists are separated by commas.
+func setFromString(field reflect.Value, value string) error {
+	if unmarshaler, ok := field.Addr().Interface().(encoding.TextUnmarshaler); ok {
+		return unmarshaler.UnmarshalText([]byte(value))
+	}
+	switch field.Kind() {
+	case reflect.String:
+		field.SetString(value)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("expected true or false, got %q", value)
+		}
+		field.SetBool(b)
+	case reflect.Int:
+		n, err := strconv.Atoi(value)
+		if err != nil {
+			return fmt.Errorf("expected an integer, got %q", value)
+		}
+		field.SetInt(int64(n))
+	case reflect.Slice:
+		if field.Type().Elem().Kind() != reflect.String {
+			return fmt.Errorf("can't be set from the environment")
+		}
+		items := []string{}
+		for _, item := range strings.Split(value, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				items = append(items, item)
+			}
+		}
+		field.Set(reflect.ValueOf(items))
+	default:
+		return fmt.Errorf("can't be set from the environment")
+	}
+	return nil
+}
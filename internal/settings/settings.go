@@ -0,0 +1,487 @@
+// Package settings reads ssm's own configuration file,
+// $XDG_CONFIG_HOME/ssm/config.toml. Every setting has a default, and
+// SSM_<SECTION>_<KEY> environment variables override the file, e.g.
+// SSM_RECORD_DIR for dir in the [record] section.
+//
+// The settings are plain data. The packages they configure read them and
+// check the values only they understand, such as theme colours and key
+// bindings.
+package settings
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/antonjah/ssm/internal/config"
+	"github.com/antonjah/ssm/internal/xdg"
+)
+
+// PathEnv overrides the settings file's location.
+const PathEnv = "SSM_CONFIG"
+
+// TransportEnvPrefix is the prefix of environment variables that define
+// custom transports, e.g. SSM_TRANSPORT_TSH="tsh ssh {host}" defines "tsh".
+const TransportEnvPrefix = "SSM_TRANSPORT_"
+
+// Audit log paths that turn auditing off, the default, or on at the default
+// location.
+const (
+	AuditOff = "off"
+	AuditOn  = "on"
+)
+
+// Hook events.
+const (
+	PreConnect  = "pre-connect"
+	PostConnect = "post-connect"
+)
+
+// DefaultHookTimeout is how long a hook may run when it doesn't set a
+// timeout.
+const DefaultHookTimeout = 30 * time.Second
+
+// Ways of confirming a connection to a protected host.
+const (
+	// ConfirmAlias asks for the host's alias to be typed.
+	ConfirmAlias = "alias"
+	// ConfirmYes asks a y/N question.
+	ConfirmYes = "yes"
+)
+
+// Ways of showing the hosts in the menu.
+const (
+	// ViewList shows each host on two lines.
+	ViewList = "list"
+	// ViewTable shows each host on one line with a column per field.
+	ViewTable = "table"
+)
+
+// TableColumns lists the columns the table view can show, in their default
+// order.
+var TableColumns = []string{"alias", "hostname", "user", "port", "tags", "last", "status"}
+
+// DefaultProtectedStyle is the tmux window style of protected hosts.
+const DefaultProtectedStyle = "bg=red,fg=white,bold"
+
+// Settings is the contents of the settings file.
+type Settings struct {
+	Menu  Menu  `toml:"menu"`
+	Theme Theme `toml:"theme"`
+	// Themes maps the names of custom themes to their colour slots and
+	// theme.BaseKey.
+	Themes  map[string]map[string]string `toml:"themes"`
+	Keys    Keys                         `toml:"keys"`
+	Tmux    Tmux                         `toml:"tmux"`
+	Connect Connect                      `toml:"connect"`
+	// Transports maps names to command templates, adding to or replacing
+	// the built-in transports.
+	Transports map[string]string `toml:"transports"`
+	Record     Record            `toml:"record"`
+	Audit      Audit             `toml:"audit"`
+	Notes      Notes             `toml:"notes"`
+	Hooks      []Hook            `toml:"hooks"`
+	Protect    Protect           `toml:"protect"`
+}
+
+// Menu configures the interactive host menu.
+type Menu struct {
+	// Probe checks each host's reachability in the background.
+	Probe bool `toml:"probe"`
+	// Banner also reads each host's SSH banner while probing.
+	Banner bool `toml:"banner"`
+	// Height caps the number of lines the list uses, or 0 to fill the
+	// terminal.
+	Height int `toml:"height"`
+	// Descriptions shows each host's HostName and description under its
+	// alias.
+	Descriptions bool `toml:"descriptions"`
+	// Preview shows the highlighted host's details beside the list on
+	// terminals wide enough for both.
+	Preview bool `toml:"preview"`
+	// View is ViewList or ViewTable.
+	View string `toml:"view"`
+	// Columns are the table view's columns, from TableColumns, in order.
+	Columns []string `toml:"columns"`
+	// Mouse lets hosts be picked with the mouse. It is on by default; turning
+	// it off leaves text selection to the terminal.
+	Mouse bool `toml:"mouse"`
+}
+
+// Theme selects the menu's colours.
+type Theme struct {
+	// Name is a built-in or custom theme, or theme.Auto to use Light or Dark
+	// to suit the terminal's background.
+	Name  string `toml:"name"`
+	Light string `toml:"light"`
+	Dark  string `toml:"dark"`
+}
+
+// Keys configures the menu's key bindings.
+type Keys struct {
+	// Preset is keys.Default, keys.Vim or keys.Emacs.
+	Preset string `toml:"preset"`
+	// Bindings maps actions to the keys that trigger them instead of the
+	// preset's. An empty list unbinds an action.
+	Bindings map[string][]string `toml:"bindings"`
+}
+
+// Tmux configures the names of the windows ssm opens.
+type Tmux struct {
+	// WindowPrefix is prepended to a host's alias to name its window.
+	WindowPrefix string `toml:"window_prefix"`
+	// TiledWindow names the window of hosts opened tiled.
+	TiledWindow string `toml:"tiled_window"`
+	// ClusterWindow names cluster windows, followed by ":<tag>" when the
+	// hosts share a tag.
+	ClusterWindow string `toml:"cluster_window"`
+	// SyncKey, pressed after the tmux prefix, toggles synchronize-panes in
+	// cluster windows. It is bound for the whole tmux server, replacing any
+	// other binding of the key, and empty leaves tmux's bindings alone.
+	SyncKey string `toml:"sync_key"`
+}
+
+// Connect configures how sessions are opened.
+type Connect struct {
+	// Transport is used for hosts without "# ssm:via" metadata.
+	Transport string `toml:"transport"`
+	// Reconnect reconnects with backoff when a connection is lost.
+	Reconnect bool `toml:"reconnect"`
+}
+
+// Record configures session recording.
+type Record struct {
+	// All records every session.
+	All bool `toml:"all"`
+	// Dir is where recordings are saved.
+	Dir string `toml:"dir"`
+	// Tags are the tags whose hosts are always recorded.
+	Tags []string `toml:"tags"`
+}
+
+// Audit configures the audit log.
+type Audit struct {
+	// Log is the audit log's path, AuditOn or AuditOff.
+	Log string `toml:"log"`
+}
+
+// Path returns the audit log's path, which is defaultPath for AuditOn, or
+// "" if auditing is off.
+func (a Audit) Path(defaultPath string) string {
+	switch {
+	case strings.EqualFold(a.Log, AuditOff):
+		return ""
+	case strings.EqualFold(a.Log, AuditOn):
+		return defaultPath
+	}
+	return a.Log
+}
+
+// Notes configures per-host notes.
+type Notes struct {
+	// Dir is where notes are kept.
+	Dir string `toml:"dir"`
+}
+
+// Default returns the defaults of the settings this package owns. The
+// theme, key preset, tmux window names, transport and the recording and
+// notes directories are left for the packages they configure to fill in.
+func Default() Settings {
+	return Settings{
+		Menu:       Menu{Probe: true, Descriptions: true, View: ViewList, Columns: slices.Clone(TableColumns), Mouse: true},
+		Themes:     map[string]map[string]string{},
+		Keys:       Keys{Bindings: map[string][]string{}},
+		Transports: map[string]string{},
+		Record:     Record{Tags: []string{}},
+		Audit:      Audit{Log: AuditOff},
+		Protect: Protect{
+			Hosts:       []string{},
+			Tags:        []string{},
+			Confirm:     ConfirmAlias,
+			WindowStyle: DefaultProtectedStyle,
+		},
+	}
+}
+
+// Protect marks hosts where a mistake is expensive, such as production.
+// Connecting to them or running commands on them from the menu has to be
+// confirmed, and they stand out in the menu and in tmux.
+type Protect struct {
+	// Hosts and Tags select the protected hosts like a Hook's do, except
+	// that without either no host is protected.
+	Hosts []string `toml:"hosts"`
+	Tags  []string `toml:"tags"`
+	// Confirm is ConfirmAlias or ConfirmYes. Defaults to ConfirmAlias.
+	Confirm string `toml:"confirm"`
+	// WindowStyle is the tmux style of protected hosts' windows. Defaults to
+	// DefaultProtectedStyle.
+	WindowStyle string `toml:"window_style"`
+}
+
+// Matches reports whether host is protected.
+func (p Protect) Matches(host config.Host) bool {
+	return matches(p.Hosts, p.Tags, host)
+}
+
+// Style returns the tmux window style for protected hosts.
+func (p Protect) Style() string {
+	if p.WindowStyle != "" {
+		return p.WindowStyle
+	}
+	return DefaultProtectedStyle
+}
+
+// Hook is a command run before or after connecting to a host.
+type Hook struct {
+	// Name identifies the hook in messages. Defaults to the command.
+	Name string `toml:"name"`
+	// Event is PreConnect or PostConnect.
+	Event string `toml:"event"`
+	// Command is run with sh -c.
+	Command string `toml:"command"`
+	// Hosts and Tags limit the hook to hosts whose alias matches one of the
+	// patterns or that have one of the tags. Without either it runs for
+	// every host.
+	Hosts []string `toml:"hosts"`
+	Tags  []string `toml:"tags"`
+	// Timeout stops the hook if it runs longer. Defaults to
+	// DefaultHookTimeout.
+	Timeout Duration `toml:"timeout"`
+}
+
+// String returns the hook's name or command.
+func (h Hook) String() string {
+	if h.Name != "" {
+		return h.Name
+	}
+	return h.Command
+}
+
+// Matches reports whether the hook runs for host.
+func (h Hook) Matches(host config.Host) bool {
+	if len(h.Hosts) == 0 && len(h.Tags) == 0 {
+		return true
+	}
+	return matches(h.Hosts, h.Tags, host)
+}
+
+// Deadline returns how long the hook may run.
+func (h Hook) Deadline() time.Duration {
+	if h.Timeout > 0 {
+		return time.Duration(h.Timeout)
+	}
+	return DefaultHookTimeout
+}
+
+// HooksFor returns the hooks that run for host, in the order they are
+// configured.
+func (s Settings) HooksFor(host config.Host) []Hook {
+	var hooks []Hook
+	for _, hook := range s.Hooks {
+		if hook.Matches(host) {
+			hooks = append(hooks, hook)
+		}
+	}
+	return hooks
+}
+
+// matches reports whether host's alias matches one of patterns or it has
+// one of tags.
+func matches(patterns, tags []string, host config.Host) bool {
+	for _, pattern := range patterns {
+		if ok, _ := path.Match(pattern, host.Alias); ok {
+			return true
+		}
+	}
+	for _, tag := range tags {
+		for _, hostTag := range host.Tags {
+			if strings.EqualFold(tag, hostTag) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Duration is a time.Duration written as a string such as "10s" or "2m".
+type Duration time.Duration
+
+// UnmarshalText parses a duration.
+func (d *Duration) UnmarshalText(text []byte) error {
+	parsed, err := time.ParseDuration(string(text))
+	if err != nil {
+		return fmt.Errorf("invalid duration %q, use a value like \"10s\" or \"2m\"", text)
+	}
+	*d = Duration(parsed)
+	return nil
+}
+
+// MarshalText formats the duration.
+func (d Duration) MarshalText() ([]byte, error) {
+	return []byte(time.Duration(d).String()), nil
+}
+
+// DefaultPath returns the settings file's location: $SSM_CONFIG, or
+// config.toml in ssm's configuration directory.
+func DefaultPath() (string, error) {
+	if p := os.Getenv(PathEnv); p != "" {
+		return p, nil
+	}
+	dir, err := xdg.ConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "config.toml"), nil
+}
+
+// Load returns defaults overridden by the settings file at p, which may be
+// missing, and then by the environment.
+func Load(p string, defaults Settings) (Settings, error) {
+	s := defaults
+	data, err := os.ReadFile(p)
+	if err != nil && !os.IsNotExist(err) {
+		return s, err
+	}
+
+	if err == nil {
+		if err := decode(data, &s); err != nil {
+			return s, fmt.Errorf("%s: %w", p, err)
+		}
+		// Transport names are case-insensitive like the built-in ones
+		transports := make(map[string]string, len(s.Transports))
+		for name, template := range s.Transports {
+			transports[strings.ToLower(name)] = template
+		}
+		s.Transports = transports
+	}
+	if err := s.applyEnv(os.Environ()); err != nil {
+		return s, err
+	}
+	for i := range s.Hooks {
+		if s.Hooks[i].Timeout == 0 {
+			s.Hooks[i].Timeout = Duration(DefaultHookTimeout)
+		}
+	}
+	if err := s.Validate(); err != nil {
+		return s, fmt.Errorf("%s: %w", p, err)
+	}
+	return s, nil
+}
+
+// applyEnv overrides settings from the SSM_<SECTION>_<KEY> variables in
+// environ and adds the transports defined by TransportEnvPrefix variables.
+// Empty variables are ignored.
+func (s *Settings) applyEnv(environ []string) error {
+	vars := make(map[string]string)
+	for _, env := range environ {
+		key, value, _ := strings.Cut(env, "=")
+		if value == "" {
+			continue
+		}
+		vars[key] = value
+		if name, ok := strings.CutPrefix(key, TransportEnvPrefix); ok && name != "" {
+			if s.Transports == nil {
+				s.Transports = make(map[string]string)
+			}
+			s.Transports[strings.ToLower(name)] = value
+		}
+	}
+	return overrideFromEnv(s, vars)
+}
+
+// validName matches the names custom transports and themes may have.
+var validName = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+// Validate checks settings that parse but make no sense. The settings of
+// other packages, such as theme colours and key bindings, are only checked
+// for what this package knows of them. Problems are reported in the order
+// of the file's sections, and of the names in tables.
+func (s Settings) Validate() error {
+	var errs []error
+	fail := func(p, format string, args ...any) {
+		errs = append(errs, &Error{Path: p, Message: fmt.Sprintf(format, args...)})
+	}
+
+	if s.Menu.Height < 0 {
+		fail("menu.height", "must not be negative")
+	}
+	if v := s.Menu.View; v != ViewList && v != ViewTable {
+		fail("menu.view", "must be %q or %q, got %q", ViewList, ViewTable, v)
+	}
+	if len(s.Menu.Columns) == 0 {
+		fail("menu.columns", "at least one column is needed")
+	}
+	for i, column := range s.Menu.Columns {
+		p := fmt.Sprintf("menu.columns[%d]", i)
+		if !slices.Contains(TableColumns, column) {
+			fail(p, "unknown column %q (expected one of %s)", column, strings.Join(TableColumns, ", "))
+		} else if slices.Index(s.Menu.Columns, column) < i {
+			fail(p, "column %q is listed twice", column)
+		}
+	}
+	for _, name := range slices.Sorted(maps.Keys(s.Themes)) {
+		if !validName.MatchString(name) {
+			fail("themes."+name, "theme names may only contain letters, digits, - and _")
+		}
+	}
+	names := []struct{ path, name string }{
+		{"tmux.tiled_window", s.Tmux.TiledWindow},
+		{"tmux.cluster_window", s.Tmux.ClusterWindow},
+	}
+	for _, n := range names {
+		if strings.TrimSpace(n.name) == "" {
+			fail(n.path, "must not be empty")
+		}
+	}
+	for _, name := range slices.Sorted(maps.Keys(s.Transports)) {
+		p := "transports." + name
+		if !validName.MatchString(name) {
+			fail(p, "transport names may only contain letters, digits, - and _")
+		}
+		if strings.TrimSpace(s.Transports[name]) == "" {
+			fail(p, "the command template is empty")
+		}
+	}
+	if strings.TrimSpace(s.Record.Dir) == "" {
+		fail("record.dir", "must not be empty")
+	}
+	if strings.TrimSpace(s.Audit.Log) == "" {
+		fail("audit.log", "must be a path, %q or %q", AuditOn, AuditOff)
+	}
+	if strings.TrimSpace(s.Notes.Dir) == "" {
+		fail("notes.dir", "must not be empty")
+	}
+	for i, hook := range s.Hooks {
+		p := fmt.Sprintf("hooks[%d]", i)
+		if hook.Event != PreConnect && hook.Event != PostConnect {
+			fail(p+".event", "must be %q or %q, got %q", PreConnect, PostConnect, hook.Event)
+		}
+		if strings.TrimSpace(hook.Command) == "" {
+			fail(p+".command", "is required")
+		}
+		for j, pattern := range hook.Hosts {
+			if _, err := path.Match(pattern, ""); err != nil {
+				fail(fmt.Sprintf("%s.hosts[%d]", p, j), "invalid pattern %q", pattern)
+			}
+		}
+		if hook.Timeout < 0 {
+			fail(p+".timeout", "must not be negative")
+		}
+	}
+	if c := s.Protect.Confirm; c != "" && c != ConfirmAlias && c != ConfirmYes {
+		fail("protect.confirm", "must be %q or %q, got %q", ConfirmAlias, ConfirmYes, c)
+	}
+	for i, pattern := range s.Protect.Hosts {
+		if _, err := path.Match(pattern, ""); err != nil {
+			fail(fmt.Sprintf("protect.hosts[%d]", i), "invalid pattern %q", pattern)
+		}
+	}
+	return errors.Join(errs...)
+}
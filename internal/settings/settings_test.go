@@ -0,0 +1,369 @@
+package settings
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/antonjah/ssm/internal/config"
+)
+
+// defaults returns Default completed the way ssm completes it.
+func defaults() Settings {
+	s := Default()
+	s.Theme = Theme{Name: "auto", Light: "latte", Dark: "mocha"}
+	s.Keys.Preset = "default"
+	s.Tmux = Tmux{WindowPrefix: "ssh:", TiledWindow: "ssh:tiled", ClusterWindow: "ssh:cluster", SyncKey: "S"}
+	s.Connect.Transport = "ssh"
+	s.Record.Dir = "/tmp/data/ssm/recordings"
+	s.Notes.Dir = "/tmp/data/ssm/notes"
+	return s
+}
+
+func writeSettings(t *testing.T, content string) string {
+	t.Helper()
+	p := filepath.Join(t.TempDir(), "config.toml")
+	if err := os.WriteFile(p, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return p
+}
+
+func TestLoad(t *testing.T) {
+	p := writeSettings(t, `
+# Load the prod key before connecting
+[[hooks]]
+name = "prod key"
+event = "pre-connect"
+command = 'ssh-add ~/.ssh/prod_ed25519'
+tags = ["prod"]
+timeout = "1m"
+
+[[hooks]]
+event = "post-connect"
+command = """
+curl -s -d "$SSM_HOST closed" https://chat.example.com/hook"""
+hosts = [
+  "db*",  # databases
+  "cache",
+]
+`)
+	s, err := Load(p, defaults())
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if len(s.Hooks) != 2 {
+		t.Fatalf("Expected 2 hooks, got %d", len(s.Hooks))
+	}
+	first := s.Hooks[0]
+	if first.Name != "prod key" || first.Event != PreConnect || first.Command != "ssh-add ~/.ssh/prod_ed25519" ||
+		first.Deadline() != time.Minute || len(first.Tags) != 1 {
+		t.Errorf("Unexpected first hook %+v", first)
+	}
+	second := s.Hooks[1]
+	if second.Command != `curl -s -d "$SSM_HOST closed" https://chat.example.com/hook` {
+		t.Errorf("Unexpected command %q", second.Command)
+	}
+	if second.Deadline() != DefaultHookTimeout || len(second.Hosts) != 2 {
+		t.Errorf("Unexpected second hook %+v", second)
+	}
+}
+
+func TestLoad_Missing(t *testing.T) {
+	s, err := Load(filepath.Join(t.TempDir(), "config.toml"), defaults())
+	if err != nil || len(s.Hooks) != 0 || s.Tmux != defaults().Tmux {
+		t.Errorf("Expected default settings for a missing file, got %+v (%v)", s, err)
+	}
+}
+
+func TestLoad_Errors(t *testing.T) {
+	tests := map[string]string{
+		"[[hooks]]\nevent = \"pre-connect\"\ncommand = \"true\"\ntimout = \"5s\"\n":  "line 4: hooks.timout: unknown setting",
+		"[menu]\nhieght = 1\n\n[record]\nal = true\n":                                "line 2: menu.hieght: unknown setting\nline 5: record.al: unknown setting",
+		"[[hooks]]\nevent = \"pre-connect\"\ncommand = true\n":                       "line 3: cannot assign boolean",
+		"[[hooks]]\nevent = \"pre-connect\"\ncommand = \"true\"\ntimeout = \"5x\"\n": "line 4: invalid duration",
+		"[[hooks]]\nevent = \"before\"\ncommand = \"true\"\n":                        "hooks[0].event: must be",
+		"[[hooks]]\nevent = \"pre-connect\"\n":                                       "hooks[0].command: is required",
+		"[[hooks]]\nevent = pre-connect\n":                                           "line 2: incomplete number",
+		"[[hooks]]\nevent = \"pre-connect\n":                                         "line 2: basic strings cannot have new lines",
+		"hooks = \"none\"\n":                                                         "line 1: cannot decode TOML string",
+		"[hooks]\n[hooks]\n":                                                         "line 1: cannot store a table in a slice",
+		"[menu]\nheight = 0755\n":                                                    "line 2:",
+		"menu.height = 1\n[menu]\n":                                                  "table menu already exists",
+	}
+	for content, expected := range tests {
+		_, err := Load(writeSettings(t, content), defaults())
+		if err == nil || !strings.Contains(err.Error(), expected) {
+			t.Errorf("Expected an error containing %q for %q, got %v", expected, content, err)
+		}
+	}
+}
+
+func TestHook_Matches(t *testing.T) {
+	host := config.Host{Alias: "db-prod", Tags: []string{"Prod", "db"}}
+	tests := []struct {
+		hook     Hook
+		expected bool
+	}{
+		{Hook{}, true},
+		{Hook{Hosts: []string{"db-*"}}, true},
+		{Hook{Hosts: []string{"web*"}}, false},
+		{Hook{Tags: []string{"prod"}}, true},
+		{Hook{Tags: []string{"staging"}}, false},
+		{Hook{Hosts: []string{"web*"}, Tags: []string{"db"}}, true},
+	}
+	for _, test := range tests {
+		if got := test.hook.Matches(host); got != test.expected {
+			t.Errorf("Hook %+v: expected %v, got %v", test.hook, test.expected, got)
+		}
+	}
+
+	s := Settings{Hooks: []Hook{{Name: "all"}, {Name: "web", Hosts: []string{"web*"}}, {Name: "prod", Tags: []string{"prod"}}}}
+	if hooks := s.HooksFor(host); len(hooks) != 2 || hooks[0].Name != "all" || hooks[1].Name != "prod" {
+		t.Errorf("Expected the global and prod hooks, got %+v", hooks)
+	}
+}
+
+func TestDefaultPath(t *testing.T) {
+	t.Setenv(PathEnv, "")
+	t.Setenv("XDG_CONFIG_HOME", "/tmp/config")
+	if p, err := DefaultPath(); err != nil || p != "/tmp/config/ssm/config.toml" {
+		t.Errorf("Expected /tmp/config/ssm/config.toml, got %s (%v)", p, err)
+	}
+	t.Setenv(PathEnv, "/etc/ssm.toml")
+	if p, err := DefaultPath(); err != nil || p != "/etc/ssm.toml" {
+		t.Errorf("Expected /etc/ssm.toml, got %s (%v)", p, err)
+	}
+}
+
+func TestProtect(t *testing.T) {
+	p := writeSettings(t, `
+[protect]
+tags = ["prod"]
+hosts = ["pci-*"]
+confirm = "yes"
+`)
+	s, err := Load(p, defaults())
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if s.Protect.Confirm != ConfirmYes || s.Protect.Style() != DefaultProtectedStyle {
+		t.Errorf("Unexpected protect settings %+v", s.Protect)
+	}
+	tests := map[string]bool{"db-prod": true, "pci-gateway": true, "db-staging": false}
+	for alias, expected := range tests {
+		host := config.Host{Alias: alias}
+		if strings.HasSuffix(alias, "-prod") {
+			host.Tags = []string{"PROD"}
+		}
+		if got := s.Protect.Matches(host); got != expected {
+			t.Errorf("Host %s: expected protected %v, got %v", alias, expected, got)
+		}
+	}
+	if (Protect{}).Matches(config.Host{Alias: "db-prod"}) {
+		t.Error("Expected no host to be protected by default")
+	}
+
+	_, err = Load(writeSettings(t, "[protect]\nconfirm = \"maybe\"\n"), defaults())
+	if err == nil || !strings.Contains(err.Error(), "protect.confirm: must be") {
+		t.Errorf("Expected an invalid confirm error, got %v", err)
+	}
+}
+
+func TestLoad_Defaults(t *testing.T) {
+	s, err := Load(writeSettings(t, "[menu]\nheight = 12\n\n[transports]\nTSH = \"tsh ssh {host}\"\n"), defaults())
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if !s.Menu.Probe || !s.Menu.Descriptions || s.Menu.Height != 12 {
+		t.Errorf("Expected the file to override only the height, got %+v", s.Menu)
+	}
+	if s.Record.Dir != "/tmp/data/ssm/recordings" || s.Notes.Dir != "/tmp/data/ssm/notes" || s.Connect.Transport != "ssh" || s.Tmux.WindowPrefix != "ssh:" {
+		t.Errorf("Unexpected defaults %+v", s)
+	}
+	if s.Transports["tsh"] != "tsh ssh {host}" {
+		t.Errorf("Expected the tsh transport, got %v", s.Transports)
+	}
+
+	_, err = Load(writeSettings(t, "[menu]\nheight = -1\n"), defaults())
+	if err == nil || !strings.Contains(err.Error(), "menu.height: must not be negative") {
+		t.Errorf("Expected a negative height error, got %v", err)
+	}
+}
+
+func TestDefault(t *testing.T) {
+	s := Default()
+	// Auditing is opt-in and the mouse opt-out
+	if s.Audit.Path("/tmp/state/ssm/audit.log") != "" || !s.Menu.Mouse {
+		t.Errorf("Expected auditing to be off and the mouse on, got %+v and %+v", s.Audit, s.Menu)
+	}
+	// The settings of other packages are left for them to fill in
+	if s.Theme != (Theme{}) || s.Tmux != (Tmux{}) || s.Record.Dir != "" || s.Connect.Transport != "" {
+		t.Errorf("Expected the other packages' settings to be empty, got %+v", s)
+	}
+	if err := s.Validate(); err == nil {
+		t.Error("Expected the incomplete defaults not to validate")
+	}
+	if err := defaults().Validate(); err != nil {
+		t.Errorf("Expected the completed defaults to validate, got %v", err)
+	}
+}
+
+func TestAudit_Path(t *testing.T) {
+	tests := map[string]string{
+		"off":              "",
+		"OFF":              "",
+		"on":               "/tmp/state/ssm/audit.log",
+		"/var/log/ssm.log": "/var/log/ssm.log",
+	}
+	for log, expected := range tests {
+		if p := (Audit{Log: log}).Path("/tmp/state/ssm/audit.log"); p != expected {
+			t.Errorf("Expected %q for %q, got %q", expected, log, p)
+		}
+	}
+}
+
+func TestLoad_Env(t *testing.T) {
+	t.Setenv("SSM_MENU_PROBE", "false")
+	t.Setenv("SSM_RECORD_DIR", "/srv/casts")
+	t.Setenv("SSM_RECORD_TAGS", "prod, pci,")
+	t.Setenv("SSM_TMUX_WINDOW_PREFIX", "")
+	t.Setenv("SSM_TRANSPORT_TSH", "tsh ssh {host}")
+	s, err := Load(writeSettings(t, "[menu]\nprobe = true\n\n[tmux]\nwindow_prefix = \"\"\n"), defaults())
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if s.Menu.Probe || s.Record.Dir != "/srv/casts" || len(s.Record.Tags) != 2 || s.Record.Tags[1] != "pci" {
+		t.Errorf("Expected the environment to override the file, got %+v", s)
+	}
+	if s.Tmux.WindowPrefix != "" {
+		t.Errorf("Expected an empty variable to be ignored, got %q", s.Tmux.WindowPrefix)
+	}
+	if s.Transports["tsh"] != "tsh ssh {host}" {
+		t.Errorf("Expected the tsh transport, got %v", s.Transports)
+	}
+
+	t.Setenv("SSM_MENU_HEIGHT", "tall")
+	if _, err := Load(writeSettings(t, ""), defaults()); err == nil || !strings.Contains(err.Error(), "SSM_MENU_HEIGHT: expected an integer") {
+		t.Errorf("Expected an invalid height error, got %v", err)
+	}
+}
+
+func TestSettings_Encode(t *testing.T) {
+	s := defaults()
+	s.Transports["tsh"] = "tsh ssh \"{host}\"\t"
+	s.Themes["nord"] = map[string]string{"base": "frappe", "accent": "#88c0d0"}
+	s.Keys.Bindings["quit"] = []string{"ctrl+q"}
+	s.Hooks = []Hook{{Name: "key", Event: PreConnect, Command: "ssh-add", Tags: []string{"prod"}, Timeout: Duration(time.Minute)}}
+
+	var b strings.Builder
+	if err := s.Encode(&b); err != nil {
+		t.Fatalf("Encode failed: %v", err)
+	}
+	if !strings.Contains(b.String(), "[[hooks]]\nname = \"key\"") || !strings.Contains(b.String(), `timeout = "1m0s"`) {
+		t.Errorf("Unexpected encoding %q", b.String())
+	}
+
+	var decoded Settings
+	if err := decode([]byte(b.String()), &decoded); err != nil {
+		t.Fatalf("decode failed: %v\n%s", err, b.String())
+	}
+	if decoded.Transports["tsh"] != s.Transports["tsh"] || decoded.Record.Dir != s.Record.Dir ||
+		decoded.Hooks[0].Deadline() != time.Minute || decoded.Protect.Style() != s.Protect.Style() ||
+		decoded.Themes["nord"]["accent"] != "#88c0d0" || decoded.Theme != s.Theme ||
+		!slices.Equal(decoded.Keys.Bindings["quit"], []string{"ctrl+q"}) {
+		t.Errorf("Expected the settings to round trip, got %+v", decoded)
+	}
+}
+
+func TestTheme(t *testing.T) {
+	s, err := Load(writeSettings(t, `
+[theme]
+name = "nord"
+
+[themes.nord]
+base = "frappe"
+accent = "#88C0D0"
+`), defaults())
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if s.Theme.Name != "nord" || s.Theme.Dark != "mocha" || s.Themes["nord"]["accent"] != "#88C0D0" {
+		t.Errorf("Expected the nord theme, got %+v and %v", s.Theme, s.Themes)
+	}
+
+	tests := map[string]string{
+		"[themes.nord]\naccent = 5\n":                "line 2: cannot decode TOML integer",
+		"[themes.\"my theme\"]\naccent = \"#fff\"\n": "themes.my theme: theme names may only contain",
+	}
+	for content, expected := range tests {
+		_, err := Load(writeSettings(t, content), defaults())
+		if err == nil || !strings.Contains(err.Error(), expected) {
+			t.Errorf("Expected an error containing %q for %q, got %v", expected, content, err)
+		}
+	}
+}
+
+func TestKeys(t *testing.T) {
+	s, err := Load(writeSettings(t, `
+[keys]
+preset = "vim"
+
+[keys.bindings]
+quit = ["ctrl+q"]
+details = []
+`), defaults())
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if s.Keys.Preset != "vim" || !slices.Equal(s.Keys.Bindings["quit"], []string{"ctrl+q"}) {
+		t.Errorf("Expected quit to be remapped in the vim preset, got %+v", s.Keys)
+	}
+	if bound, ok := s.Keys.Bindings["details"]; !ok || len(bound) != 0 {
+		t.Errorf("Expected details to be unbound, got %v", s.Keys.Bindings)
+	}
+}
+
+func TestValidate_Order(t *testing.T) {
+	s := defaults()
+	s.Transports = map[string]string{"c d": "x", "a b": "x", "e f": "", "b c": "x"}
+	s.Themes = map[string]map[string]string{"z z": {}, "y y": {}}
+	expected := s.Validate()
+	if expected == nil {
+		t.Fatal("Expected errors")
+	}
+	for range 20 {
+		if err := s.Validate(); err.Error() != expected.Error() {
+			t.Fatalf("Expected the same errors on every run, got %q and then %q", expected, err)
+		}
+	}
+	if i, j := strings.Index(expected.Error(), "themes.y y"), strings.Index(expected.Error(), "transports.a b"); i < 0 || j < i {
+		t.Errorf("Expected themes before transports, sorted by name, got %q", expected)
+	}
+}
+
+func TestMenu_Table(t *testing.T) {
+	s, err := Load(writeSettings(t, "[menu]\nview = \"table\"\ncolumns = [\"alias\", \"status\"]\n"), defaults())
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if s.Menu.View != ViewTable || !slices.Equal(s.Menu.Columns, []string{"alias", "status"}) {
+		t.Errorf("Unexpected menu settings %+v", s.Menu)
+	}
+
+	tests := map[string]string{
+		"[menu]\nview = \"grid\"\n":                   `menu.view: must be "list" or "table", got "grid"`,
+		"[menu]\ncolumns = []\n":                      "menu.columns: at least one column is needed",
+		"[menu]\ncolumns = [\"alias\", \"uptime\"]\n": `menu.columns[1]: unknown column "uptime"`,
+		"[menu]\ncolumns = [\"alias\", \"alias\"]\n":  `menu.columns[1]: column "alias" is listed twice`,
+	}
+	for content, expected := range tests {
+		_, err := Load(writeSettings(t, content), defaults())
+		if err == nil || !strings.Contains(err.Error(), expected) {
+			t.Errorf("Expected an error containing %q for %q, got %v", expected, content, err)
+		}
+	}
+}
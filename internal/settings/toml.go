@@ -0,0 +1,64 @@
+package settings
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/pelletier/go-toml/v2"
+)
+
+// Error is a problem with a setting, located by its line in the file where
+// that is known.
+type Error struct {
+	Line int
+	// Path is the setting, e.g. "menu.height".
+	Path    string
+	Message string
+}
+
+func (e *Error) Error() string {
+	var b strings.Builder
+	if e.Line > 0 {
+		fmt.Fprintf(&b, "line %d: ", e.Line)
+	}
+	if e.Path != "" {
+		b.WriteString(e.Path + ": ")
+	}
+	b.WriteString(e.Message)
+	return b.String()
+}
+
+// decode reads the settings file in data into s. Settings the file doesn't
+// mention keep their values, and settings s has no field for are errors.
+func decode(data []byte, s *Settings) error {
+	err := toml.NewDecoder(bytes.NewReader(data)).DisallowUnknownFields().Decode(s)
+	if err == nil {
+		return nil
+	}
+	// Every unknown setting is reported at once
+	var missing *toml.StrictMissingError
+	if errors.As(err, &missing) {
+		errs := make([]error, len(missing.Errors))
+		for i := range missing.Errors {
+			errs[i] = decodeError(&missing.Errors[i], "unknown setting")
+		}
+		return errors.Join(errs...)
+	}
+	var decodeErr *toml.DecodeError
+	if errors.As(err, &decodeErr) {
+		return decodeError(decodeErr, "")
+	}
+	return &Error{Message: strings.TrimPrefix(err.Error(), "toml: ")}
+}
+
+// decodeError converts err to an Error, with message instead of err's own
+// if it is set.
+func decodeError(err *toml.DecodeError, message string) *Error {
+	line, _ := err.Position()
+	if message == "" {
+		message = strings.TrimPrefix(err.Error(), "toml: ")
+	}
+	return &Error{Line: line, Path: strings.Join(err.Key(), "."), Message: message}
+}
@@ -0,0 +1,267 @@
+// Package theme defines the colours ssm's menu is drawn with: the four
+// Catppuccin flavours, a monochrome and a high-contrast theme, and custom
+// themes that change some of a built-in theme's colour slots.
+package theme
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+
+	catppuccin "github.com/catppuccin/go"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// Theme names with a special meaning.
+const (
+	// Auto picks a light or dark theme to suit the terminal's background.
+	Auto = "auto"
+	// Mono has no colours. It is used when NO_COLOR is set.
+	Mono = "mono"
+	// HighContrast uses the terminal's own foreground and basic colours.
+	HighContrast = "high-contrast"
+	// Default is used when no theme is configured.
+	Default = "mocha"
+	// BaseKey names the theme a custom theme starts from.
+	BaseKey = "base"
+)
+
+// Theme is a set of colours for the menu's elements.
+type Theme struct {
+	Name string
+	// Monochrome themes have no colours, so highlights are drawn with text
+	// attributes instead.
+	Monochrome bool
+
+	Text       lipgloss.TerminalColor
+	Subtext    lipgloss.TerminalColor
+	Muted      lipgloss.TerminalColor
+	Dim        lipgloss.TerminalColor
+	Border     lipgloss.TerminalColor
+	Background lipgloss.TerminalColor
+	Accent     lipgloss.TerminalColor
+	Highlight  lipgloss.TerminalColor
+	Success    lipgloss.TerminalColor
+	Error      lipgloss.TerminalColor
+	Warning    lipgloss.TerminalColor
+	Info       lipgloss.TerminalColor
+}
+
+// Slots lists the colour slots custom themes may set, in the order of
+// Theme's fields.
+var Slots = []string{
+	"text", "subtext", "muted", "dim", "border", "background",
+	"accent", "highlight", "success", "error", "warning", "info",
+}
+
+// slot returns the field holding the colour slot called name.
+func (t *Theme) slot(name string) *lipgloss.TerminalColor {
+	switch name {
+	case "text":
+		return &t.Text
+	case "subtext":
+		return &t.Subtext
+	case "muted":
+		return &t.Muted
+	case "dim":
+		return &t.Dim
+	case "border":
+		return &t.Border
+	case "background":
+		return &t.Background
+	case "accent":
+		return &t.Accent
+	case "highlight":
+		return &t.Highlight
+	case "success":
+		return &t.Success
+	case "error":
+		return &t.Error
+	case "warning":
+		return &t.Warning
+	case "info":
+		return &t.Info
+	}
+	return nil
+}
+
+// Selection returns the style of the highlighted row in a list: the accent
+// colour as background, or reversed text without colours.
+func (t Theme) Selection() lipgloss.Style {
+	if t.Monochrome {
+		return lipgloss.NewStyle().Reverse(true)
+	}
+	return lipgloss.NewStyle().Foreground(t.Background).Background(t.Accent)
+}
+
+// FocusBorder returns the border of the focused pane. Without colours it is
+// thicker than the other panes' borders.
+func (t Theme) FocusBorder() lipgloss.Border {
+	if t.Monochrome {
+		return lipgloss.ThickBorder()
+	}
+	return lipgloss.RoundedBorder()
+}
+
+// Hex returns c as a colour string for components that take one, or "" for
+// no colour.
+func Hex(c lipgloss.TerminalColor) string {
+	if color, ok := c.(lipgloss.Color); ok {
+		return string(color)
+	}
+	return ""
+}
+
+// catppuccinTheme maps a Catppuccin flavour onto the colour slots.
+func catppuccinTheme(flavor catppuccin.Flavor) Theme {
+	color := func(c catppuccin.Color) lipgloss.TerminalColor { return lipgloss.Color(c.Hex) }
+	return Theme{
+		Name:       flavor.Name(),
+		Text:       color(flavor.Text()),
+		Subtext:    color(flavor.Subtext1()),
+		Muted:      color(flavor.Subtext0()),
+		Dim:        color(flavor.Overlay0()),
+		Border:     color(flavor.Surface2()),
+		Background: color(flavor.Base()),
+		Accent:     color(flavor.Mauve()),
+		Highlight:  color(flavor.Pink()),
+		Success:    color(flavor.Green()),
+		Error:      color(flavor.Red()),
+		Warning:    color(flavor.Yellow()),
+		Info:       color(flavor.Blue()),
+	}
+}
+
+// builtins are the themes that are always available.
+var builtins = map[string]func() Theme{
+	"latte":     func() Theme { return catppuccinTheme(catppuccin.Latte) },
+	"frappe":    func() Theme { return catppuccinTheme(catppuccin.Frappe) },
+	"macchiato": func() Theme { return catppuccinTheme(catppuccin.Macchiato) },
+	"mocha":     func() Theme { return catppuccinTheme(catppuccin.Mocha) },
+	Mono: func() Theme {
+		none := lipgloss.NoColor{}
+		return Theme{
+			Name: Mono, Monochrome: true,
+			Text: none, Subtext: none, Muted: none, Dim: none, Border: none, Background: none,
+			Accent: none, Highlight: none, Success: none, Error: none, Warning: none, Info: none,
+		}
+	},
+	// High contrast keeps the terminal's foreground for text, which suits
+	// both light and dark backgrounds, and the basic ANSI colours, which
+	// terminals choose to be readable
+	HighContrast: func() Theme {
+		none := lipgloss.NoColor{}
+		return Theme{
+			Name: HighContrast,
+			Text: none, Subtext: none, Muted: none, Dim: none, Border: none, Background: none,
+			Accent:    lipgloss.Color("5"),
+			Highlight: lipgloss.Color("5"),
+			Success:   lipgloss.Color("2"),
+			Error:     lipgloss.Color("1"),
+			Warning:   lipgloss.Color("3"),
+			Info:      lipgloss.Color("4"),
+		}
+	},
+}
+
+// Names returns the names of the built-in themes, sorted.
+func Names() []string {
+	return slices.Sorted(maps.Keys(builtins))
+}
+
+// normalize lowercases a theme name and accepts Frappé with its accent.
+func normalize(name string) string {
+	return strings.ReplaceAll(strings.ToLower(strings.TrimSpace(name)), "é", "e")
+}
+
+// Builtin returns the built-in theme called name.
+func Builtin(name string) (Theme, bool) {
+	theme, ok := builtins[normalize(name)]
+	if !ok {
+		return Theme{}, false
+	}
+	return theme(), true
+}
+
+// ParseColor parses a colour written as "#rrggbb", "#rgb", an ANSI colour
+// number from 0 to 255, or "none" for the terminal's default.
+func ParseColor(s string) (lipgloss.TerminalColor, error) {
+	s = strings.TrimSpace(s)
+	switch {
+	case strings.EqualFold(s, "none"):
+		return lipgloss.NoColor{}, nil
+	case strings.HasPrefix(s, "#") && (len(s) == 4 || len(s) == 7):
+		if _, err := strconv.ParseUint(s[1:], 16, 32); err == nil {
+			return lipgloss.Color(strings.ToLower(s)), nil
+		}
+	default:
+		if n, err := strconv.Atoi(s); err == nil && n >= 0 && n <= 255 {
+			return lipgloss.Color(s), nil
+		}
+	}
+	return nil, fmt.Errorf("invalid colour %q, use \"#rrggbb\", an ANSI colour from 0 to 255 or \"none\"", s)
+}
+
+// Registry looks themes up by name.
+type Registry struct {
+	// Custom maps theme names to their colour slots, and optionally BaseKey
+	// to the built-in theme they start from. Without one they start from
+	// Default. Custom themes replace built-in themes of the same name.
+	Custom map[string]map[string]string
+}
+
+// Lookup returns the theme called name.
+func (r Registry) Lookup(name string) (Theme, error) {
+	for customName, colours := range r.Custom {
+		if normalize(customName) == normalize(name) {
+			return custom(customName, colours)
+		}
+	}
+	if theme, ok := Builtin(name); ok {
+		return theme, nil
+	}
+	return Theme{}, fmt.Errorf("unknown theme %q (expected %s, %s or a custom theme)", name, Auto, strings.Join(Names(), ", "))
+}
+
+// Resolve returns the theme called name, or for Auto the light or the dark
+// theme to suit the terminal's background. isDark is only called for Auto.
+func (r Registry) Resolve(name, light, dark string, isDark func() bool) (Theme, error) {
+	if strings.EqualFold(name, Auto) {
+		name = light
+		if isDark() {
+			name = dark
+		}
+	}
+	return r.Lookup(name)
+}
+
+// custom returns the custom theme called name.
+func custom(name string, colours map[string]string) (Theme, error) {
+	base := Default
+	if b, ok := colours[BaseKey]; ok {
+		base = b
+	}
+	theme, ok := Builtin(base)
+	if !ok {
+		return Theme{}, fmt.Errorf("theme %q: %s must be a built-in theme (%s), got %q", name, BaseKey, strings.Join(Names(), ", "), base)
+	}
+	theme.Name = name
+	for _, slot := range slices.Sorted(maps.Keys(colours)) {
+		if slot == BaseKey {
+			continue
+		}
+		value := colours[slot]
+		field := theme.slot(slot)
+		if field == nil {
+			return Theme{}, fmt.Errorf("theme %q: unknown colour slot %q (expected one of %s)", name, slot, strings.Join(Slots, ", "))
+		}
+		color, err := ParseColor(value)
+		if err != nil {
+			return Theme{}, fmt.Errorf("theme %q: %s: %w", name, slot, err)
+		}
+		*field = color
+	}
+	return theme, nil
+}
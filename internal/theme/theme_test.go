@@ -0,0 +1,108 @@
+package theme
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+func TestBuiltin(t *testing.T) {
+	for _, name := range []string{"latte", "Frappé", "frappe", "macchiato", "mocha", Mono, HighContrast} {
+		theme, ok := Builtin(name)
+		if !ok {
+			t.Errorf("Expected %s to be a built-in theme", name)
+			continue
+		}
+		for _, slot := range Slots {
+			if *theme.slot(slot) == nil {
+				t.Errorf("Theme %s: expected a colour for %s", name, slot)
+			}
+		}
+	}
+	if mocha, _ := Builtin("mocha"); mocha.Accent != lipgloss.Color("#cba6f7") {
+		t.Errorf("Expected Mocha's mauve accent, got %v", mocha.Accent)
+	}
+	if _, ok := Builtin("solarized"); ok {
+		t.Error("Expected solarized to be unknown")
+	}
+}
+
+func TestParseColor(t *testing.T) {
+	tests := map[string]lipgloss.TerminalColor{
+		"#FF0000": lipgloss.Color("#ff0000"),
+		"#f00":    lipgloss.Color("#f00"),
+		"208":     lipgloss.Color("208"),
+		"none":    lipgloss.NoColor{},
+	}
+	for value, expected := range tests {
+		if got, err := ParseColor(value); err != nil || got != expected {
+			t.Errorf("ParseColor(%q): expected %v, got %v (%v)", value, expected, got, err)
+		}
+	}
+	for _, value := range []string{"red", "#ff00", "256", "#gggggg", ""} {
+		if _, err := ParseColor(value); err == nil {
+			t.Errorf("ParseColor(%q): expected an error", value)
+		}
+	}
+}
+
+func TestRegistry_Lookup(t *testing.T) {
+	registry := Registry{Custom: map[string]map[string]string{
+		"nord":   {"accent": "#88c0d0", "error": "1"},
+		"paper":  {BaseKey: "latte", "background": "none"},
+		"broken": {"acent": "#fff"},
+		"bad":    {BaseKey: "nord"},
+	}}
+
+	nord, err := registry.Lookup("Nord")
+	if err != nil {
+		t.Fatalf("Lookup failed: %v", err)
+	}
+	mocha, _ := Builtin("mocha")
+	if nord.Name != "nord" || nord.Accent != lipgloss.Color("#88c0d0") || nord.Error != lipgloss.Color("1") || nord.Text != mocha.Text {
+		t.Errorf("Expected Mocha with a new accent and error colour, got %+v", nord)
+	}
+	latte, _ := Builtin("latte")
+	if paper, err := registry.Lookup("paper"); err != nil || paper.Text != latte.Text || paper.Background != (lipgloss.NoColor{}) {
+		t.Errorf("Expected Latte without a background, got %+v (%v)", paper, err)
+	}
+
+	tests := map[string]string{
+		"broken":    `unknown colour slot "acent"`,
+		"bad":       "base must be a built-in theme",
+		"solarized": `unknown theme "solarized"`,
+	}
+	for name, expected := range tests {
+		if _, err := registry.Lookup(name); err == nil || !strings.Contains(err.Error(), expected) {
+			t.Errorf("Lookup(%q): expected an error containing %q, got %v", name, expected, err)
+		}
+	}
+}
+
+func TestRegistry_Resolve(t *testing.T) {
+	registry := Registry{Custom: map[string]map[string]string{"nord": {BaseKey: "frappe"}}}
+	if theme, err := registry.Resolve("nord", "latte", "mocha", nil); err != nil || theme.Name != "nord" {
+		t.Errorf("Expected the nord theme, got %q (%v)", theme.Name, err)
+	}
+	for dark, expected := range map[bool]string{true: "mocha", false: "latte"} {
+		theme, err := registry.Resolve(Auto, "latte", "mocha", func() bool { return dark })
+		if err != nil || theme.Name != expected {
+			t.Errorf("Dark background %v: expected %s, got %s (%v)", dark, expected, theme.Name, err)
+		}
+	}
+	if _, err := registry.Resolve(Auto, "latte", "auto", func() bool { return true }); err == nil {
+		t.Error("Expected an error for an automatic dark theme")
+	}
+}
+
+func TestTheme_Selection(t *testing.T) {
+	mono, _ := Builtin(Mono)
+	if !mono.Selection().GetReverse() || mono.FocusBorder() != lipgloss.ThickBorder() {
+		t.Error("Expected the monochrome theme to highlight with attributes")
+	}
+	mocha, _ := Builtin("mocha")
+	if mocha.Selection().GetReverse() || mocha.Selection().GetBackground() != mocha.Accent {
+		t.Error("Expected Mocha to highlight with the accent colour")
+	}
+}
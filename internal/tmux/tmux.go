@@ -2,48 +2,276 @@
 package tmux
 
 import (
+	"bytes"
+	"errors"
+	"fmt"
 	"os"
 	"os/exec"
 	"strings"
 	"syscall"
 )
 
+// ErrNotInTmux is returned by operations that require a tmux session.
+var ErrNotInTmux = errors.New("not running inside a tmux session")
+
+// clusterOption marks cluster windows, so that a sync key binding only acts
+// in them.
+const clusterOption = "@ssm-cluster"
+
+// clusterBorderFormat shows each pane's host and whether input is synchronized.
+const clusterBorderFormat = " #{pane_title} #{?synchronize-panes,[sync],} "
+
 // IsTmuxSession returns true if the current process is running inside a tmux session.
 func IsTmuxSession() bool {
 	return os.Getenv("TMUX") != ""
 }
 
-// SSHWindow creates or switches to a tmux window for the given SSH host.
-// If a window with the name "ssh:<host>" already exists, it switches to it.
-// Otherwise, it creates a new window with that name.
-func SSHWindow(host string) {
+// Session is a host to open and the command that connects to it.
+type Session struct {
+	Host string
+	// Name is the name of the session's window. Defaults to "ssh:<Host>".
+	Name string
+	// Command is the program and arguments to run. Defaults to "ssh <Host>".
+	Command []string
+	// Style is the tmux style of the session's window in the status line,
+	// e.g. "bg=red,fg=white,bold" to make protected hosts stand out.
+	Style string
+}
+
+// command returns the session's command, defaulting to plain ssh.
+func (s Session) command() []string {
+	if len(s.Command) == 0 {
+		return []string{"ssh", s.Host}
+	}
+	return s.Command
+}
+
+// DefaultWindowPrefix is prepended to a host's alias to name its window.
+const DefaultWindowPrefix = "ssh:"
+
+// windowName returns the name of the session's window.
+func (s Session) windowName() string {
+	if s.Name == "" {
+		return DefaultWindowPrefix + s.Host
+	}
+	return s.Name
+}
+
+// findWindow returns the index of the window called name from the output of
+// "tmux list-windows -F '#{window_index},#{window_name}'".
+func findWindow(output, name string) (string, bool) {
+	lines := strings.Split(strings.TrimSpace(output), "\n")
+	for _, line := range lines {
+		parts := strings.SplitN(line, ",", 2)
+		if len(parts) == 2 && parts[1] == name {
+			return parts[0], true
+		}
+	}
+	return "", false
+}
+
+// run executes a tmux command and returns its trimmed standard output.
+func run(args ...string) (string, error) {
+	var stderr bytes.Buffer
+	cmd := exec.Command("tmux", args...)
+	cmd.Stderr = &stderr
+	output, err := cmd.Output()
+	if err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return "", fmt.Errorf("tmux %s: %s", args[0], msg)
+		}
+		return "", fmt.Errorf("tmux %s: %w", args[0], err)
+	}
+	return strings.TrimSpace(string(output)), nil
+}
+
+// listWindows returns the index and name of every window in the current session.
+func listWindows() (string, error) {
+	return run("list-windows", "-F", "#{window_index},#{window_name}")
+}
+
+// SSHWindow creates or switches to a tmux window for the given session.
+// If a window with the session's name already exists, it switches to it.
+// Otherwise, it creates a new window with that name running the session's
+// command.
+func SSHWindow(session Session) {
 	if !IsTmuxSession() {
 		return
 	}
 
-	cmd := exec.Command("tmux", "list-windows", "-F", "#{window_index},#{window_name}")
-	output, err := cmd.Output()
+	output, err := listWindows()
 	if err != nil {
 		// tmux not available or error, skip silently
 		return
 	}
 
-	lines := strings.Split(strings.TrimSpace(string(output)), "\n")
-	for _, line := range lines {
-		parts := strings.Split(line, ",")
-		if len(parts) == 2 && parts[1] == "ssh:"+host {
-			tmuxPath, err := exec.LookPath("tmux")
-			if err != nil {
-				return
+	tmuxPath, err := exec.LookPath("tmux")
+	if err != nil {
+		return
+	}
+
+	if index, ok := findWindow(output, session.windowName()); ok {
+		syscall.Exec(tmuxPath, []string{"tmux", "select-window", "-t", index}, os.Environ())
+	}
+
+	// Create new window, styling it in the same tmux invocation
+	args := append([]string{"tmux", "new-window", "-n", session.windowName()}, session.command()...)
+	for _, command := range styleCommands("", session.Style) {
+		args = append(append(args, ";"), command...)
+	}
+	syscall.Exec(tmuxPath, args, os.Environ())
+}
+
+// SSHWindows opens each session in its own tmux window. Hosts that already
+// have a window are left as they are. Unlike SSHWindow it returns to the caller.
+func SSHWindows(sessions []Session) error {
+	if !IsTmuxSession() {
+		return ErrNotInTmux
+	}
+
+	output, err := listWindows()
+	if err != nil {
+		return err
+	}
+
+	for _, session := range sessions {
+		if _, ok := findWindow(output, session.windowName()); ok {
+			continue
+		}
+		window, err := run(append([]string{"new-window", "-P", "-F", "#{window_id}", "-n", session.windowName()}, session.command()...)...)
+		if err != nil {
+			return err
+		}
+		if err := setStyle(window, session.Style); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// SSHTiled opens all sessions as panes of a single new tmux window called
+// name, arranged in a tiled layout. It returns the tmux ID of the new window.
+func SSHTiled(name string, sessions []Session) (string, error) {
+	if !IsTmuxSession() {
+		return "", ErrNotInTmux
+	}
+	if len(sessions) == 0 {
+		return "", errors.New("no hosts to open")
+	}
+
+	output, err := run(append([]string{"new-window", "-P", "-F", "#{window_id} #{pane_id}", "-n", name}, sessions[0].command()...)...)
+	if err != nil {
+		return "", err
+	}
+	window, pane, _ := strings.Cut(output, " ")
+	if _, err := run("select-pane", "-t", pane, "-T", sessions[0].Host); err != nil {
+		return window, err
+	}
+	for _, session := range sessions {
+		if session.Style != "" {
+			if err := setStyle(window, session.Style); err != nil {
+				return window, err
 			}
-			syscall.Exec(tmuxPath, []string{"tmux", "select-window", "-t", parts[0]}, os.Environ())
+			break
 		}
 	}
 
-	// Create new window
-	tmuxPath, err := exec.LookPath("tmux")
+	for _, session := range sessions[1:] {
+		pane, err := run(append([]string{"split-window", "-P", "-F", "#{pane_id}", "-t", window}, session.command()...)...)
+		if err != nil {
+			return window, err
+		}
+		if _, err := run("select-pane", "-t", pane, "-T", session.Host); err != nil {
+			return window, err
+		}
+		// Re-tile after every split so there is room for the next pane
+		if _, err := run("select-layout", "-t", window, "tiled"); err != nil {
+			return window, err
+		}
+	}
+	return window, nil
+}
+
+// SSHCluster opens all sessions as tiled panes of a new window called name
+// and turns on synchronize-panes so keystrokes go to every pane. If syncKey
+// is set, pressing the tmux prefix followed by it toggles synchronization in
+// cluster windows; tmux's bindings are otherwise left alone.
+func SSHCluster(name string, sessions []Session, syncKey string) (string, error) {
+	window, err := SSHTiled(name, sessions)
 	if err != nil {
-		return
+		return window, err
+	}
+
+	for _, args := range clusterCommands(window, syncKey) {
+		if _, err := run(args...); err != nil {
+			return window, err
+		}
+	}
+	return window, nil
+}
+
+// clusterCommands returns the tmux commands that turn window into a cluster
+// window, binding syncKey if it is set and saying how to use it. Key
+// bindings are global in tmux, so the binding replaces any other binding of
+// the key and does nothing outside cluster windows.
+func clusterCommands(window, syncKey string) [][]string {
+	commands := [][]string{
+		{"set-window-option", "-t", window, "synchronize-panes", "on"},
+		{"set-window-option", "-t", window, "pane-border-status", "top"},
+		{"set-window-option", "-t", window, "pane-border-format", clusterBorderFormat},
+		{"set-window-option", "-t", window, clusterOption, "1"},
+	}
+	if syncKey != "" {
+		toggle := []string{"bind-key", syncKey, "if-shell", "-F", "#{" + clusterOption + "}", "set-window-option synchronize-panes"}
+		help := []string{"display-message", "Cluster mode: prefix " + syncKey + " toggles synchronized input"}
+		commands = append(commands, toggle, help)
+	}
+	return commands
+}
+
+// styleCommands returns the tmux commands that give window style in the
+// status line. An empty window means the current one.
+func styleCommands(window, style string) [][]string {
+	if style == "" {
+		return nil
+	}
+	var commands [][]string
+	for _, option := range []string{"window-status-style", "window-status-current-style", "pane-active-border-style"} {
+		command := []string{"set-window-option"}
+		if window != "" {
+			command = append(command, "-t", window)
+		}
+		commands = append(commands, append(command, option, style))
+	}
+	return commands
+}
+
+// setStyle applies style to window.
+func setStyle(window, style string) error {
+	for _, args := range styleCommands(window, style) {
+		if _, err := run(args...); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Default names of windows holding several hosts.
+const (
+	DefaultTiledName   = "ssh:tiled"
+	DefaultClusterName = "ssh:cluster"
+)
+
+// DefaultSyncKey, pressed after the tmux prefix, toggles synchronization in
+// cluster windows. tmux doesn't bind it by default.
+const DefaultSyncKey = "S"
+
+// ClusterName returns the window name for a cluster session, e.g.
+// "ssh:cluster:prod" for base "ssh:cluster" when all hosts share the tag
+// "prod".
+func ClusterName(base, tag string) string {
+	if tag == "" {
+		return base
 	}
-	syscall.Exec(tmuxPath, []string{"tmux", "new-window", "-n", "ssh:" + host, "ssh", host}, os.Environ())
+	return base + ":" + tag
 }
@@ -2,6 +2,8 @@ package tmux
 
 import (
 	"os"
+	"reflect"
+	"strings"
 	"testing"
 )
 
@@ -21,3 +23,100 @@ func TestIsTmuxSession(t *testing.T) {
 	// Clean up
 	os.Unsetenv("TMUX")
 }
+
+func TestFindWindow(t *testing.T) {
+	output := "0,zsh\n1,ssh:web\n2,ssh:db,replica\n"
+
+	if index, ok := findWindow(output, "ssh:web"); !ok || index != "1" {
+		t.Errorf("Expected window 1, got %q (found %v)", index, ok)
+	}
+	if index, ok := findWindow(output, "ssh:db,replica"); !ok || index != "2" {
+		t.Errorf("Expected window 2, got %q (found %v)", index, ok)
+	}
+	if _, ok := findWindow(output, "ssh:missing"); ok {
+		t.Error("Expected no window for ssh:missing")
+	}
+}
+
+func TestSSHWindows_NotInTmux(t *testing.T) {
+	os.Unsetenv("TMUX")
+	if err := SSHWindows([]Session{{Host: "web"}}); err != ErrNotInTmux {
+		t.Errorf("Expected ErrNotInTmux, got %v", err)
+	}
+	if _, err := SSHTiled("ssh:tiled", []Session{{Host: "web"}}); err != ErrNotInTmux {
+		t.Errorf("Expected ErrNotInTmux, got %v", err)
+	}
+}
+
+func TestSession_Command(t *testing.T) {
+	if command := (Session{Host: "web"}).command(); !reflect.DeepEqual(command, []string{"ssh", "web"}) {
+		t.Errorf("Expected ssh web, got %v", command)
+	}
+	session := Session{Host: "web", Command: []string{"mosh", "web"}}
+	if command := session.command(); !reflect.DeepEqual(command, []string{"mosh", "web"}) {
+		t.Errorf("Expected mosh web, got %v", command)
+	}
+}
+
+func TestSession_WindowName(t *testing.T) {
+	if name := (Session{Host: "web"}).windowName(); name != "ssh:web" {
+		t.Errorf("Expected 'ssh:web', got '%s'", name)
+	}
+	if name := (Session{Host: "web", Name: "web"}).windowName(); name != "web" {
+		t.Errorf("Expected 'web', got '%s'", name)
+	}
+}
+
+func TestClusterName(t *testing.T) {
+	if name := ClusterName(DefaultClusterName, "prod"); name != "ssh:cluster:prod" {
+		t.Errorf("Expected 'ssh:cluster:prod', got '%s'", name)
+	}
+	if name := ClusterName(DefaultClusterName, ""); name != "ssh:cluster" {
+		t.Errorf("Expected 'ssh:cluster', got '%s'", name)
+	}
+}
+
+func TestClusterCommands(t *testing.T) {
+	commands := clusterCommands("@3", "")
+	first := commands[0]
+	expected := []string{"set-window-option", "-t", "@3", "synchronize-panes", "on"}
+	if len(first) != len(expected) {
+		t.Fatalf("Expected %v, got %v", expected, first)
+	}
+	for i := range expected {
+		if first[i] != expected[i] {
+			t.Errorf("Expected %v, got %v", expected, first)
+		}
+	}
+
+	for _, command := range commands {
+		if command[0] == "bind-key" {
+			t.Errorf("Expected no key binding without a sync key, got %v", command)
+		}
+	}
+
+	commands = clusterCommands("@3", "S")
+	toggle := strings.Join(commands[len(commands)-2], " ")
+	if toggle != "bind-key S if-shell -F #{@ssm-cluster} set-window-option synchronize-panes" {
+		t.Errorf("Expected a sync toggle for cluster windows only, got %v", toggle)
+	}
+	if help := commands[len(commands)-1]; help[0] != "display-message" || !strings.Contains(help[1], "prefix S") {
+		t.Errorf("Expected a message naming the sync key, got %v", help)
+	}
+}
+
+func TestStyleCommands(t *testing.T) {
+	if commands := styleCommands("@3", ""); commands != nil {
+		t.Errorf("Expected no commands without a style, got %v", commands)
+	}
+	commands := styleCommands("@3", "bg=red")
+	if len(commands) != 3 {
+		t.Fatalf("Expected 3 commands, got %v", commands)
+	}
+	if got := strings.Join(commands[0], " "); got != "set-window-option -t @3 window-status-style bg=red" {
+		t.Errorf("Unexpected command %q", got)
+	}
+	if got := strings.Join(styleCommands("", "bg=red")[1], " "); got != "set-window-option window-status-current-style bg=red" {
+		t.Errorf("Expected the current window without a target, got %q", got)
+	}
+}
@@ -0,0 +1,272 @@
+// Package transfer copies files to and from SSH hosts with scp, rsync or
+// sftp, reporting the tool's progress as it runs.
+package transfer
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os/exec"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/creack/pty"
+)
+
+// Tool is the program used to copy files.
+type Tool string
+
+const (
+	// SCP copies with scp.
+	SCP Tool = "scp"
+	// Rsync copies with rsync over ssh.
+	Rsync Tool = "rsync"
+	// SFTP copies with sftp in batch mode.
+	SFTP Tool = "sftp"
+)
+
+// Tools lists the supported tools in the order they are offered.
+var Tools = []Tool{SCP, Rsync, SFTP}
+
+// ParseTool returns the tool with the given name.
+func ParseTool(name string) (Tool, error) {
+	for _, tool := range Tools {
+		if string(tool) == name {
+			return tool, nil
+		}
+	}
+	return "", fmt.Errorf("unknown transfer tool %q: expected scp, rsync or sftp", name)
+}
+
+// Endpoint is one side of a copy: a local path, or a path on a host.
+type Endpoint struct {
+	// User overrides the login user for a remote endpoint.
+	User string
+	// Host is the alias of a remote endpoint and empty for a local one.
+	Host string
+	// Path is the file or directory. An empty remote path is the home
+	// directory.
+	Path string
+}
+
+// Remote reports whether the endpoint is on a host.
+func (e Endpoint) Remote() bool {
+	return e.Host != ""
+}
+
+// Destination returns the "[user@]host" ssh connects to.
+func (e Endpoint) Destination() string {
+	if e.User != "" {
+		return e.User + "@" + e.Host
+	}
+	return e.Host
+}
+
+// String returns the endpoint in scp's "[user@]host:path" syntax.
+func (e Endpoint) String() string {
+	if !e.Remote() {
+		return e.Path
+	}
+	return e.Destination() + ":" + e.Path
+}
+
+// ParseEndpoint parses "[user@]alias:path" or a local path. Like scp, a colon
+// after a slash is part of a local path, so "./a:b" is local. isAlias
+// reports whether a name is a configured host; anything else before the
+// colon is an error rather than a silent local path.
+func ParseEndpoint(s string, isAlias func(string) bool) (Endpoint, error) {
+	colon := strings.Index(s, ":")
+	if colon <= 0 || strings.Contains(s[:colon], "/") {
+		return Endpoint{Path: s}, nil
+	}
+
+	endpoint := Endpoint{Host: s[:colon], Path: s[colon+1:]}
+	if at := strings.LastIndex(endpoint.Host, "@"); at >= 0 {
+		endpoint.User, endpoint.Host = endpoint.Host[:at], endpoint.Host[at+1:]
+	}
+	if !isAlias(endpoint.Host) {
+		return Endpoint{}, fmt.Errorf("unknown host %q (write local paths containing ':' as ./%s)", endpoint.Host, s)
+	}
+	return endpoint, nil
+}
+
+// Transfer copies Source to Dest. Exactly one of them is remote.
+type Transfer struct {
+	Tool   Tool
+	Source Endpoint
+	Dest   Endpoint
+	// Prompt lets ssh ask for passwords and passphrases. Only set it when
+	// the tool runs on the user's terminal.
+	Prompt bool
+}
+
+// New returns a transfer between src and dst, checking that exactly one of
+// them is remote.
+func New(tool Tool, src, dst Endpoint) (Transfer, error) {
+	switch {
+	case src.Remote() && dst.Remote():
+		return Transfer{}, errors.New("copying between two hosts is not supported")
+	case !src.Remote() && !dst.Remote():
+		return Transfer{}, errors.New("one side of the copy must be a host, as in alias:path")
+	}
+	return Transfer{Tool: tool, Source: src, Dest: dst}, nil
+}
+
+// Host returns the remote endpoint of the transfer.
+func (t Transfer) Host() Endpoint {
+	if t.Source.Remote() {
+		return t.Source
+	}
+	return t.Dest
+}
+
+// Upload reports whether the transfer copies from the local machine to the
+// host.
+func (t Transfer) Upload() bool {
+	return t.Dest.Remote()
+}
+
+// Command returns the command that performs the transfer. path overrides the
+// tool's binary when not empty. Directories are copied recursively, and
+// unless Prompt is set BatchMode stops ssh from prompting.
+func (t Transfer) Command(ctx context.Context, path string) *exec.Cmd {
+	if path == "" {
+		path = string(t.Tool)
+	}
+	var options []string
+	if !t.Prompt {
+		options = []string{"-o", "BatchMode=yes"}
+	}
+
+	switch t.Tool {
+	case Rsync:
+		rsh := strings.Join(append([]string{"ssh"}, options...), " ")
+		return exec.CommandContext(ctx, path, "-a", "--info=progress2", "-e", rsh, t.Source.String(), t.Dest.String())
+	case SFTP:
+		args := append(options, "-b", "-", t.Host().Destination())
+		cmd := exec.CommandContext(ctx, path, args...)
+		cmd.Stdin = strings.NewReader(t.batch())
+		return cmd
+	default:
+		args := append(append([]string{"-r"}, options...), t.Source.String(), t.Dest.String())
+		return exec.CommandContext(ctx, path, args...)
+	}
+}
+
+// batch returns the sftp batch commands for the transfer.
+func (t Transfer) batch() string {
+	remote := t.Host().Path
+	if remote == "" {
+		remote = "."
+	}
+	if t.Upload() {
+		return fmt.Sprintf("put -R %s %s\n", sftpQuote(t.Source.Path), sftpQuote(remote))
+	}
+	return fmt.Sprintf("get -R %s %s\n", sftpQuote(remote), sftpQuote(t.Dest.Path))
+}
+
+// sftpQuote quotes an argument for an sftp batch file.
+func sftpQuote(s string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(s) + `"`
+}
+
+// Progress is a line of output from the transfer tool.
+type Progress struct {
+	// Percent is the completion the tool reported on this line, or -1 if
+	// the line has none.
+	Percent int
+	// Line is the output line with surrounding whitespace removed.
+	Line string
+}
+
+// Options configures Run.
+type Options struct {
+	// Path overrides the tool's binary.
+	Path string
+	// OnProgress is called for each line the tool prints.
+	OnProgress func(Progress)
+}
+
+// percentPattern matches the completion percentage in scp and rsync
+// progress lines.
+var percentPattern = regexp.MustCompile(`\b(\d{1,3})%`)
+
+// ParseProgress extracts the completion percentage from a line of output.
+func ParseProgress(line string) Progress {
+	progress := Progress{Percent: -1, Line: strings.TrimSpace(line)}
+	if match := percentPattern.FindStringSubmatch(line); match != nil {
+		if percent, err := strconv.Atoi(match[1]); err == nil && percent <= 100 {
+			progress.Percent = percent
+		}
+	}
+	return progress
+}
+
+// Run performs the transfer. The tool runs under a pseudo-terminal because
+// scp only prints its progress meter to one. On failure the error includes
+// the tool's last messages.
+func Run(ctx context.Context, t Transfer, opts Options) error {
+	cmd := t.Command(ctx, opts.Path)
+	ptmx, err := pty.StartWithSize(cmd, &pty.Winsize{Rows: 24, Cols: 200})
+	if err != nil {
+		return fmt.Errorf("failed to start %s: %w", t.Tool, err)
+	}
+	defer ptmx.Close()
+
+	var messages []string
+	readLines(ptmx, func(line string) {
+		progress := ParseProgress(line)
+		if progress.Line == "" {
+			return
+		}
+		if progress.Percent < 0 {
+			messages = append(messages, progress.Line)
+		}
+		if opts.OnProgress != nil {
+			opts.OnProgress(progress)
+		}
+	})
+
+	if err := cmd.Wait(); err != nil {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if len(messages) > 3 {
+			messages = messages[len(messages)-3:]
+		}
+		if len(messages) > 0 {
+			return fmt.Errorf("%s failed: %s", t.Tool, strings.Join(messages, "; "))
+		}
+		return fmt.Errorf("%s failed: %w", t.Tool, err)
+	}
+	return nil
+}
+
+// readLines calls fn for each line read from r, treating carriage returns
+// as line ends since progress meters redraw with them. Reading stops at the
+// first error, which for a pseudo-terminal is EIO once the tool exits.
+func readLines(r io.Reader, fn func(string)) {
+	var pending []byte
+	buf := make([]byte, 4096)
+	for {
+		n, err := r.Read(buf)
+		pending = append(pending, buf[:n]...)
+		for {
+			end := bytes.IndexAny(pending, "\r\n")
+			if end < 0 {
+				break
+			}
+			fn(string(pending[:end]))
+			pending = pending[end+1:]
+		}
+		if err != nil {
+			break
+		}
+	}
+	if len(pending) > 0 {
+		fn(string(pending))
+	}
+}
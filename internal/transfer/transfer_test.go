@@ -0,0 +1,140 @@
+package transfer
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func isAlias(name string) bool {
+	return name == "prod-db" || name == "web"
+}
+
+func TestParseEndpoint(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected Endpoint
+	}{
+		{"./file", Endpoint{Path: "./file"}},
+		{"./a:b", Endpoint{Path: "./a:b"}},
+		{"/tmp/x", Endpoint{Path: "/tmp/x"}},
+		{"prod-db:/tmp/", Endpoint{Host: "prod-db", Path: "/tmp/"}},
+		{"root@web:", Endpoint{User: "root", Host: "web"}},
+	}
+	for _, test := range tests {
+		endpoint, err := ParseEndpoint(test.input, isAlias)
+		if err != nil {
+			t.Errorf("ParseEndpoint(%q): unexpected error %v", test.input, err)
+			continue
+		}
+		if endpoint != test.expected {
+			t.Errorf("ParseEndpoint(%q): expected %+v, got %+v", test.input, test.expected, endpoint)
+		}
+	}
+
+	if _, err := ParseEndpoint("typo:/tmp", isAlias); err == nil {
+		t.Error("Expected error for an unknown host")
+	}
+}
+
+func TestNew(t *testing.T) {
+	local := Endpoint{Path: "x"}
+	remote := Endpoint{Host: "web", Path: "/tmp"}
+	if _, err := New(SCP, local, local); err == nil {
+		t.Error("Expected error for a local copy")
+	}
+	if _, err := New(SCP, remote, remote); err == nil {
+		t.Error("Expected error for a host-to-host copy")
+	}
+	transfer, err := New(SCP, remote, local)
+	if err != nil || transfer.Upload() || transfer.Host() != remote {
+		t.Errorf("Expected a download from web, got %+v (%v)", transfer, err)
+	}
+}
+
+func TestTransfer_Command(t *testing.T) {
+	upload := Transfer{Source: Endpoint{Path: "./my file"}, Dest: Endpoint{User: "root", Host: "web", Path: "/tmp/"}}
+	download := Transfer{Source: Endpoint{Host: "web", Path: "/var/log/x.log"}, Dest: Endpoint{Path: "."}}
+	prompt := download
+	prompt.Prompt = true
+
+	tests := []struct {
+		tool     Tool
+		transfer Transfer
+		args     []string
+		stdin    string
+	}{
+		{SCP, upload, []string{"scp", "-r", "-o", "BatchMode=yes", "./my file", "root@web:/tmp/"}, ""},
+		{Rsync, download, []string{"rsync", "-a", "--info=progress2", "-e", "ssh -o BatchMode=yes", "web:/var/log/x.log", "."}, ""},
+		{SFTP, upload, []string{"sftp", "-o", "BatchMode=yes", "-b", "-", "root@web"}, "put -R \"./my file\" \"/tmp/\"\n"},
+		{SFTP, download, []string{"sftp", "-o", "BatchMode=yes", "-b", "-", "web"}, "get -R \"/var/log/x.log\" \".\"\n"},
+		{Rsync, prompt, []string{"rsync", "-a", "--info=progress2", "-e", "ssh", "web:/var/log/x.log", "."}, ""},
+	}
+	for _, test := range tests {
+		test.transfer.Tool = test.tool
+		cmd := test.transfer.Command(context.Background(), "")
+		if !reflect.DeepEqual(cmd.Args, test.args) {
+			t.Errorf("%s: expected args %q, got %q", test.tool, test.args, cmd.Args)
+		}
+		var stdin string
+		if cmd.Stdin != nil {
+			data, _ := io.ReadAll(cmd.Stdin)
+			stdin = string(data)
+		}
+		if stdin != test.stdin {
+			t.Errorf("%s: expected batch %q, got %q", test.tool, test.stdin, stdin)
+		}
+	}
+}
+
+func TestParseProgress(t *testing.T) {
+	tests := []struct {
+		line    string
+		percent int
+	}{
+		{"x.log                          45%  123KB   1.2MB/s   00:03 ETA", 45},
+		{"      1,234,567 100%   10.50MB/s    0:00:00 (xfr#1, to-chk=0/1)", 100},
+		{"Permission denied (publickey).", -1},
+	}
+	for _, test := range tests {
+		if got := ParseProgress(test.line); got.Percent != test.percent {
+			t.Errorf("ParseProgress(%q): expected %d%%, got %d%%", test.line, test.percent, got.Percent)
+		}
+	}
+}
+
+// writeTool writes a stand-in transfer tool running script.
+func writeTool(t *testing.T, script string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "scp")
+	if err := os.WriteFile(path, []byte("#!/bin/sh\n"+script+"\n"), 0o755); err != nil {
+		t.Fatalf("Failed to write fake tool: %v", err)
+	}
+	return path
+}
+
+func TestRun(t *testing.T) {
+	transfer := Transfer{Tool: SCP, Source: Endpoint{Path: "x"}, Dest: Endpoint{Host: "web", Path: "/tmp"}}
+
+	var percents []int
+	path := writeTool(t, `printf 'x   10%%  1KB\rx   60%%  6KB\rx  100%% 10KB\n'`)
+	err := Run(context.Background(), transfer, Options{Path: path, OnProgress: func(p Progress) {
+		percents = append(percents, p.Percent)
+	}})
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	if !reflect.DeepEqual(percents, []int{10, 60, 100}) {
+		t.Errorf("Expected progress 10, 60, 100, got %v", percents)
+	}
+
+	path = writeTool(t, `echo "scp: /tmp: Permission denied"; exit 1`)
+	err = Run(context.Background(), transfer, Options{Path: path})
+	if err == nil || !strings.Contains(err.Error(), "Permission denied") {
+		t.Errorf("Expected the tool's error, got %v", err)
+	}
+}
@@ -0,0 +1,192 @@
+// Package transport builds the command that opens an interactive session to
+// a host: plain ssh, mosh, Eternal Terminal, autossh or a user template.
+package transport
+
+import (
+	"errors"
+	"fmt"
+	"os/exec"
+	"sort"
+	"strings"
+
+	"github.com/antonjah/ssm/internal/config"
+)
+
+// Default is the transport used when neither the host, the invocation nor
+// ssm's settings pick one.
+const Default = "ssh"
+
+// ErrUnknown is returned for a transport name that is neither built in nor
+// defined in ssm's settings.
+var ErrUnknown = errors.New("unknown transport")
+
+// builtins are the command templates of the built-in transports.
+var builtins = map[string]string{
+	"ssh":     "ssh {host}",
+	"mosh":    "mosh {host}",
+	"et":      "et {host}",
+	"autossh": "autossh -M 0 -o ServerAliveInterval=30 -o ServerAliveCountMax=3 {host}",
+}
+
+// Transport is a named command template. The template is split into words
+// like a shell would and may use these placeholders:
+//
+//	{host}      the alias from ~/.ssh/config
+//	{hostname}  the configured HostName, or the alias
+//	{user}      the configured User, or the local user as in ssh
+//	{port}      the configured Port, or 22
+type Transport struct {
+	Name     string
+	Template string
+}
+
+// Registry holds the transports available to a session: the built-in ones
+// and custom ones from ssm's settings, which take precedence.
+type Registry struct {
+	// Custom maps lowercase names to command templates.
+	Custom map[string]string
+	// Default is the transport of hosts that don't pick one. Defaults to
+	// Default.
+	Default string
+}
+
+// Names returns the built-in transport names followed by the custom ones,
+// each group sorted.
+func (r Registry) Names() []string {
+	var names, custom []string
+	for name := range builtins {
+		names = append(names, name)
+	}
+	for name := range r.Custom {
+		if _, ok := builtins[name]; !ok {
+			custom = append(custom, name)
+		}
+	}
+	sort.Strings(names)
+	sort.Strings(custom)
+	return append(names, custom...)
+}
+
+// Lookup returns the transport called name. A name containing a placeholder
+// is used as a template directly, so "# ssm:via mosh --ssh='ssh -p 2222' {host}"
+// works without defining a transport first.
+func (r Registry) Lookup(name string) (Transport, error) {
+	name = strings.TrimSpace(name)
+	if strings.Contains(name, "{host}") || strings.Contains(name, "{hostname}") {
+		return Transport{Name: "custom", Template: name}, nil
+	}
+	key := strings.ToLower(name)
+	if template := r.Custom[key]; template != "" {
+		return Transport{Name: key, Template: template}, nil
+	}
+	if template, ok := builtins[key]; ok {
+		return Transport{Name: key, Template: template}, nil
+	}
+	return Transport{}, fmt.Errorf("%w %q (available: %s)", ErrUnknown, name, strings.Join(r.Names(), ", "))
+}
+
+// ForHost returns the transport for host: override if set, otherwise the
+// host's "# ssm:via" metadata, otherwise the registry's default.
+func (r Registry) ForHost(host config.Host, override string) (Transport, error) {
+	switch {
+	case override != "":
+		return r.Lookup(override)
+	case host.Via != "":
+		return r.Lookup(host.Via)
+	case r.Default != "":
+		return r.Lookup(r.Default)
+	}
+	return r.Lookup(Default)
+}
+
+// Command returns the argument list that connects to host. The host is
+// appended when the template doesn't mention it.
+func (t Transport) Command(host config.Host) ([]string, error) {
+	words, err := split(t.Template)
+	if err != nil {
+		return nil, fmt.Errorf("transport %s: %w", t.Name, err)
+	}
+	if len(words) == 0 {
+		return nil, fmt.Errorf("transport %s: empty command", t.Name)
+	}
+
+	replacer := strings.NewReplacer(
+		"{host}", host.Alias,
+		"{hostname}", host.EffectiveHostName(),
+		"{user}", host.EffectiveUser(),
+		"{port}", host.EffectivePort(),
+	)
+
+	mentioned := false
+	for i, word := range words {
+		if strings.Contains(word, "{host}") || strings.Contains(word, "{hostname}") {
+			mentioned = true
+		}
+		words[i] = replacer.Replace(word)
+	}
+	if !mentioned {
+		words = append(words, host.Alias)
+	}
+	return words, nil
+}
+
+// Resolve returns the command for host with its program looked up in PATH.
+func (t Transport) Resolve(host config.Host) (string, []string, error) {
+	args, err := t.Command(host)
+	if err != nil {
+		return "", nil, err
+	}
+	path, err := exec.LookPath(args[0])
+	if err != nil {
+		return "", nil, fmt.Errorf("%s command not found: %w", args[0], err)
+	}
+	return path, args, nil
+}
+
+// split splits s into words, honouring single and double quotes and
+// backslash escapes outside single quotes.
+func split(s string) ([]string, error) {
+	var (
+		words   []string
+		word    strings.Builder
+		inWord  bool
+		quote   rune
+		escaped bool
+	)
+	for _, r := range s {
+		switch {
+		case escaped:
+			word.WriteRune(r)
+			escaped = false
+		case r == '\\' && quote != '\'':
+			escaped, inWord = true, true
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			} else {
+				word.WriteRune(r)
+			}
+		case r == '\'' || r == '"':
+			quote, inWord = r, true
+		case r == ' ' || r == '\t' || r == '\n':
+			if inWord {
+				words = append(words, word.String())
+				word.Reset()
+				inWord = false
+			}
+		default:
+			word.WriteRune(r)
+			inWord = true
+		}
+	}
+	if quote != 0 {
+		return nil, fmt.Errorf("unterminated %c quote", quote)
+	}
+	if escaped {
+		return nil, errors.New("trailing backslash")
+	}
+	if inWord {
+		words = append(words, word.String())
+	}
+	return words, nil
+}
@@ -0,0 +1,126 @@
+package transport
+
+import (
+	"errors"
+	"os"
+	"os/user"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/antonjah/ssm/internal/config"
+)
+
+func TestRegistry_Lookup(t *testing.T) {
+	registry := Registry{Custom: map[string]string{"tsh": "tsh ssh {user}@{host}"}}
+
+	tests := []struct {
+		name     string
+		template string
+	}{
+		{"ssh", "ssh {host}"},
+		{"MOSH", "mosh {host}"},
+		{"tsh", "tsh ssh {user}@{host}"},
+		{"mosh --ssh='ssh -p 2222' {host}", "mosh --ssh='ssh -p 2222' {host}"},
+	}
+	for _, test := range tests {
+		transport, err := registry.Lookup(test.name)
+		if err != nil {
+			t.Errorf("Lookup(%q): unexpected error: %v", test.name, err)
+			continue
+		}
+		if transport.Template != test.template {
+			t.Errorf("Lookup(%q): expected template %q, got %q", test.name, test.template, transport.Template)
+		}
+	}
+
+	if _, err := registry.Lookup("telnet"); !errors.Is(err, ErrUnknown) {
+		t.Errorf("Expected ErrUnknown, got %v", err)
+	}
+}
+
+func TestRegistry_Names(t *testing.T) {
+	registry := Registry{Custom: map[string]string{"tsh": "tsh ssh {host}", "mosh": "mosh --predict=always {host}"}}
+	expected := []string{"autossh", "et", "mosh", "ssh", "tsh"}
+	if names := registry.Names(); !reflect.DeepEqual(names, expected) {
+		t.Errorf("Expected %v, got %v", expected, names)
+	}
+}
+
+func TestRegistry_ForHost(t *testing.T) {
+	host := config.Host{Alias: "web", Via: "mosh"}
+	tests := []struct {
+		host     config.Host
+		override string
+		expected string
+	}{
+		{config.Host{Alias: "web"}, "", "ssh"},
+		{host, "", "mosh"},
+		{host, "et", "et"},
+	}
+	for _, test := range tests {
+		transport, err := (Registry{}).ForHost(test.host, test.override)
+		if err != nil || transport.Name != test.expected {
+			t.Errorf("ForHost(%+v, %q): expected %s, got %s (%v)", test.host, test.override, test.expected, transport.Name, err)
+		}
+	}
+
+	registry := Registry{Default: "autossh"}
+	if transport, err := registry.ForHost(config.Host{Alias: "web"}, ""); err != nil || transport.Name != "autossh" {
+		t.Errorf("Expected the registry's default, got %s (%v)", transport.Name, err)
+	}
+	if transport, err := registry.ForHost(host, ""); err != nil || transport.Name != "mosh" {
+		t.Errorf("Expected the host's transport over the default, got %s (%v)", transport.Name, err)
+	}
+}
+
+func TestTransport_Command(t *testing.T) {
+	host := config.Host{Alias: "web", HostName: "10.0.0.5", User: "deploy"}
+	tests := []struct {
+		template string
+		expected []string
+	}{
+		{"ssh {host}", []string{"ssh", "web"}},
+		{"mosh --ssh=\"ssh -p {port}\" {host}", []string{"mosh", "--ssh=ssh -p 22", "web"}},
+		{"tsh ssh {user}@{hostname}", []string{"tsh", "ssh", "deploy@10.0.0.5"}},
+		{"kitten ssh", []string{"kitten", "ssh", "web"}},
+		{`echo 'a b' c\ d`, []string{"echo", "a b", "c d", "web"}},
+	}
+	for _, test := range tests {
+		command, err := Transport{Name: "test", Template: test.template}.Command(host)
+		if err != nil || !reflect.DeepEqual(command, test.expected) {
+			t.Errorf("Command(%q): expected %q, got %q (%v)", test.template, test.expected, command, err)
+		}
+	}
+
+	local, err := user.Current()
+	if err != nil {
+		t.Fatalf("Failed to look up the local user: %v", err)
+	}
+	command, err := Transport{Name: "test", Template: "tsh ssh {user}@{host}"}.Command(config.Host{Alias: "web"})
+	if expected := []string{"tsh", "ssh", local.Username + "@web"}; err != nil || !reflect.DeepEqual(command, expected) {
+		t.Errorf("Expected {user} to fall back to the local user, got %q (%v)", command, err)
+	}
+
+	for _, template := range []string{"", "ssh 'web", `ssh \`} {
+		if _, err := (Transport{Name: "test", Template: template}).Command(host); err == nil {
+			t.Errorf("Command(%q): expected an error", template)
+		}
+	}
+}
+
+func TestTransport_Resolve(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "mosh"), []byte("#!/bin/sh\n"), 0755); err != nil {
+		t.Fatalf("Failed to write fake mosh: %v", err)
+	}
+	t.Setenv("PATH", dir)
+
+	path, command, err := Transport{Name: "mosh", Template: "mosh {host}"}.Resolve(config.Host{Alias: "web"})
+	if err != nil || path != filepath.Join(dir, "mosh") || !reflect.DeepEqual(command, []string{"mosh", "web"}) {
+		t.Errorf("Expected %s mosh web, got %s %v (%v)", filepath.Join(dir, "mosh"), path, command, err)
+	}
+	if _, _, err := (Transport{Name: "et", Template: "et {host}"}).Resolve(config.Host{Alias: "web"}); err == nil {
+		t.Error("Expected an error for a missing program")
+	}
+}
@@ -0,0 +1,49 @@
+// Package xdg locates ssm's directories following the XDG Base Directory
+// specification.
+package xdg
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// appName is the subdirectory used inside each base directory.
+const appName = "ssm"
+
+// ConfigDir returns ssm's configuration directory, $XDG_CONFIG_HOME/ssm or
+// ~/.config/ssm.
+func ConfigDir() (string, error) {
+	return dir("XDG_CONFIG_HOME", ".config")
+}
+
+// CacheDir returns ssm's cache directory, $XDG_CACHE_HOME/ssm or ~/.cache/ssm.
+func CacheDir() (string, error) {
+	return dir("XDG_CACHE_HOME", ".cache")
+}
+
+// StateDir returns ssm's state directory, $XDG_STATE_HOME/ssm or
+// ~/.local/state/ssm.
+func StateDir() (string, error) {
+	return dir("XDG_STATE_HOME", filepath.Join(".local", "state"))
+}
+
+// DataDir returns ssm's data directory, $XDG_DATA_HOME/ssm or
+// ~/.local/share/ssm.
+func DataDir() (string, error) {
+	return dir("XDG_DATA_HOME", filepath.Join(".local", "share"))
+}
+
+// dir returns the ssm subdirectory of the base directory named by env,
+// falling back to fallback inside the user's home directory. Relative values
+// are ignored as the specification requires.
+func dir(env, fallback string) (string, error) {
+	if base := os.Getenv(env); base != "" && filepath.IsAbs(base) {
+		return filepath.Join(base, appName), nil
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to get user home directory: %w", err)
+	}
+	return filepath.Join(home, fallback, appName), nil
+}
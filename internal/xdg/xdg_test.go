@@ -0,0 +1,73 @@
+package xdg
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestCacheDir(t *testing.T) {
+	t.Setenv("XDG_CACHE_HOME", "/tmp/cache")
+	dir, err := CacheDir()
+	if err != nil {
+		t.Fatalf("CacheDir failed: %v", err)
+	}
+	if dir != "/tmp/cache/ssm" {
+		t.Errorf("Expected '/tmp/cache/ssm', got '%s'", dir)
+	}
+
+	// Relative paths are ignored
+	t.Setenv("XDG_CACHE_HOME", "relative")
+	t.Setenv("HOME", "/home/test")
+	dir, err = CacheDir()
+	if err != nil {
+		t.Fatalf("CacheDir failed: %v", err)
+	}
+	if dir != filepath.Join("/home/test", ".cache", "ssm") {
+		t.Errorf("Expected fallback to ~/.cache/ssm, got '%s'", dir)
+	}
+}
+
+func TestStateDir(t *testing.T) {
+	t.Setenv("XDG_STATE_HOME", "")
+	t.Setenv("HOME", "/home/test")
+	dir, err := StateDir()
+	if err != nil {
+		t.Fatalf("StateDir failed: %v", err)
+	}
+	if dir != filepath.Join("/home/test", ".local", "state", "ssm") {
+		t.Errorf("Expected fallback to ~/.local/state/ssm, got '%s'", dir)
+	}
+}
+
+func TestDataDir(t *testing.T) {
+	t.Setenv("XDG_DATA_HOME", "/tmp/data")
+	dir, err := DataDir()
+	if err != nil {
+		t.Fatalf("DataDir failed: %v", err)
+	}
+	if dir != "/tmp/data/ssm" {
+		t.Errorf("Expected '/tmp/data/ssm', got '%s'", dir)
+	}
+
+	t.Setenv("XDG_DATA_HOME", "")
+	t.Setenv("HOME", "/home/test")
+	dir, err = DataDir()
+	if err != nil {
+		t.Fatalf("DataDir failed: %v", err)
+	}
+	if dir != filepath.Join("/home/test", ".local", "share", "ssm") {
+		t.Errorf("Expected fallback to ~/.local/share/ssm, got '%s'", dir)
+	}
+}
+
+func TestConfigDir(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", "")
+	t.Setenv("HOME", "/home/test")
+	dir, err := ConfigDir()
+	if err != nil {
+		t.Fatalf("ConfigDir failed: %v", err)
+	}
+	if dir != filepath.Join("/home/test", ".config", "ssm") {
+		t.Errorf("Expected fallback to ~/.config/ssm, got '%s'", dir)
+	}
+}